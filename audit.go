@@ -0,0 +1,107 @@
+package mattress
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"hash"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ExposeEvent describes a single exposure of the plaintext of a Secret, as reported to
+// the hooks registered with OnExpose. It never includes the plaintext itself.
+type ExposeEvent struct {
+	Label string // Label is the label of the Secret, as set by WithLabel
+
+	// Fingerprint identifies the data exposed. It is keyed with a random key generated
+	// for the process, so it cannot be used to confirm guesses of the plaintext and is
+	// only comparable with other events from the same process.
+	Fingerprint Fingerprint
+	File        string    // File is the source file of the call which exposed the Secret
+	Line        int       // Line is the line within File of the call
+	Time        time.Time // Time is when the exposure took place
+	Err         error     // Err is the reason the exposure failed, if it did
+}
+
+// hooks holds the functions registered with OnExpose.
+var hooks struct {
+	fns  atomic.Pointer[[]*func(ExposeEvent)] // fns is replaced wholesale so it can be read without locking
+	lock sync.Mutex                           // serialize registrations
+}
+
+// auditKey keys the fingerprints reported in ExposeEvents.
+var auditKey = sync.OnceValue(func() []byte {
+	key := make([]byte, sha256.Size)
+	if _, err := rand.Read(key); err != nil {
+		panic(err)
+	}
+	return key
+})
+
+// OnExpose registers fn to be called after every exposure of any Secret, whether by
+// Expose, ExposeErr, ExposeInto, WithExposed, or WithPlaintext, and by the operations
+// built on them which hand the plaintext onwards, such as ExposeTo and Map. Operations
+// which use the data without ever handing it out, such as Equal, Fingerprint, HMAC,
+// Seal, and redaction, are not exposures. This provides an audit trail of every
+// plaintext access without instrumenting every call site. fn is called synchronously,
+// after the Secret has been unlocked, so it may itself use Secrets but should return
+// quickly. OnExpose returns a function which unregisters fn.
+func OnExpose(fn func(ev ExposeEvent)) func() {
+	hook := &fn
+
+	hooks.lock.Lock()         // Lock before replacing the hooks
+	defer hooks.lock.Unlock() // Ensure the lock is Unlocked when the function returns
+
+	var fns []*func(ExposeEvent)
+	if current := hooks.fns.Load(); current != nil {
+		fns = append(fns, *current...)
+	}
+
+	fns = append(fns, hook)
+	hooks.fns.Store(&fns)
+
+	var once sync.Once
+
+	return func() {
+		once.Do(func() {
+			hooks.lock.Lock()         // Lock before replacing the hooks
+			defer hooks.lock.Unlock() // Ensure the lock is Unlocked when the function returns
+
+			var remaining []*func(ExposeEvent)
+			for _, h := range *hooks.fns.Load() {
+				if h != hook {
+					remaining = append(remaining, h)
+				}
+			}
+
+			hooks.fns.Store(&remaining)
+		})
+	}
+}
+
+// auditing reports whether any OnExpose hooks are registered.
+func auditing() bool {
+	fns := hooks.fns.Load()
+
+	return fns != nil && len(*fns) > 0
+}
+
+// emit calls every registered OnExpose hook with ev.
+func emit(ev ExposeEvent) {
+	fns := hooks.fns.Load()
+	if fns == nil {
+		return
+	}
+
+	for _, fn := range *fns {
+		(*fn)(ev)
+	}
+}
+
+// auditHash returns the keyed hash of the encoded bytes whose Fingerprint is reported
+// in ExposeEvents.
+func auditHash() hash.Hash {
+	return hmac.New(sha256.New, auditKey())
+}
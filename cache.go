@@ -0,0 +1,90 @@
+package mattress
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// CachingProvider is a Provider that caches the secrets fetched from another Provider
+// for a fixed time-to-live, reducing the load on the underlying secret store.
+//
+// Cached secrets are owned by the CachingProvider. Each call to Fetch returns an
+// independent copy, which the caller is responsible for destroying, while the cached
+// original is destroyed once it expires or is invalidated.
+type CachingProvider struct {
+	provider Provider              // provider is the underlying source of secrets
+	ttl      time.Duration         // ttl is how long a fetched secret is cached for
+	now      func() time.Time      // now returns the current time
+	entries  map[string]cacheEntry // entries maps names to cached secrets
+	lock     sync.Mutex            // synchronize access to entries
+}
+
+// cacheEntry is a secret cached by a CachingProvider along with its expiry.
+type cacheEntry struct {
+	secret  *Secret[string]
+	expires time.Time
+}
+
+// NewCachingProvider returns a CachingProvider which caches the secrets fetched from p
+// for ttl.
+func NewCachingProvider(p Provider, ttl time.Duration) *CachingProvider {
+	return &CachingProvider{
+		provider: p,
+		ttl:      ttl,
+		now:      time.Now,
+		entries:  make(map[string]cacheEntry),
+	}
+}
+
+// Fetch returns a copy of the cached secret called name, fetching it from the
+// underlying Provider if it is not cached or its time-to-live has elapsed.
+func (c *CachingProvider) Fetch(ctx context.Context, name string) (*Secret[string], error) {
+	c.lock.Lock()         // Lock before reading the entries
+	defer c.lock.Unlock() // Ensure the lock is Unlocked when the method returns
+
+	entry, ok := c.entries[name]
+	if ok && c.now().Before(entry.expires) {
+		return entry.secret.clone()
+	}
+
+	if ok {
+		c.evict(name, entry)
+	}
+
+	secret, err := c.provider.Fetch(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	c.entries[name] = cacheEntry{secret: secret, expires: c.now().Add(c.ttl)}
+
+	return secret.clone()
+}
+
+// Invalidate destroys the cached secret called name, if any, so that the next Fetch
+// retrieves it from the underlying Provider.
+func (c *CachingProvider) Invalidate(name string) {
+	c.lock.Lock()         // Lock before writing the entries
+	defer c.lock.Unlock() // Ensure the lock is Unlocked when the method returns
+
+	if entry, ok := c.entries[name]; ok {
+		c.evict(name, entry)
+	}
+}
+
+// InvalidateAll destroys every cached secret.
+func (c *CachingProvider) InvalidateAll() {
+	c.lock.Lock()         // Lock before writing the entries
+	defer c.lock.Unlock() // Ensure the lock is Unlocked when the method returns
+
+	for name, entry := range c.entries {
+		c.evict(name, entry)
+	}
+}
+
+// evict destroys entry and removes it from the cache. The caller must hold c.lock.
+func (c *CachingProvider) evict(name string, entry cacheEntry) {
+	entry.secret.Destroy()
+	delete(c.entries, name)
+}
@@ -0,0 +1,135 @@
+package mattress
+
+import (
+	"io"
+
+	"github.com/awnumar/memguard"
+)
+
+// WithChunkedStorage splits data larger than size bytes across multiple
+// memguard.Enclaves of at most size bytes each, rather than holding it in a single
+// LockedBuffer, for large values such as multi-megabyte private keys or license blobs
+// which would otherwise exhaust the locked-memory budget of the process. Like
+// WithSealedStorage, the data is only decrypted while it is being exposed.
+//
+// Exposures which decode the data, such as Expose, reassemble it into a single
+// LockedBuffer for their duration. ExposeTo instead streams it a chunk at a time, so
+// that no more than size bytes are ever decrypted at once. Data of at most size bytes
+// is stored as it would be without this option.
+func WithChunkedStorage(size int) Option {
+	return func(c *config) {
+		c.chunkSize = size
+	}
+}
+
+// ExposeTo writes the encoded bytes held by the Secret to w, which for Secrets using
+// BytesCodec or StringCodec are the plaintext itself, returning the number of bytes
+// written. It counts as a single exposure. Secrets created with WithChunkedStorage are
+// decrypted and written a chunk at a time, each being wiped once written, rather than
+// reassembled in full. w must not retain the slices passed to Write.
+func (s *Secret[T]) ExposeTo(w io.Writer) (int64, error) {
+	var n int64
+
+	err := s.expose(s.viewChunks, func(chunk []byte) error {
+		written, err := w.Write(chunk)
+		n += int64(written)
+		return err
+	})
+
+	return n, err
+}
+
+// chunkedStorage keeps the data split across multiple Enclaves, decrypting them one at
+// a time where possible.
+type chunkedStorage struct {
+	chunks []*memguard.Enclave // chunks hold the data in order
+	chunk  int                 // chunk is the size of every chunk but the last
+	total  int                 // total is the number of bytes across every chunk
+}
+
+// newChunkedStorage seals b into Enclaves of at most size bytes, wiping b in the
+// process.
+func newChunkedStorage(b []byte, size int) *chunkedStorage {
+	c := &chunkedStorage{chunk: size, total: len(b)}
+
+	for start := 0; start < len(b); start += size {
+		// NewEnclave wipes the part of b it seals.
+		c.chunks = append(c.chunks, memguard.NewEnclave(b[start:min(start+size, len(b))]))
+	}
+
+	return c
+}
+
+// view reassembles the chunks into a single LockedBuffer.
+func (c *chunkedStorage) view() ([]byte, func(), error) {
+	if c.chunks == nil {
+		return nil, nil, ErrDestroyed
+	}
+
+	if err := reserveView(c.total); err != nil {
+		return nil, nil, err
+	}
+
+	buffer := memguard.NewBuffer(c.total)
+	n := 0
+
+	err := c.each(func(chunk []byte) error {
+		n += copy(buffer.Bytes()[n:], chunk)
+		return nil
+	})
+	if err != nil {
+		buffer.Destroy()
+		return nil, nil, err
+	}
+
+	return buffer.Bytes(), buffer.Destroy, nil
+}
+
+// each calls fn with each chunk in turn, decrypting it into a LockedBuffer which is
+// destroyed once fn returns.
+func (c *chunkedStorage) each(fn func(chunk []byte) error) error {
+	if c.chunks == nil {
+		return ErrDestroyed
+	}
+
+	for _, enclave := range c.chunks {
+		buffer, err := openEnclave(enclave)
+		if err != nil {
+			return err
+		}
+
+		err = fn(buffer.Bytes())
+		buffer.Destroy()
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// from decrypts the chunk holding the byte at off, returning the bytes from off to the
+// end of the chunk along with a function to wipe them.
+func (c *chunkedStorage) from(off int) ([]byte, func(), error) {
+	if c.chunks == nil {
+		return nil, nil, ErrDestroyed
+	}
+
+	buffer, err := openEnclave(c.chunks[off/c.chunk])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return buffer.Bytes()[off%c.chunk:], buffer.Destroy, nil
+}
+
+// destroy drops the references to the Enclaves, as enclaveStorage does.
+func (c *chunkedStorage) destroy() {
+	c.chunks = nil
+	c.total = 0
+}
+
+func (c *chunkedStorage) size() int {
+	return c.total
+}
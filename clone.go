@@ -0,0 +1,106 @@
+package mattress
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Clone returns an independent copy of the Secret, secured with the same Codec and
+// options in memory of its own, so that ownership of the copy can be handed to code
+// with a different lifetime, such as another goroutine, which destroys it once done.
+// Destroying either Secret leaves the other intact. The stored bytes are copied
+// directly in guarded memory, without being decoded, and Clone does not count as an
+// exposure. Limits set by WithTTL and WithMaxExposures apply to the copy afresh.
+func (s *Secret[T]) Clone() (*Secret[T], error) {
+	return s.clone()
+}
+
+// clone returns an independent copy of the Secret, as described by Clone.
+func (s *Secret[T]) clone() (*Secret[T], error) {
+	var secret *Secret[T]
+
+	err := s.withBytes(func(b []byte) error {
+		store, err := s.copyStore(b)
+		if err != nil {
+			return err
+		}
+
+		secret = newSecret(store, s.codec, s.cfg)
+
+		return nil
+	})
+
+	return secret, err
+}
+
+// Rekey moves the data held by the Secret into freshly allocated guarded memory,
+// destroying the memory which held it, for use after a suspected partial disclosure of
+// memory, such as through an out-of-bounds read. Data sealed by WithSealedStorage is
+// encrypted anew under a freshly generated key of its own, rather than the session key
+// memguard shares between every Enclave, and the previous key is destroyed, so that
+// whatever was disclosed of the old key or ciphertext is of no use against the new
+// ones. Each key is held split across a memguard Coffer, which is re-split in the
+// background until the Secret is destroyed. Data kept in guarded memory in plaintext is
+// copied into new memory, and masked data is masked anew with a fresh pad.
+//
+// Rekey returns an error wrapping errors.ErrUnsupported for Secrets created with
+// WithPool, WithChunkedStorage, or WithDPAPI, whose keys cannot be replaced. The other
+// options of the Secret, including the deadline set by WithTTL and the exposures
+// counted against WithMaxExposures, are unchanged, and Rekey does not count as an
+// exposure. Clones of the Secret made after Rekey are sealed under keys of their own.
+func (s *Secret[T]) Rekey() error {
+	if s == nil {
+		return ErrUninitialized
+	}
+
+	s.lock.Lock()         // Lock before replacing the store
+	defer s.lock.Unlock() // Ensure the lock is Unlocked when the method returns
+
+	if s.cfg.pool != nil || s.cfg.chunkSize > 0 || s.cfg.dpapi {
+		return fmt.Errorf("mattress: cannot rekey a Secret created with WithPool, WithChunkedStorage, or WithDPAPI: %w", errors.ErrUnsupported)
+	}
+
+	cfg := s.cfg
+	cfg.ownKey = true
+
+	var store storage
+
+	err := s.view(func(b []byte) error {
+		buffer, err := guardedCopy(b)
+		if err != nil {
+			return err
+		}
+
+		store, err = newStorageFromBuffer(buffer, cfg)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	s.destroyStore()
+	s.store = store
+	s.cfg = cfg
+	s.trackID = track(store, cfg.label)
+
+	// The timer expires the store it was armed with, so it is re-armed for the new
+	// store with the remainder of the TTL.
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = time.AfterFunc(time.Until(s.deadline), func() { s.expire(store) })
+	}
+
+	return nil
+}
+
+// copyStore secures a copy of b, the stored bytes, according to the options of the
+// Secret. The copy is made directly in guarded memory.
+func (s *Secret[T]) copyStore(b []byte) (storage, error) {
+	buffer, err := guardedCopy(b)
+	if err != nil {
+		return nil, err
+	}
+
+	return newStorageFromBuffer(buffer, s.cfg)
+}
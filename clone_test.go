@@ -0,0 +1,79 @@
+package mattress
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestRekey(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		opts []Option
+	}{
+		{name: "locked"},
+		{name: "sealed", opts: []Option{WithSealedStorage()}},
+		{name: "compressed", opts: []Option{WithSealedStorage(), WithCompression()}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			secret, err := NewSecret("hunter2", tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer secret.Destroy()
+
+			for range 2 {
+				if err := secret.Rekey(); err != nil {
+					t.Fatalf("Rekey() = %v", err)
+				}
+
+				if got, err := secret.ExposeErr(); err != nil || got != "hunter2" {
+					t.Fatalf("ExposeErr() after Rekey = %q, %v, want %q", got, err, "hunter2")
+				}
+			}
+		})
+	}
+}
+
+func TestRekeyReplacesKey(t *testing.T) {
+	secret, err := NewSecret("hunter2", WithSealedStorage())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer secret.Destroy()
+
+	if err := secret.Rekey(); err != nil {
+		t.Fatal(err)
+	}
+
+	before, ok := secret.store.(*keyedStorage)
+	if !ok {
+		t.Fatalf("store after Rekey is %T, want *keyedStorage", secret.store)
+	}
+	key := before.key
+	ciphertext := bytes.Clone(before.ciphertext)
+
+	if err := secret.Rekey(); err != nil {
+		t.Fatal(err)
+	}
+
+	after := secret.store.(*keyedStorage)
+	if after.key == key || bytes.Equal(after.ciphertext, ciphertext) {
+		t.Error("Rekey kept the previous key or ciphertext")
+	}
+	if !key.Destroyed() {
+		t.Error("Rekey did not destroy the previous key")
+	}
+}
+
+func TestRekeyUnsupported(t *testing.T) {
+	secret, err := NewSecret("hunter2", WithPool(NewPool(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer secret.Destroy()
+
+	if err := secret.Rekey(); !errors.Is(err, errors.ErrUnsupported) {
+		t.Fatalf("Rekey() = %v, want errors.ErrUnsupported", err)
+	}
+}
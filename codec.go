@@ -0,0 +1,324 @@
+package mattress
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"unsafe"
+
+	"github.com/awnumar/memguard"
+)
+
+// Codec converts values of type T to and from the byte representation stored within
+// a Secret.
+//
+// The slice returned by Encode is owned by the Secret and is wiped once its contents
+// have been secured, so it must not alias memory the caller still needs. The slice
+// passed to Decode is only valid for the duration of the call and must not be retained.
+type Codec[T any] interface {
+	Encode(data T) ([]byte, error)
+	Decode(b []byte) (T, error)
+}
+
+// IntoDecoder is an optional interface implemented by Codecs which can decode into an
+// existing value, reusing its storage where possible. ExposeInto uses it when the
+// Codec of a Secret implements it, and falls back to Decode otherwise.
+type IntoDecoder[T any] interface {
+	DecodeInto(b []byte, dst *T) error
+}
+
+// guardedEncoder is implemented by Codecs which can encode directly into guarded
+// memory, bypassing the intermediate heap slice returned by Encode.
+type guardedEncoder[T any] interface {
+	encodeGuarded(data T) (guardedBuffer, error)
+}
+
+// GobCodec is a Codec that serializes values using encoding/gob. It is the codec used
+// by NewSecret.
+//
+// When used by a Secret, the encoding is streamed directly into guarded memory rather
+// than accumulated in a growing heap buffer. encoding/gob still assembles each message
+// in an internal buffer, which is wiped once the message has been written but is not
+// itself guarded; use a Codec such as StringCodec or BytesCodec where no heap copy at
+// all is acceptable.
+//
+// Encoders and decoders are reused across values of the same type, so that the type
+// definitions encoding/gob transmits ahead of each value are only built and compiled
+// once. Every encoding still carries its definitions, and is identical to that of a
+// fresh gob.Encoder. Types holding interfaces are encoded afresh each time, as the
+// concrete types they hold are only defined when first sent. Strings, byte slices, and
+// fixed-size integers bypass encoding/gob altogether, and are written in its format
+// directly.
+//
+// Types are checked before they are encoded or decoded, so that those which
+// encoding/gob cannot serialize, such as structs whose fields are all unexported, fail
+// with an error wrapping ErrUnsupportedType which names the offending field.
+type GobCodec[T any] struct{}
+
+// Encode serializes data using encoding/gob.
+func (GobCodec[T]) Encode(data T) ([]byte, error) {
+	var buf [gobHeaderLen]byte
+
+	// WipeBytes securely erases the header, which holds the value of an integer.
+	defer memguard.WipeBytes(buf[:])
+
+	if header, payload, ok := gobPrimitive(data, &buf); ok {
+		return append(append(make([]byte, 0, len(header)+len(payload)), header...), payload...), nil
+	}
+
+	if err := gobSupported[T](); err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+
+	if err := gobEncode(&b, data); err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
+
+// encodeGuarded serializes data using encoding/gob directly into a LockedBuffer.
+func (GobCodec[T]) encodeGuarded(data T) (guardedBuffer, error) {
+	var buf [gobHeaderLen]byte
+
+	// WipeBytes securely erases the header, which holds the value of an integer.
+	defer memguard.WipeBytes(buf[:])
+
+	if header, payload, ok := gobPrimitive(data, &buf); ok {
+		buffer, err := newGuardedBuffer(len(header) + len(payload))
+		if err != nil {
+			return nil, err
+		}
+
+		copy(buffer.Bytes()[copy(buffer.Bytes(), header):], payload)
+
+		return buffer, nil
+	}
+
+	if err := gobSupported[T](); err != nil {
+		return nil, err
+	}
+
+	var w guardedWriter
+
+	if err := gobEncode(&w, data); err != nil {
+		w.destroy()
+		return nil, err
+	}
+
+	return w.finish()
+}
+
+// Decode deserializes b using encoding/gob.
+func (GobCodec[T]) Decode(b []byte) (T, error) {
+	var data T
+
+	if gobPrimitiveDecode(b, &data) {
+		return data, nil
+	}
+
+	if err := gobSupported[T](); err != nil {
+		return data, err
+	}
+
+	if err := gobDecode(b, &data); err != nil {
+		var zero T
+		return zero, err
+	}
+
+	return data, nil
+}
+
+// DecodeInto deserializes b using encoding/gob into dst, which is reset to its zero
+// value first so that fields omitted from the encoding are not left over.
+func (GobCodec[T]) DecodeInto(b []byte, dst *T) error {
+	if gobPrimitiveDecode(b, dst) {
+		return nil
+	}
+
+	if err := gobSupported[T](); err != nil {
+		return err
+	}
+
+	var zero T
+	*dst = zero
+
+	return gobDecode(b, dst)
+}
+
+// JSONCodec is a Codec that serializes values using encoding/json. It is useful for
+// types that encoding/gob cannot handle, such as those relying on custom JSON
+// marshaling.
+type JSONCodec[T any] struct{}
+
+// Encode serializes data using encoding/json.
+func (JSONCodec[T]) Encode(data T) ([]byte, error) {
+	return json.Marshal(data)
+}
+
+// Decode deserializes b using encoding/json.
+func (JSONCodec[T]) Decode(b []byte) (T, error) {
+	var data T
+
+	if err := json.Unmarshal(b, &data); err != nil {
+		var zero T
+		return zero, err
+	}
+
+	return data, nil
+}
+
+// DecodeInto deserializes b using encoding/json into dst, which is reset to its zero
+// value first so that keys absent from the encoding are not left over.
+func (JSONCodec[T]) DecodeInto(b []byte, dst *T) error {
+	var zero T
+	*dst = zero
+
+	return json.Unmarshal(b, dst)
+}
+
+// BytesCodec is a Codec for []byte that stores the bytes as-is, without any
+// serialization overhead.
+type BytesCodec struct{}
+
+// Encode returns a copy of data, leaving the caller's slice untouched.
+func (BytesCodec) Encode(data []byte) ([]byte, error) {
+	return bytes.Clone(data), nil
+}
+
+// encodeGuarded copies data directly into a LockedBuffer.
+func (BytesCodec) encodeGuarded(data []byte) (guardedBuffer, error) {
+	return guardedCopy(data)
+}
+
+// Decode returns a copy of b.
+func (BytesCodec) Decode(b []byte) ([]byte, error) {
+	return bytes.Clone(b), nil
+}
+
+// DecodeInto copies b into dst, reusing its capacity if it is large enough.
+func (BytesCodec) DecodeInto(b []byte, dst *[]byte) error {
+	*dst = append((*dst)[:0], b...)
+
+	return nil
+}
+
+// StringCodec is a Codec for string that stores the string's bytes as-is, without
+// any serialization overhead.
+type StringCodec struct{}
+
+// Encode returns the bytes of data.
+func (StringCodec) Encode(data string) ([]byte, error) {
+	return []byte(data), nil
+}
+
+// encodeGuarded copies the bytes of data directly into a LockedBuffer, without first
+// converting data to a heap-allocated byte slice.
+func (StringCodec) encodeGuarded(data string) (guardedBuffer, error) {
+	return guardedCopy(unsafe.Slice(unsafe.StringData(data), len(data)))
+}
+
+// Decode returns b as a string.
+func (StringCodec) Decode(b []byte) (string, error) {
+	return string(b), nil
+}
+
+// guardedCopy returns a LockedBuffer holding a copy of b, leaving b untouched.
+func guardedCopy(b []byte) (guardedBuffer, error) {
+	buffer, err := newGuardedBuffer(len(b))
+	if err != nil {
+		return nil, err
+	}
+
+	copy(buffer.Bytes(), b)
+
+	return buffer, nil
+}
+
+// guardedWriter is an io.Writer which accumulates everything written to it in a
+// LockedBuffer, doubling the buffer as required.
+type guardedWriter struct {
+	buffer guardedBuffer // buffer holds the bytes written so far
+	n      int           // n is the number of bytes written so far
+}
+
+// Write appends p to the buffer.
+func (w *guardedWriter) Write(p []byte) (int, error) {
+	if w.buffer == nil {
+		buffer, err := newGuardedBuffer(max(len(p), 64))
+		if err != nil {
+			return 0, err
+		}
+		w.buffer = buffer
+	}
+
+	for w.n+len(p) > w.buffer.Size() {
+		grown, err := grow(w.buffer)
+		if err != nil {
+			return 0, err
+		}
+		w.buffer = grown
+	}
+
+	w.n += copy(w.buffer.Bytes()[w.n:], p)
+
+	return len(p), nil
+}
+
+// ReadFrom reads r until EOF directly into the buffer, growing it as required, so that
+// the bytes read never pass through a slice on the regular heap.
+func (w *guardedWriter) ReadFrom(r io.Reader) (int64, error) {
+	var total int64
+
+	for {
+		if w.buffer == nil {
+			buffer, err := newGuardedBuffer(os.Getpagesize())
+			if err != nil {
+				return total, err
+			}
+			w.buffer = buffer
+		}
+
+		if w.n == w.buffer.Size() {
+			grown, err := grow(w.buffer)
+			if err != nil {
+				return total, err
+			}
+			w.buffer = grown
+		}
+
+		n, err := r.Read(w.buffer.Bytes()[w.n:])
+		w.n += n
+		total += int64(n)
+
+		if errors.Is(err, io.EOF) {
+			return total, nil
+		}
+		if err != nil {
+			return total, err
+		}
+	}
+}
+
+// finish returns a LockedBuffer holding exactly the bytes written, destroying the
+// working buffer.
+func (w *guardedWriter) finish() (guardedBuffer, error) {
+	if w.buffer == nil {
+		return newGuardedBuffer(0)
+	}
+
+	defer w.destroy()
+
+	return guardedCopy(w.buffer.Bytes()[:w.n])
+}
+
+// destroy destroys the working buffer.
+func (w *guardedWriter) destroy() {
+	if w.buffer != nil {
+		w.buffer.Destroy()
+	}
+}
@@ -0,0 +1,190 @@
+package mattress_test
+
+import (
+	"bytes"
+	"encoding/gob"
+	"math"
+	"reflect"
+	"testing"
+	"unicode/utf8"
+
+	m "github.com/garrettladley/mattress"
+	"github.com/garrettladley/mattress/mattresstest"
+)
+
+// benchmarkCredentials is a small struct, the kind of value whose encoding is dominated
+// by the type definitions a fresh gob.Encoder transmits.
+type benchmarkCredentials struct {
+	Username string
+	Password string
+	Port     int
+}
+
+var credentials = benchmarkCredentials{Username: "admin", Password: "correct horse battery staple", Port: 5432}
+
+// BenchmarkGobEncode compares GobCodec with encoding a value through a fresh
+// gob.Encoder, as GobCodec did before reusing encoders.
+func BenchmarkGobEncode(b *testing.B) {
+	b.Run("fresh", func(b *testing.B) {
+		b.ReportAllocs()
+
+		for b.Loop() {
+			var buf bytes.Buffer
+			if err := gob.NewEncoder(&buf).Encode(credentials); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("codec", func(b *testing.B) {
+		b.ReportAllocs()
+
+		for b.Loop() {
+			if _, err := (m.GobCodec[benchmarkCredentials]{}).Encode(credentials); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// BenchmarkGobDecode compares GobCodec with decoding a value through a fresh
+// gob.Decoder, as GobCodec did before reusing decoders.
+func BenchmarkGobDecode(b *testing.B) {
+	encoded, err := m.GobCodec[benchmarkCredentials]{}.Encode(credentials)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.Run("fresh", func(b *testing.B) {
+		b.ReportAllocs()
+
+		for b.Loop() {
+			var data benchmarkCredentials
+			if err := gob.NewDecoder(bytes.NewReader(encoded)).Decode(&data); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("codec", func(b *testing.B) {
+		b.ReportAllocs()
+
+		for b.Loop() {
+			if _, err := (m.GobCodec[benchmarkCredentials]{}).Decode(encoded); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// FuzzGobCodec checks that GobCodec round-trips strings, byte slices, integers, and
+// structs, and that it decodes arbitrary input exactly as a fresh gob.Decoder does,
+// whether or not it takes the fast path for primitive types.
+func FuzzGobCodec(f *testing.F) {
+	for _, seed := range mattresstest.Corpus() {
+		f.Add(seed, int64(len(seed)))
+	}
+
+	for _, n := range []int64{0, -1, 127, 128, -129, math.MaxInt64, math.MinInt64} {
+		encoded, err := m.GobCodec[int64]{}.Encode(n)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(encoded, n)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte, n int64) {
+		checkGobRoundTrip(t, string(data))
+		checkGobRoundTrip(t, n)
+		checkGobRoundTrip(t, int8(n))
+		checkGobRoundTrip(t, uint32(n))
+		checkGobRoundTrip(t, benchmarkCredentials{Username: string(data), Password: string(data), Port: int(n)})
+
+		decoded, err := m.GobCodec[[]byte]{}.Decode(mustGobEncode(t, data))
+		if err != nil || !bytes.Equal(decoded, data) {
+			t.Fatalf("round-tripping %q: got %q, %v", data, decoded, err)
+		}
+
+		checkGobDecode[string](t, data)
+		checkGobDecode[[]byte](t, data)
+		checkGobDecode[int8](t, data)
+		checkGobDecode[uint64](t, data)
+		checkGobDecode[benchmarkCredentials](t, data)
+	})
+}
+
+// checkGobRoundTrip fails the test unless GobCodec decodes its own encoding of data,
+// and a fresh gob.Decoder its encoding, as data.
+func checkGobRoundTrip[T comparable](t *testing.T, data T) {
+	t.Helper()
+
+	encoded := mustGobEncode(t, data)
+
+	decoded, err := m.GobCodec[T]{}.Decode(encoded)
+	if err != nil || decoded != data {
+		t.Fatalf("round-tripping %v: got %v, %v", data, decoded, err)
+	}
+
+	var fresh T
+	if err := gob.NewDecoder(bytes.NewReader(encoded)).Decode(&fresh); err != nil || fresh != data {
+		t.Fatalf("decoding %v with a fresh gob.Decoder: got %v, %v", data, fresh, err)
+	}
+}
+
+// mustGobEncode returns the GobCodec encoding of data, failing the test if it cannot
+// be encoded.
+func mustGobEncode[T any](t *testing.T, data T) []byte {
+	t.Helper()
+
+	encoded, err := m.GobCodec[T]{}.Encode(data)
+	if err != nil {
+		t.Fatalf("encoding %v: %v", data, err)
+	}
+
+	return encoded
+}
+
+// checkGobDecode fails the test unless GobCodec and a fresh gob.Decoder agree on
+// whether b decodes as a T, and if so, on its value.
+func checkGobDecode[T any](t *testing.T, b []byte) {
+	t.Helper()
+
+	decoded, err := m.GobCodec[T]{}.Decode(b)
+
+	var fresh T
+	freshErr := gob.NewDecoder(bytes.NewReader(b)).Decode(&fresh)
+
+	if (err == nil) != (freshErr == nil) {
+		t.Fatalf("decoding %q as %T: GobCodec returned %v, gob.Decoder %v", b, fresh, err, freshErr)
+	}
+
+	if err == nil && !reflect.DeepEqual(decoded, fresh) {
+		t.Fatalf("decoding %q as %T: GobCodec returned %v, gob.Decoder %v", b, fresh, decoded, fresh)
+	}
+}
+
+// FuzzJSONCodec checks that JSONCodec round-trips strings and structs holding valid
+// UTF-8, and that it rejects rather than panics on arbitrary input.
+func FuzzJSONCodec(f *testing.F) {
+	mattresstest.AddCorpus(f)
+	f.Add([]byte(`{"Username":"admin","Password":"hunter2","Port":5432}`))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if utf8.Valid(data) {
+			value := benchmarkCredentials{Username: string(data), Password: string(data), Port: len(data)}
+
+			encoded, err := m.JSONCodec[benchmarkCredentials]{}.Encode(value)
+			if err != nil {
+				t.Fatalf("encoding %v: %v", value, err)
+			}
+
+			decoded, err := m.JSONCodec[benchmarkCredentials]{}.Decode(encoded)
+			if err != nil || decoded != value {
+				t.Fatalf("round-tripping %v: got %v, %v", value, decoded, err)
+			}
+		}
+
+		_, _ = m.JSONCodec[benchmarkCredentials]{}.Decode(data)
+		_, _ = m.JSONCodec[string]{}.Decode(data)
+	})
+}
@@ -0,0 +1,108 @@
+package mattress
+
+import (
+	"bytes"
+	"compress/flate"
+	"io"
+
+	"github.com/awnumar/memguard"
+)
+
+// WithCompression compresses the encoded data with DEFLATE before securing it, and
+// decompresses it into guarded memory for the duration of each exposure, reducing the
+// locked memory held between exposures by large, compressible values such as JSON
+// service account keys and certificate bundles. Data which does not shrink is stored
+// as it is. The option is transparent to everything but memory use: encodings written
+// by Save, Export, and the like are unaffected.
+//
+// DEFLATE keeps its working state, which includes a window of recently processed
+// data, on the regular heap, where this package cannot wipe it; avoid WithCompression
+// for small or highly sensitive values, where it saves little.
+func WithCompression() Option {
+	return func(c *config) {
+		c.compress = true
+	}
+}
+
+// compressedStorage keeps the data compressed within another storage, decompressing it
+// into a LockedBuffer on each view.
+type compressedStorage struct {
+	inner storage // inner holds the compressed data
+	n     int     // n is the length of the data once decompressed
+}
+
+// newCompressedStorage compresses b and secures the result according to cfg, wiping b
+// in the process. b is secured uncompressed if it does not shrink.
+func newCompressedStorage(b []byte, cfg config) (storage, error) {
+	cfg.compress = false
+
+	var w guardedWriter
+
+	zw, err := flate.NewWriter(&w, flate.DefaultCompression)
+	if err == nil {
+		if _, err = zw.Write(b); err == nil {
+			err = zw.Close()
+		}
+	}
+	if err != nil {
+		w.destroy()
+		memguard.WipeBytes(b)
+		return nil, err
+	}
+
+	if w.n >= len(b) {
+		w.destroy()
+		return newStorage(b, cfg)
+	}
+
+	n := len(b)
+
+	// WipeBytes securely erases the uncompressed data once compressed.
+	memguard.WipeBytes(b)
+
+	buffer, err := w.finish()
+	if err != nil {
+		return nil, err
+	}
+
+	inner, err := newStorageFromBuffer(buffer, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &compressedStorage{inner: inner, n: n}, nil
+}
+
+// view decompresses the data into a LockedBuffer.
+func (c *compressedStorage) view() ([]byte, func(), error) {
+	compressed, release, err := c.inner.view()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer release()
+
+	buffer, err := newViewBuffer(c.n)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	zr := flate.NewReader(bytes.NewReader(compressed))
+	defer zr.Close()
+
+	if _, err := io.ReadFull(zr, buffer.Bytes()); err != nil {
+		buffer.Destroy()
+		return nil, nil, err
+	}
+
+	return buffer.Bytes(), buffer.Destroy, nil
+}
+
+// destroy wipes the compressed data.
+func (c *compressedStorage) destroy() {
+	c.inner.destroy()
+}
+
+// size returns the length of the data once decompressed.
+func (c *compressedStorage) size() int {
+	return c.n
+}
@@ -0,0 +1,63 @@
+package mattress
+
+import "context"
+
+// ContextKey identifies a Secret[T] stored in a context.Context. Keys are compared by
+// identity, so each call to NewContextKey yields a distinct key.
+type ContextKey[T any] struct {
+	name string // name describes the key for debugging
+}
+
+// NewContextKey returns a new ContextKey, described by name.
+func NewContextKey[T any](name string) *ContextKey[T] {
+	return &ContextKey[T]{name: name}
+}
+
+// String returns the name of the key.
+func (k *ContextKey[T]) String() string {
+	return "mattress.ContextKey(" + k.name + ")"
+}
+
+// ContextOption configures how WithSecret stores a Secret.
+type ContextOption func(*contextConfig)
+
+// contextConfig holds the settings accumulated from the ContextOptions passed to
+// WithSecret.
+type contextConfig struct {
+	destroyOnDone bool // destroyOnDone destroys the Secret once the context is done
+}
+
+// DestroyOnDone causes WithSecret to destroy the Secret as soon as the returned
+// context is cancelled or its deadline passes, so request-scoped credentials are
+// cleaned up deterministically.
+func DestroyOnDone() ContextOption {
+	return func(c *contextConfig) {
+		c.destroyOnDone = true
+	}
+}
+
+// WithSecret returns a copy of ctx carrying secret under key, which can be retrieved
+// with SecretFromContext.
+func WithSecret[T any](ctx context.Context, key *ContextKey[T], secret *Secret[T], opts ...ContextOption) context.Context {
+	var cfg contextConfig
+
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	ctx = context.WithValue(ctx, key, secret)
+
+	if cfg.destroyOnDone {
+		context.AfterFunc(ctx, secret.Destroy)
+	}
+
+	return ctx
+}
+
+// SecretFromContext returns the Secret stored in ctx under key by WithSecret, and
+// whether one was found.
+func SecretFromContext[T any](ctx context.Context, key *ContextKey[T]) (*Secret[T], bool) {
+	secret, ok := ctx.Value(key).(*Secret[T])
+
+	return secret, ok && secret != nil
+}
@@ -0,0 +1,101 @@
+package mattress
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"hash"
+)
+
+// HMAC computes the HMAC of message using the textual form of the data held by the
+// Secret, as passed to WithPlaintext, as the key. The key material is only decrypted
+// for the duration of the computation and is never returned to the caller. HMAC
+// returns nil if the Secret could not be exposed, for example because it has been
+// destroyed. HMAC, like Seal and Open, does not count as an exposure.
+func (s *Secret[T]) HMAC(h func() hash.Hash, message []byte) []byte {
+	var mac []byte
+
+	err := s.withPlaintext(func(key []byte) error {
+		m := hmac.New(h, key)
+		m.Write(message)
+		mac = m.Sum(nil)
+		return nil
+	})
+	if err != nil {
+		return nil
+	}
+
+	return mac
+}
+
+// Seal encrypts and authenticates plaintext with AES-GCM, using the textual form of the
+// data held by the Secret, such as the bytes of a Secret[[]byte], as the key. The key
+// must be 16, 24, or 32 bytes long, selecting AES-128, AES-192, or AES-256. A random
+// nonce is generated for each call and prepended to the returned ciphertext. The key
+// material is only decrypted for the duration of the call.
+func (s *Secret[T]) Seal(plaintext []byte) ([]byte, error) {
+	var ciphertext []byte
+
+	err := s.withAEAD(func(aead cipher.AEAD) error {
+		nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+
+		if _, err := rand.Read(nonce); err != nil {
+			return err
+		}
+
+		ciphertext = aead.Seal(nonce, nonce, plaintext, nil)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ciphertext, nil
+}
+
+// Open decrypts and authenticates ciphertext produced by Seal with the same key,
+// returning ErrDecryptionFailed if it has been tampered with or was sealed under a
+// different key.
+func (s *Secret[T]) Open(ciphertext []byte) ([]byte, error) {
+	var plaintext []byte
+
+	err := s.withAEAD(func(aead cipher.AEAD) error {
+		if len(ciphertext) < aead.NonceSize() {
+			return ErrDecryptionFailed
+		}
+
+		nonce, sealed := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+
+		var err error
+		if plaintext, err = aead.Open(nil, nonce, sealed, nil); err != nil {
+			return ErrDecryptionFailed
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return plaintext, nil
+}
+
+// withAEAD calls fn with an AES-GCM AEAD keyed by the textual form of the data held by
+// the Secret.
+func (s *Secret[T]) withAEAD(fn func(aead cipher.AEAD) error) error {
+	return s.withPlaintext(func(key []byte) error {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return err
+		}
+
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return err
+		}
+
+		return fn(aead)
+	})
+}
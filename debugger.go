@@ -0,0 +1,85 @@
+package mattress
+
+import (
+	"sync"
+	"time"
+)
+
+// DetectDebugger starts a watchdog which checks every interval whether a debugger has
+// attached to the process, as reported by DebuggerAttached, and purges every live
+// Secret, as Purge does, when one does. OnDebugger replaces the purge with a callback.
+// The watchdog fires once each time a debugger attaches, rather than on every check
+// while it remains attached. A non-positive interval stops the watchdog, which is
+// disabled by default.
+func DetectDebugger(interval time.Duration) ConfigureOption {
+	return func(c *globalConfig) {
+		c.debuggerInterval = interval
+	}
+}
+
+// OnDebugger registers fn to be called by the watchdog started with DetectDebugger
+// when a debugger attaches, instead of purging every live Secret. fn may call Purge or
+// SafeExit itself.
+func OnDebugger(fn func()) ConfigureOption {
+	return func(c *globalConfig) {
+		c.onDebugger = fn
+	}
+}
+
+// DebuggerAttached reports whether a debugger is attached to the process: a tracer
+// reported by /proc/self/status on Linux, a traced process on macOS, or
+// IsDebuggerPresent on Windows. It always reports false on other platforms.
+func DebuggerAttached() bool {
+	return debuggerAttached()
+}
+
+// watchdog holds the state of the debugger watchdog.
+var watchdog struct {
+	stop chan struct{} // stop is closed to stop the current watchdog, if any
+	lock sync.Mutex    // synchronize access to the fields above
+}
+
+// watchDebugger replaces any running watchdog with one configured by cfg.
+func watchDebugger(cfg globalConfig) {
+	watchdog.lock.Lock()         // Lock before replacing the watchdog
+	defer watchdog.lock.Unlock() // Ensure the lock is Unlocked when the function returns
+
+	if watchdog.stop != nil {
+		close(watchdog.stop)
+		watchdog.stop = nil
+	}
+
+	if cfg.debuggerInterval <= 0 {
+		return
+	}
+
+	watchdog.stop = make(chan struct{})
+
+	go watch(cfg.debuggerInterval, watchdog.stop, cfg.onDebugger)
+}
+
+// watch checks for a debugger every interval until stop is closed, calling onAttach,
+// or Purge if it is nil, each time one attaches.
+func watch(interval time.Duration, stop <-chan struct{}, onAttach func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	attached := false
+
+	for {
+		select {
+		case <-ticker.C:
+			now := debuggerAttached()
+			if now && !attached {
+				if onAttach != nil {
+					onAttach()
+				} else {
+					Purge()
+				}
+			}
+			attached = now
+		case <-stop:
+			return
+		}
+	}
+}
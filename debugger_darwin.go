@@ -0,0 +1,20 @@
+package mattress
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// pTraced is the P_TRACED process flag, set while the process is being debugged.
+const pTraced = 0x00000800
+
+// debuggerAttached reports whether the kernel has flagged the process as traced.
+func debuggerAttached() bool {
+	info, err := unix.SysctlKinfoProc("kern.proc.pid", os.Getpid())
+	if err != nil {
+		return false
+	}
+
+	return info.Proc.P_flag&pTraced != 0
+}
@@ -0,0 +1,25 @@
+package mattress
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+)
+
+// debuggerAttached reports whether /proc/self/status names a non-zero TracerPid.
+func debuggerAttached() bool {
+	status, err := os.ReadFile("/proc/self/status")
+	if err != nil {
+		return false
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(status))
+	for scanner.Scan() {
+		if pid, ok := bytes.CutPrefix(scanner.Bytes(), []byte("TracerPid:")); ok {
+			pid = bytes.TrimSpace(pid)
+			return len(pid) > 0 && !bytes.Equal(pid, []byte("0"))
+		}
+	}
+
+	return false
+}
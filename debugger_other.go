@@ -0,0 +1,8 @@
+//go:build !linux && !darwin && !windows
+
+package mattress
+
+// debuggerAttached always reports false on platforms without a supported check.
+func debuggerAttached() bool {
+	return false
+}
@@ -0,0 +1,17 @@
+package mattress
+
+import "golang.org/x/sys/windows"
+
+// procIsDebuggerPresent reports whether a user-mode debugger is attached.
+var procIsDebuggerPresent = windows.NewLazySystemDLL("kernel32.dll").NewProc("IsDebuggerPresent")
+
+// debuggerAttached calls IsDebuggerPresent.
+func debuggerAttached() bool {
+	if procIsDebuggerPresent.Find() != nil {
+		return false
+	}
+
+	present, _, _ := procIsDebuggerPresent.Call()
+
+	return present != 0
+}
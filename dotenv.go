@@ -0,0 +1,405 @@
+package mattress
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"runtime"
+	"unsafe"
+
+	"github.com/awnumar/memguard"
+)
+
+// DefaultSensitivePatterns are the key patterns whose values LoadDotenv seals into
+// Secrets unless WithSensitivePatterns is given.
+var DefaultSensitivePatterns = []string{
+	"*PASSWORD",
+	"*PASSWD",
+	"*PASSPHRASE",
+	"*SECRET",
+	"*TOKEN",
+	"*_KEY",
+	"*CREDENTIALS",
+	"*DSN",
+}
+
+// LoadDotenv reads the .env file at path in one pass, directly into guarded memory,
+// and seals the value of every key matching DefaultSensitivePatterns, or the patterns
+// given by WithSensitivePatterns, into a Secret. Every other key is exported to the
+// environment with os.Setenv, unless the environment already defines it. The buffer
+// the file was read into is destroyed before LoadDotenv returns.
+//
+// Each line holds KEY=VALUE, optionally preceded by "export". Values may be unquoted,
+// in which case a " #" begins a comment, single-quoted, which are taken literally, or
+// double-quoted, which interpret the escapes \n, \r, \t, \", \\, and \$. Quoted values
+// may span several lines. Unlike godotenv, variables are never expanded.
+//
+// The returned Secrets are owned by the caller and are created with opts, which also
+// accept WithSizeLimit and WithStrictPermissions as NewSecretFromFile does. It returns
+// an error wrapping ErrSecretNotFound if the file does not exist. If the file cannot
+// be parsed, no Secrets are returned, although keys preceding the error may already
+// have been exported.
+func LoadDotenv(path string, opts ...Option) (map[string]*Secret[string], error) {
+	cfg := newConfig(opts)
+
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("%w: %w", ErrSecretNotFound, err)
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	if cfg.strictPerms && runtime.GOOS != "windows" {
+		info, err := f.Stat()
+		if err != nil {
+			return nil, err
+		}
+
+		if info.Mode().Perm()&0o004 != 0 {
+			return nil, fmt.Errorf("%w: %s", ErrInsecurePermissions, path)
+		}
+	}
+
+	buffer, err := readLocked(f, cfg.sizeLimit)
+	if err != nil {
+		return nil, fmt.Errorf("mattress: reading %s: %w", path, err)
+	}
+	defer buffer.Destroy()
+
+	patterns := cfg.sensitive
+	if patterns == nil {
+		patterns = DefaultSensitivePatterns
+	}
+
+	secrets := make(map[string]*Secret[string])
+
+	err = parseDotenv(buffer.Bytes(), func(key string, value []byte) error {
+		if !sensitive(key, patterns) {
+			if _, ok := os.LookupEnv(key); ok {
+				return nil
+			}
+
+			return os.Setenv(key, string(value))
+		}
+
+		// The value is secured without first being copied into an ordinary string.
+		secret, err := NewSecretString(unsafe.String(unsafe.SliceData(value), len(value)), opts...)
+		if err != nil {
+			return err
+		}
+
+		if previous, ok := secrets[key]; ok {
+			previous.Destroy()
+		}
+
+		secrets[key] = secret
+
+		return nil
+	})
+	if err != nil {
+		for _, secret := range secrets {
+			secret.Destroy()
+		}
+
+		return nil, fmt.Errorf("mattress: parsing %s: %w", path, err)
+	}
+
+	return secrets, nil
+}
+
+// sensitive reports whether key matches any of patterns.
+func sensitive(key string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, key); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseDotenv parses the .env formatted b, as described by LoadDotenv, calling fn with
+// each key and value in turn. The value is only valid for the duration of fn. Errors
+// identify the offending line, but never include any of its content.
+func parseDotenv(b []byte, fn func(key string, value []byte) error) error {
+	p := dotenvParser{b: b, line: 1}
+
+	for {
+		p.skipSpace(true)
+
+		if p.eof() {
+			return nil
+		}
+
+		if p.peek() == '#' {
+			p.skipLine()
+			continue
+		}
+
+		key, err := p.key()
+		if err != nil {
+			return err
+		}
+
+		value, owned, err := p.value()
+		if err != nil {
+			return err
+		}
+
+		err = fn(key, value)
+
+		if owned {
+			// WipeBytes securely erases values which were unescaped into a new slice.
+			memguard.WipeBytes(value)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// dotenvParser holds the state of parseDotenv.
+type dotenvParser struct {
+	b    []byte // b is the .env file being parsed
+	i    int    // i is the offset of the next byte to be parsed
+	line int    // line is the line number of the next byte to be parsed
+}
+
+func (p *dotenvParser) eof() bool {
+	return p.i >= len(p.b)
+}
+
+func (p *dotenvParser) peek() byte {
+	return p.b[p.i]
+}
+
+// errorf returns an error identifying the current line.
+func (p *dotenvParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("line %d: %s", p.line, fmt.Sprintf(format, args...))
+}
+
+// skipSpace skips spaces and tabs, along with line breaks if newlines is true.
+func (p *dotenvParser) skipSpace(newlines bool) {
+	for !p.eof() {
+		switch p.peek() {
+		case ' ', '\t':
+		case '\r', '\n':
+			if !newlines {
+				return
+			}
+
+			if p.peek() == '\n' {
+				p.line++
+			}
+		default:
+			return
+		}
+
+		p.i++
+	}
+}
+
+// skipLine skips to the start of the next line.
+func (p *dotenvParser) skipLine() {
+	for !p.eof() && p.peek() != '\n' {
+		p.i++
+	}
+}
+
+// endLine consumes the remainder of the line following a quoted value, which may only
+// hold whitespace and a comment.
+func (p *dotenvParser) endLine() error {
+	p.skipSpace(false)
+
+	if p.eof() {
+		return nil
+	}
+
+	switch p.peek() {
+	case '#':
+		p.skipLine()
+	case '\r', '\n':
+	default:
+		return p.errorf("unexpected characters after quoted value")
+	}
+
+	return nil
+}
+
+// key parses an optional export keyword, a key, and the following equals sign.
+func (p *dotenvParser) key() (string, error) {
+	if rest := p.b[p.i:]; bytes.HasPrefix(rest, []byte("export")) && len(rest) > len("export") &&
+		(rest[len("export")] == ' ' || rest[len("export")] == '\t') {
+		p.i += len("export")
+		p.skipSpace(false)
+	}
+
+	start := p.i
+	for !p.eof() && isKeyByte(p.peek()) {
+		p.i++
+	}
+
+	if p.i == start {
+		return "", p.errorf("expected a key")
+	}
+
+	key := string(p.b[start:p.i])
+
+	p.skipSpace(false)
+
+	if p.eof() || p.peek() != '=' {
+		return "", p.errorf("expected = after key %s", key)
+	}
+
+	p.i++
+	p.skipSpace(false)
+
+	return key, nil
+}
+
+// isKeyByte reports whether c may appear in a key.
+func isKeyByte(c byte) bool {
+	return c == '_' || c == '.' || c == '-' ||
+		'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9'
+}
+
+// value parses a value, reporting whether it was unescaped into a newly allocated
+// slice rather than sliced from the file.
+func (p *dotenvParser) value() ([]byte, bool, error) {
+	if p.eof() {
+		return nil, false, nil
+	}
+
+	switch p.peek() {
+	case '\'':
+		return p.singleQuoted()
+	case '"':
+		return p.doubleQuoted()
+	default:
+		return p.unquoted(), false, nil
+	}
+}
+
+// unquoted parses an unquoted value, which ends at the end of the line or at a comment
+// preceded by whitespace.
+func (p *dotenvParser) unquoted() []byte {
+	start := p.i
+	end := p.i
+
+	for !p.eof() {
+		c := p.peek()
+
+		if c == '\n' || c == '#' && p.i > start && (p.b[p.i-1] == ' ' || p.b[p.i-1] == '\t') {
+			break
+		}
+
+		p.i++
+
+		if c != ' ' && c != '\t' && c != '\r' {
+			end = p.i
+		}
+	}
+
+	p.skipLine()
+
+	return p.b[start:end]
+}
+
+// singleQuoted parses a single-quoted value, which is taken literally.
+func (p *dotenvParser) singleQuoted() ([]byte, bool, error) {
+	line := p.line
+	p.i++
+	start := p.i
+
+	for !p.eof() && p.peek() != '\'' {
+		if p.peek() == '\n' {
+			p.line++
+		}
+		p.i++
+	}
+
+	if p.eof() {
+		p.line = line
+		return nil, false, p.errorf("unterminated single-quoted value")
+	}
+
+	value := p.b[start:p.i]
+	p.i++
+
+	return value, false, p.endLine()
+}
+
+// doubleQuoted parses a double-quoted value, interpreting its escapes.
+func (p *dotenvParser) doubleQuoted() ([]byte, bool, error) {
+	line := p.line
+	p.i++
+
+	// The value is allocated with enough capacity up front, so that appending to it
+	// never leaves a partial copy behind in a discarded array.
+	value := make([]byte, 0, quotedLen(p.b[p.i:]))
+
+	for !p.eof() && p.peek() != '"' {
+		c := p.peek()
+		p.i++
+
+		switch {
+		case c == '\\' && !p.eof():
+			escaped := p.peek()
+			p.i++
+
+			switch escaped {
+			case 'n':
+				value = append(value, '\n')
+			case 'r':
+				value = append(value, '\r')
+			case 't':
+				value = append(value, '\t')
+			case '"', '\\', '$':
+				value = append(value, escaped)
+			default:
+				value = append(value, c, escaped)
+			}
+		case c == '\n':
+			p.line++
+			value = append(value, c)
+		default:
+			value = append(value, c)
+		}
+	}
+
+	if p.eof() {
+		memguard.WipeBytes(value)
+		p.line = line
+		return nil, false, p.errorf("unterminated double-quoted value")
+	}
+
+	p.i++
+
+	if err := p.endLine(); err != nil {
+		memguard.WipeBytes(value)
+		return nil, false, err
+	}
+
+	return value, true, nil
+}
+
+// quotedLen returns the number of bytes preceding the closing quote of the
+// double-quoted value b begins, which bounds the length of the unescaped value.
+func quotedLen(b []byte) int {
+	for i := 0; i < len(b); i++ {
+		switch b[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+
+	return len(b)
+}
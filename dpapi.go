@@ -0,0 +1,81 @@
+package mattress
+
+// WithDPAPI additionally encrypts the data held by the Secret with CryptProtectMemory on
+// Windows, under a key held by the system for the lifetime of the process, so that
+// memory which is paged out, or captured in a crash dump, holds only ciphertext bound
+// to this process. The encrypted data is kept in guarded memory and decrypted into a
+// temporary LockedBuffer for the duration of each exposure. WithDPAPI takes precedence
+// over WithSealedStorage, and is ignored on other platforms.
+//
+// SaveDPAPI and LoadSecretDPAPI persist Secrets bound to the current user with
+// CryptProtectData.
+func WithDPAPI() Option {
+	return func(c *config) {
+		c.dpapi = true
+	}
+}
+
+// dpapiMagic prefixes files written by SaveDPAPI, identifying the format and its
+// version.
+var dpapiMagic = [4]byte{'M', 'T', 'R', 'D'}
+
+// SaveDPAPI encrypts the data held by the Secret with CryptProtectData, which binds it
+// to the current Windows user, and atomically writes it to the file at path with
+// permissions 0600. The file can be restored with LoadSecretDPAPI by the same user on
+// the same machine, without a passphrase. It returns errors.ErrUnsupported on platforms
+// other than Windows.
+func (s *Secret[T]) SaveDPAPI(path string) error {
+	var ciphertext []byte
+
+	err := s.withBytes(func(b []byte) error {
+		var err error
+		ciphertext, err = protectData(b)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return writeAtomic(path, append(dpapiMagic[:], ciphertext...))
+}
+
+// LoadSecretDPAPI restores a Secret from the file at path, written by SaveDPAPI,
+// decoding it with GobCodec, or the Codec set by WithCodec. ErrDecryptionFailed is
+// returned if the file was written by another user or has been tampered with, and an
+// error wrapping ErrSecretNotFound if the file does not exist. It returns
+// errors.ErrUnsupported on platforms other than Windows.
+func LoadSecretDPAPI[T any](path string, opts ...Option) (*Secret[T], error) {
+	codec, err := codecFor[T](newConfig(opts))
+	if err != nil {
+		return nil, err
+	}
+
+	return LoadSecretDPAPIWithCodec(path, codec, opts...)
+}
+
+// LoadSecretDPAPIWithCodec restores a Secret from the file at path as described by
+// LoadSecretDPAPI, using codec to decode the data.
+func LoadSecretDPAPIWithCodec[T any](path string, codec Codec[T], opts ...Option) (*Secret[T], error) {
+	cfg := newConfig(opts)
+
+	raw, err := readPersisted(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(raw) < len(dpapiMagic) || [4]byte(raw[:len(dpapiMagic)]) != dpapiMagic {
+		return nil, ErrDecryptionFailed
+	}
+
+	buffer, err := unprotectData(raw[len(dpapiMagic):])
+	if err != nil {
+		return nil, err
+	}
+
+	store, err := newStorageFromBuffer(buffer, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return newSecret(store, codec, cfg), nil
+}
@@ -0,0 +1,21 @@
+//go:build !windows
+
+package mattress
+
+import "errors"
+
+// newDPAPIStorage secures b in a LockedBuffer, as WithDPAPI is ignored on platforms
+// other than Windows.
+func newDPAPIStorage(b []byte, cfg config) (storage, error) {
+	return newLockedStorage(b, cfg)
+}
+
+// protectData is unsupported on platforms other than Windows.
+func protectData([]byte) ([]byte, error) {
+	return nil, errors.ErrUnsupported
+}
+
+// unprotectData is unsupported on platforms other than Windows.
+func unprotectData([]byte) (guardedBuffer, error) {
+	return nil, errors.ErrUnsupported
+}
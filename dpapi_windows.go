@@ -0,0 +1,135 @@
+package mattress
+
+import (
+	"runtime"
+	"unsafe"
+
+	"github.com/awnumar/memguard"
+	"golang.org/x/sys/windows"
+)
+
+// cryptProtectMemory flags and block size, from dpapi.h.
+const (
+	cryptProtectMemoryBlockSize   = 16
+	cryptProtectMemorySameProcess = 0
+)
+
+var (
+	procCryptProtectMemory   = windows.NewLazySystemDLL("crypt32.dll").NewProc("CryptProtectMemory")
+	procCryptUnprotectMemory = windows.NewLazySystemDLL("crypt32.dll").NewProc("CryptUnprotectMemory")
+)
+
+// dpapiStorage keeps the data in a LockedBuffer, encrypted with CryptProtectMemory and
+// padded to a whole number of blocks.
+type dpapiStorage struct {
+	buffer  *memguard.LockedBuffer // buffer holds the encrypted, padded data
+	n       int                    // n is the length of the data before padding
+	cleanup runtime.Cleanup        // cleanup destroys the buffer once the storage is garbage collected
+}
+
+// newDPAPIStorage encrypts a copy of b with CryptProtectMemory, wiping b in the process.
+func newDPAPIStorage(b []byte, cfg config) (storage, error) {
+	// WipeBytes securely erases b once it has been copied, or if securing it fails.
+	defer memguard.WipeBytes(b)
+
+	padded := (len(b) + cryptProtectMemoryBlockSize - 1) / cryptProtectMemoryBlockSize * cryptProtectMemoryBlockSize
+
+	if err := reserveLocked(padded); err != nil {
+		return nil, err
+	}
+
+	buffer := memguard.NewBuffer(padded)
+	copy(buffer.Bytes(), b)
+
+	if err := cryptMemory(procCryptProtectMemory, buffer.Bytes()); err != nil {
+		buffer.Destroy()
+		return nil, err
+	}
+
+	buffer.Freeze()
+
+	d := &dpapiStorage{buffer: buffer, n: len(b)}
+
+	// The buffer is destroyed when the storage is garbage collected, as the cleanup of
+	// a lockedStorage destroys its buffer.
+	if !cfg.noCleanup {
+		d.cleanup = runtime.AddCleanup(d, (*memguard.LockedBuffer).Destroy, buffer)
+	}
+
+	return d, nil
+}
+
+func (d *dpapiStorage) view() ([]byte, func(), error) {
+	// The buffer is only destroyed behind the Secret's back by Purge.
+	if !d.buffer.IsAlive() {
+		return nil, nil, ErrDestroyed
+	}
+
+	if err := reserveView(d.buffer.Size()); err != nil {
+		return nil, nil, err
+	}
+
+	plaintext := memguard.NewBuffer(d.buffer.Size())
+	copy(plaintext.Bytes(), d.buffer.Bytes())
+
+	if err := cryptMemory(procCryptUnprotectMemory, plaintext.Bytes()); err != nil {
+		plaintext.Destroy()
+		return nil, nil, err
+	}
+
+	return plaintext.Bytes()[:d.n], plaintext.Destroy, nil
+}
+
+func (d *dpapiStorage) destroy() {
+	d.cleanup.Stop()
+	d.buffer.Destroy()
+}
+
+func (d *dpapiStorage) size() int {
+	return d.n
+}
+
+// cryptMemory calls CryptProtectMemory or CryptUnprotectMemory on b in place.
+func cryptMemory(proc *windows.LazyProc, b []byte) error {
+	if err := proc.Find(); err != nil {
+		return err
+	}
+
+	ok, _, err := proc.Call(uintptr(unsafe.Pointer(unsafe.SliceData(b))), uintptr(len(b)), cryptProtectMemorySameProcess)
+	if ok == 0 {
+		return err
+	}
+
+	return nil
+}
+
+// protectData encrypts b with CryptProtectData for the current user.
+func protectData(b []byte) ([]byte, error) {
+	in := windows.DataBlob{Size: uint32(len(b)), Data: unsafe.SliceData(b)}
+
+	var out windows.DataBlob
+	if err := windows.CryptProtectData(&in, nil, nil, 0, nil, windows.CRYPTPROTECT_UI_FORBIDDEN, &out); err != nil {
+		return nil, err
+	}
+	defer windows.LocalFree(windows.Handle(unsafe.Pointer(out.Data)))
+
+	return append([]byte(nil), unsafe.Slice(out.Data, out.Size)...), nil
+}
+
+// unprotectData decrypts ciphertext written by protectData into guarded memory.
+func unprotectData(ciphertext []byte) (guardedBuffer, error) {
+	in := windows.DataBlob{Size: uint32(len(ciphertext)), Data: unsafe.SliceData(ciphertext)}
+
+	var out windows.DataBlob
+	if err := windows.CryptUnprotectData(&in, nil, nil, 0, nil, windows.CRYPTPROTECT_UI_FORBIDDEN, &out); err != nil {
+		return nil, ErrDecryptionFailed
+	}
+
+	plaintext := unsafe.Slice(out.Data, out.Size)
+	defer windows.LocalFree(windows.Handle(unsafe.Pointer(out.Data)))
+
+	// WipeBytes securely erases the plaintext returned by the system once copied.
+	defer memguard.WipeBytes(plaintext)
+
+	return guardedCopy(plaintext)
+}
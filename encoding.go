@@ -0,0 +1,96 @@
+package mattress
+
+import (
+	"encoding"
+	"fmt"
+	"unsafe"
+)
+
+// MarshalText implements encoding.TextMarshaler, which many serialization libraries
+// (YAML, TOML, expvar and others) fall back to. Under the default SerializationPolicy
+// the Secret is encoded as Placeholder; under RefuseOnSerialize it returns
+// ErrSerializationRefused.
+func (s *Secret[T]) MarshalText() ([]byte, error) {
+	return redactOrRefuse(func() ([]byte, error) {
+		return []byte(Placeholder), nil
+	})
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler, which encoding/gob and other
+// binary serialization libraries fall back to. Under the default SerializationPolicy
+// the Secret is encoded as Placeholder; under RefuseOnSerialize it returns
+// ErrSerializationRefused.
+func (s *Secret[T]) MarshalBinary() ([]byte, error) {
+	return redactOrRefuse(func() ([]byte, error) {
+		return []byte(Placeholder), nil
+	})
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, securing text within the Secret so
+// that configuration libraries which decode text, such as those for YAML, TOML, and
+// environment variables, can decode directly into Secrets. text is converted to a T:
+// strings and byte slices take it as-is, and types whose pointer implements
+// encoding.TextUnmarshaler decode it; any other type fails. Any data the Secret
+// previously held is destroyed, and an uninitialized Secret of string or []byte stores
+// the raw bytes, as NewSecretString and NewSecretBytes do. text is not retained, but
+// remains owned, and so must be wiped, by the caller.
+func (s *Secret[T]) UnmarshalText(text []byte) error {
+	codec, cfg := s.textSettings()
+
+	return s.setText(text, codec, cfg)
+}
+
+// textSettings returns the Codec and configuration the Secret was created with, as
+// settings does, but defaults to storing strings and byte slices as raw bytes.
+func (s *Secret[T]) textSettings() (Codec[T], config) {
+	s.lock.RLock()         // RLock before reading the settings
+	defer s.lock.RUnlock() // Ensure the lock is RUnlocked when the method returns
+
+	if s.codec == nil {
+		return rawCodec[T](), config{}
+	}
+
+	return s.codec, s.cfg
+}
+
+// setText converts text to a T, as described by UnmarshalText, and secures it within
+// the Secret using codec and cfg.
+func (s *Secret[T]) setText(text []byte, codec Codec[T], cfg config) error {
+	var data T
+
+	switch d := any(&data).(type) {
+	case *string:
+		// The string aliases text rather than copying it, as set copies it into
+		// guarded memory and the caller remains responsible for wiping text.
+		*d = unsafe.String(unsafe.SliceData(text), len(text))
+
+		return s.set(data, codec, cfg)
+	case *[]byte:
+		*d = text
+
+		return s.set(data, codec, cfg)
+	case encoding.TextUnmarshaler:
+		if err := d.UnmarshalText(text); err != nil {
+			return err
+		}
+
+		defer wipe(&data)
+
+		return s.set(data, codec, cfg)
+	default:
+		return fmt.Errorf("mattress: cannot decode text into %T", data)
+	}
+}
+
+// rawCodec returns the Codec storing the raw bytes of strings and byte slices, or
+// GobCodec for any other type.
+func rawCodec[T any]() Codec[T] {
+	switch any((*T)(nil)).(type) {
+	case *string:
+		return any(StringCodec{}).(Codec[T])
+	case *[]byte:
+		return any(BytesCodec{}).(Codec[T])
+	default:
+		return GobCodec[T]{}
+	}
+}
@@ -0,0 +1,49 @@
+package mattress
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+// NewSecretFromEnv initializes a new Secret with the value of the environment variable
+// called name and then unsets the variable, so that the plaintext is no longer
+// available to the rest of the process or to child processes. WithEnvOverwrite
+// additionally wipes the value from the environment block the process was started
+// with. It returns an error wrapping ErrSecretNotFound if the variable is not set.
+func NewSecretFromEnv(name string, opts ...Option) (*Secret[string], error) {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return nil, fmt.Errorf("mattress: environment variable %q: %w", name, ErrSecretNotFound)
+	}
+
+	secret, err := NewSecretString(value, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.Unsetenv(name); err != nil {
+		secret.Destroy()
+		return nil, err
+	}
+
+	if newConfig(opts).overwriteEnv {
+		if err := overwriteInitialEnv(name); err != nil {
+			secret.Destroy()
+			return nil, fmt.Errorf("mattress: overwriting environment variable %q: %w", name, err)
+		}
+	}
+
+	return secret, nil
+}
+
+// EnvProvider is a Provider which sources secrets from environment variables using
+// NewSecretFromEnv, unsetting each variable once it has been read.
+type EnvProvider struct {
+	Options []Option // Options are passed to NewSecretFromEnv
+}
+
+// Fetch implements Provider.
+func (p EnvProvider) Fetch(_ context.Context, name string) (*Secret[string], error) {
+	return NewSecretFromEnv(name, p.Options...)
+}
@@ -0,0 +1,120 @@
+package mattress
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+)
+
+// envelopeMagic prefixes blobs produced by Export, identifying the format and its
+// version.
+var envelopeMagic = [4]byte{'M', 'T', 'R', '1'}
+
+// dataKeyLen is the length in bytes of the AES-256 data keys generated by Export.
+const dataKeyLen = 32
+
+// Wrapper wraps and unwraps data keys using a key encryption key held elsewhere, such as
+// in a cloud KMS. Implementations are provided by mattressaws, mattressgcp,
+// mattressage, mattresstpm, and mattresspkcs11.
+type Wrapper interface {
+	// WrapKey encrypts the data key held by key, returning the wrapped key.
+	WrapKey(ctx context.Context, key *Secret[[]byte]) ([]byte, error)
+
+	// UnwrapKey decrypts a key wrapped by WrapKey, returning it as a Secret owned by
+	// the caller.
+	UnwrapKey(ctx context.Context, wrapped []byte) (*Secret[[]byte], error)
+}
+
+// Export encrypts the data held by the Secret under a freshly generated data key,
+// which is in turn wrapped by w, and returns the resulting blob. The blob may be
+// persisted or transferred freely and restored with ImportSecret given a Wrapper able
+// to unwrap the data key.
+func (s *Secret[T]) Export(ctx context.Context, w Wrapper) ([]byte, error) {
+	raw := make([]byte, dataKeyLen)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, err
+	}
+
+	// NewSecretBytes wipes the generated data key once it has been secured.
+	dataKey, err := NewSecretBytes(raw)
+	if err != nil {
+		return nil, err
+	}
+	defer dataKey.Destroy()
+
+	var ciphertext []byte
+
+	err = s.withBytes(func(b []byte) error {
+		var err error
+		ciphertext, err = dataKey.Seal(b)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	wrapped, err := w.WrapKey(ctx, dataKey)
+	if err != nil {
+		return nil, fmt.Errorf("mattress: wrapping data key: %w", err)
+	}
+
+	blob := make([]byte, 0, len(envelopeMagic)+4+len(wrapped)+len(ciphertext))
+	blob = append(blob, envelopeMagic[:]...)
+	blob = binary.BigEndian.AppendUint32(blob, uint32(len(wrapped)))
+	blob = append(blob, wrapped...)
+	blob = append(blob, ciphertext...)
+
+	return blob, nil
+}
+
+// ImportSecret restores a Secret from a blob produced by Export, using w to unwrap its
+// data key. The data is decoded with GobCodec, or the Codec set by WithCodec, so
+// Secrets created with a different Codec, such as by NewSecretString, must be imported
+// with that Codec. ErrDecryptionFailed is returned if the blob is malformed or has been
+// tampered with.
+func ImportSecret[T any](ctx context.Context, w Wrapper, blob []byte, opts ...Option) (*Secret[T], error) {
+	codec, err := codecFor[T](newConfig(opts))
+	if err != nil {
+		return nil, err
+	}
+
+	return ImportSecretWithCodec(ctx, w, blob, codec, opts...)
+}
+
+// ImportSecretWithCodec restores a Secret from a blob produced by Export, as described
+// by ImportSecret, using codec to decode the data.
+func ImportSecretWithCodec[T any](ctx context.Context, w Wrapper, blob []byte, codec Codec[T], opts ...Option) (*Secret[T], error) {
+	header := len(envelopeMagic) + 4
+	if len(blob) < header || [4]byte(blob[:len(envelopeMagic)]) != envelopeMagic {
+		return nil, ErrDecryptionFailed
+	}
+
+	n := binary.BigEndian.Uint32(blob[len(envelopeMagic):header])
+	if uint64(n) > uint64(len(blob)-header) {
+		return nil, ErrDecryptionFailed
+	}
+
+	wrapped, ciphertext := blob[header:header+int(n)], blob[header+int(n):]
+
+	dataKey, err := w.UnwrapKey(ctx, wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("mattress: unwrapping data key: %w", err)
+	}
+	defer dataKey.Destroy()
+
+	plaintext, err := dataKey.Open(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := newConfig(opts)
+
+	// newStorage wipes the decrypted data once it has been secured.
+	store, err := newStorage(plaintext, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return newSecret(store, codec, cfg), nil
+}
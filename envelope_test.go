@@ -0,0 +1,54 @@
+package mattress_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	m "github.com/garrettladley/mattress"
+)
+
+// plainWrapper is a Wrapper which leaves data keys unwrapped, so that fuzzed blobs
+// reach the decryption of the data itself.
+type plainWrapper struct{}
+
+func (plainWrapper) WrapKey(_ context.Context, key *m.Secret[[]byte]) ([]byte, error) {
+	return key.ExposeErr()
+}
+
+func (plainWrapper) UnwrapKey(_ context.Context, wrapped []byte) (*m.Secret[[]byte], error) {
+	return m.NewSecretBytes(bytes.Clone(wrapped))
+}
+
+// FuzzImportSecret checks that ImportSecret rejects rather than panics on malformed
+// envelopes, and that any envelope it accepts holds what Export wrote.
+func FuzzImportSecret(f *testing.F) {
+	secret, err := m.NewSecret("hunter2")
+	if err != nil {
+		f.Fatal(err)
+	}
+	defer secret.Destroy()
+
+	blob, err := secret.Export(context.Background(), plainWrapper{})
+	if err != nil {
+		f.Fatal(err)
+	}
+
+	f.Add(blob)
+	f.Add(blob[:len(blob)-1])
+	f.Add(blob[:8])
+	f.Add([]byte("MTR1\xff\xff\xff\xff"))
+	f.Add([]byte{})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		imported, err := m.ImportSecret[string](context.Background(), plainWrapper{}, data)
+		if err != nil {
+			return
+		}
+		defer imported.Destroy()
+
+		if got, err := imported.ExposeErr(); err != nil || got != "hunter2" {
+			t.Fatalf("importing %x: got %q, %v", data, got, err)
+		}
+	})
+}
@@ -0,0 +1,93 @@
+package mattress
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/awnumar/memguard"
+)
+
+// overwriteInitialEnv overwrites the value of the environment variable called name
+// within the environment block the process was started with. The Go runtime copies
+// the environment at startup, so unsetting a variable leaves this original block, and
+// therefore /proc/self/environ, untouched.
+func overwriteInitialEnv(name string) error {
+	start, end, err := initialEnvBounds()
+	if err != nil {
+		return err
+	}
+
+	mem, err := os.OpenFile("/proc/self/mem", os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer mem.Close()
+
+	block := make([]byte, end-start)
+	if _, err := mem.ReadAt(block, start); err != nil {
+		return err
+	}
+
+	prefix := []byte(name + "=")
+
+	for offset := 0; offset < len(block); {
+		entry := block[offset:]
+		if i := bytes.IndexByte(entry, 0); i >= 0 {
+			entry = entry[:i]
+		}
+
+		if bytes.HasPrefix(entry, prefix) {
+			value := entry[len(prefix):]
+
+			if _, err := mem.WriteAt(make([]byte, len(value)), start+int64(offset+len(prefix))); err != nil {
+				return err
+			}
+		}
+
+		offset += len(entry) + 1
+	}
+
+	// WipeBytes securely erases the copy of the environment block.
+	memguard.WipeBytes(block)
+
+	return nil
+}
+
+// initialEnvBounds returns the addresses of the start and end of the environment
+// block the process was started with, as reported by /proc/self/stat.
+func initialEnvBounds() (int64, int64, error) {
+	stat, err := os.ReadFile("/proc/self/stat")
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// The command name in the second field may itself contain spaces and parentheses,
+	// so fields are counted from after its closing parenthesis, which ends field 2.
+	i := bytes.LastIndexByte(stat, ')')
+	if i < 0 {
+		return 0, 0, fmt.Errorf("malformed /proc/self/stat")
+	}
+
+	fields := bytes.Fields(stat[i+1:])
+
+	// env_start and env_end are fields 50 and 51.
+	const envStart, envEnd = 50 - 3, 51 - 3
+
+	if len(fields) <= envEnd {
+		return 0, 0, fmt.Errorf("/proc/self/stat does not report the environment block")
+	}
+
+	start, err := strconv.ParseInt(string(fields[envStart]), 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	end, err := strconv.ParseInt(string(fields[envEnd]), 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return start, end, nil
+}
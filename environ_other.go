@@ -0,0 +1,9 @@
+//go:build !linux
+
+package mattress
+
+// overwriteInitialEnv is a no-op on platforms other than Linux, which do not expose
+// the initial environment block in the same way.
+func overwriteInitialEnv(string) error {
+	return nil
+}
@@ -0,0 +1,69 @@
+package mattress
+
+import (
+	"crypto/subtle"
+	"unsafe"
+
+	"github.com/awnumar/memguard"
+)
+
+// Equal reports whether s and other hold the same data, comparing their encoded
+// representations in constant time with crypto/subtle so that neither value is
+// exposed into regular memory and the comparison does not leak timing information
+// about their contents. Only the lengths of the encoded representations may be
+// inferred from timing.
+//
+// Both Secrets must use the same Codec, and that Codec must encode equal values
+// identically: for example, encoding/gob does not encode maps deterministically.
+// Equal returns false if either Secret is nil, uninitialized, or destroyed.
+func (s *Secret[T]) Equal(other *Secret[T]) bool {
+	if s == other {
+		return s.withBytes(func([]byte) error { return nil }) == nil
+	}
+
+	// Acquire the locks in a consistent order, by address, so that concurrent
+	// comparisons of the same pair of Secrets cannot deadlock.
+	first, second := s, other
+	if uintptr(unsafe.Pointer(first)) > uintptr(unsafe.Pointer(second)) {
+		first, second = second, first
+	}
+
+	var equal bool
+
+	err := first.withBytes(func(a []byte) error {
+		return second.withBytes(func(b []byte) error {
+			equal = subtle.ConstantTimeCompare(a, b) == 1
+			return nil
+		})
+	})
+
+	return err == nil && equal
+}
+
+// EqualBytes reports whether the textual form of the data held by s, as passed to
+// WithPlaintext, equals b. The comparison is performed in constant time with
+// crypto/subtle, making it suitable for checking credentials such as API tokens
+// presented by a client. Only the length of the plaintext may be inferred from
+// timing. EqualBytes returns false if s has been destroyed. Like Equal, it does not
+// count as an exposure.
+func (s *Secret[T]) EqualBytes(b []byte) bool {
+	var equal bool
+
+	err := s.withPlaintext(func(plaintext []byte) error {
+		equal = subtle.ConstantTimeCompare(plaintext, b) == 1
+		return nil
+	})
+
+	return err == nil && equal
+}
+
+// EqualString reports whether the textual form of the data held by s equals str, as
+// described by EqualBytes.
+func (s *Secret[T]) EqualString(str string) bool {
+	b := []byte(str)
+
+	// WipeBytes securely erases the copy of str once compared.
+	defer memguard.WipeBytes(b)
+
+	return s.EqualBytes(b)
+}
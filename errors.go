@@ -0,0 +1,99 @@
+package mattress
+
+import "errors"
+
+// ErrDestroyed is returned when an operation is attempted on a Secret whose
+// underlying buffer has already been destroyed.
+var ErrDestroyed = errors.New("mattress: secret has been destroyed")
+
+// ErrSerializationRefused is returned when a Secret is asked to serialize itself while
+// the SerializationPolicy is RefuseOnSerialize.
+var ErrSerializationRefused = errors.New("mattress: refusing to serialize secret")
+
+// ErrProviderNotFound is returned when a secret is fetched from a Provider that has
+// not been registered.
+var ErrProviderNotFound = errors.New("mattress: provider not found")
+
+// ErrSecretNotFound is returned, possibly wrapped, by a Provider when the requested
+// secret does not exist.
+var ErrSecretNotFound = errors.New("mattress: secret not found")
+
+// ErrSecretTooLarge is returned when a secret being read exceeds the configured size
+// limit.
+var ErrSecretTooLarge = errors.New("mattress: secret exceeds size limit")
+
+// ErrInsecurePermissions is returned when a secret file is readable by any user and
+// WithStrictPermissions is in effect.
+var ErrInsecurePermissions = errors.New("mattress: secret file is world-readable")
+
+// ErrMemlockExhausted is returned when securing data would need more locked memory than
+// the process may lock, as limited by RLIMIT_MEMLOCK. CheckEnvironment reports the
+// budget.
+var ErrMemlockExhausted = errors.New("mattress: locked memory limit exhausted")
+
+// ErrPromptInterrupted is returned by PromptSecret when the user interrupts the prompt
+// with Ctrl-C.
+var ErrPromptInterrupted = errors.New("mattress: prompt interrupted")
+
+// ErrDecryptionFailed is returned when ciphertext cannot be decrypted, either because
+// it has been tampered with or because it was encrypted under a different key.
+var ErrDecryptionFailed = errors.New("mattress: decryption failed")
+
+// ErrInvalidHash is returned by VerifyPassword when the encoded password hash is
+// malformed or uses an unsupported algorithm.
+var ErrInvalidHash = errors.New("mattress: invalid password hash")
+
+// ErrInvalidShares is returned by Combine when the shares are malformed, of differing
+// lengths, or duplicated.
+var ErrInvalidShares = errors.New("mattress: invalid secret shares")
+
+// ErrSecretExpired is returned when a Secret created with WithTTL or WithMaxExposures is
+// exposed after its deadline has passed or its exposures have been used up.
+var ErrSecretExpired = errors.New("mattress: secret has expired")
+
+// ErrNoPreviousVersion is returned by VersionedSecret.Rollback when there is no earlier
+// version to roll back to.
+var ErrNoPreviousVersion = errors.New("mattress: no previous version")
+
+// ErrCopied is returned when a Secret which has been copied by value, rather than
+// passed by pointer, is exposed.
+var ErrCopied = errors.New("mattress: secret was copied by value")
+
+// ErrUninitialized is returned when a nil Secret, or a zero-value Secret which was never
+// given any data, is exposed.
+var ErrUninitialized = errors.New("mattress: secret is uninitialized")
+
+// ErrValidationFailed is wrapped by each ValidationError returned by Validate.
+var ErrValidationFailed = errors.New("mattress: secret failed validation")
+
+// ErrUnsupportedType is returned, wrapped with the offending field, when GobCodec is
+// used with a type which encoding/gob cannot serialize, such as one with no exported
+// fields.
+var ErrUnsupportedType = errors.New("mattress: type cannot be serialized by encoding/gob")
+
+// ErrExposureRateExceeded is returned when a Secret created with WithExposureRateLimit
+// is exposed more often than its limit allows.
+var ErrExposureRateExceeded = errors.New("mattress: secret exposure rate exceeded")
+
+// ErrRedactionFailed is returned, wrapping the underlying error, when output cannot be
+// redacted because the plaintext of one of the secrets to redact cannot be obtained.
+var ErrRedactionFailed = errors.New("mattress: cannot redact secret")
+
+// labelError annotates an error returned by a Secret created with WithLabel with its
+// label, while still matching the underlying error with errors.Is.
+type labelError struct {
+	label string // label is the label of the Secret
+	err   error  // err is the underlying error
+}
+
+func (e *labelError) Error() string {
+	return e.err.Error() + " [" + e.label + "]"
+}
+
+func (e *labelError) Unwrap() error {
+	return e.err
+}
+
+// ErrInvalidTag is returned by Process when a mattress struct tag is malformed or is
+// attached to a field which is not a *Secret.
+var ErrInvalidTag = errors.New("mattress: invalid struct tag")
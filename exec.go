@@ -0,0 +1,122 @@
+package mattress
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// inheritEnvPrefix prefixes the environment variables naming the descriptors passed to
+// a child by PassToCommand.
+const inheritEnvPrefix = "MATTRESS_FD_"
+
+// PassToCommand arranges for the data held by the Secret to be inherited by the child
+// process cmd starts, under name, without placing it in the environment or the
+// arguments of the child. The data is written to an anonymous file, a sealed memfd on
+// Linux or a pipe on other Unix systems, which is appended to cmd.ExtraFiles; only the
+// number of the descriptor is placed in the environment of the child, which recovers
+// the Secret with InheritSecret. It must be called before cmd is started, and counts
+// as an exposure of the Secret.
+//
+// Once cmd has been started, or if it fails to start, the caller must call release to
+// close the copy of the descriptor held by the current process. PassToCommand returns
+// errors.ErrUnsupported on platforms which cannot pass descriptors to children.
+func (s *Secret[T]) PassToCommand(cmd *exec.Cmd, name string) (release func() error, err error) {
+	if name == "" || strings.ContainsAny(name, "=\x00") {
+		return nil, fmt.Errorf("mattress: invalid inherited secret name %q", name)
+	}
+
+	if cmd.Process != nil {
+		return nil, errors.New("mattress: PassToCommand after the command has started")
+	}
+
+	var f *os.File
+
+	err = s.withExposure(func(b []byte) error {
+		var err error
+		f, err = inheritedFile(name, b)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// ExtraFiles[i] becomes descriptor 3+i in the child, after stdin, stdout, and stderr.
+	fd := 3 + len(cmd.ExtraFiles)
+
+	cmd.ExtraFiles = append(cmd.ExtraFiles, f)
+
+	if cmd.Env == nil {
+		cmd.Env = os.Environ()
+	}
+
+	cmd.Env = append(cmd.Env, inheritEnvPrefix+name+"="+strconv.Itoa(fd))
+
+	return f.Close, nil
+}
+
+// InheritSecret recovers the Secret passed to the current process under name by its
+// parent with PassToCommand. The data is read directly into guarded memory and
+// decoded with GobCodec, or the Codec set by WithCodec, so Secrets created with a
+// different Codec, such as by NewSecretString, must be inherited with that Codec. The
+// descriptor is closed and its environment variable unset once read, so that neither
+// is passed on to further children. It returns an error wrapping ErrSecretNotFound if
+// no Secret was passed under name.
+func InheritSecret[T any](name string, opts ...Option) (*Secret[T], error) {
+	codec, err := codecFor[T](newConfig(opts))
+	if err != nil {
+		return nil, err
+	}
+
+	return InheritSecretWithCodec(name, codec, opts...)
+}
+
+// InheritSecretWithCodec recovers the Secret passed to the current process under name
+// as described by InheritSecret, using codec to decode the data.
+func InheritSecretWithCodec[T any](name string, codec Codec[T], opts ...Option) (*Secret[T], error) {
+	cfg := newConfig(opts)
+
+	key := inheritEnvPrefix + name
+
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return nil, fmt.Errorf("mattress: inherited secret %q: %w", name, ErrSecretNotFound)
+	}
+
+	if err := os.Unsetenv(key); err != nil {
+		return nil, err
+	}
+
+	fd, err := strconv.Atoi(value)
+	if err != nil || fd < 3 {
+		return nil, fmt.Errorf("mattress: inherited secret %q: invalid descriptor %q", name, value)
+	}
+
+	f := os.NewFile(uintptr(fd), name)
+	if f == nil {
+		return nil, fmt.Errorf("mattress: inherited secret %q: invalid descriptor %q", name, value)
+	}
+	defer f.Close()
+
+	// The descriptor shares its offset with the parent, so a memfd is read from the start.
+	_, _ = f.Seek(0, 0)
+
+	buffer, err := readAll(f)
+	if err != nil {
+		return nil, fmt.Errorf("mattress: inherited secret %q: %w", name, err)
+	}
+
+	// Truncating a memfd releases its pages once the data has been secured. Pipes cannot
+	// be truncated, and have already been drained.
+	_ = f.Truncate(0)
+
+	store, err := newStorageFromBuffer(buffer, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return newSecret(store, codec, cfg), nil
+}
@@ -0,0 +1,37 @@
+package mattress
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// inheritedFile returns a memfd holding b, sealed against any further writes, to be
+// inherited by a child process. The memfd can still be truncated, so that the child
+// can release its pages once it has read them.
+func inheritedFile(name string, b []byte) (*os.File, error) {
+	fd, err := unix.MemfdCreate("mattress:"+name, unix.MFD_CLOEXEC|unix.MFD_ALLOW_SEALING)
+	if err != nil {
+		return nil, err
+	}
+
+	f := os.NewFile(uintptr(fd), "mattress:"+name)
+
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	if _, err := unix.FcntlInt(f.Fd(), unix.F_ADD_SEALS, unix.F_SEAL_WRITE|unix.F_SEAL_GROW|unix.F_SEAL_SEAL); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	// The child shares the offset of the memfd, so it is rewound for it to read from.
+	if _, err := f.Seek(0, 0); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return f, nil
+}
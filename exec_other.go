@@ -0,0 +1,14 @@
+//go:build !unix
+
+package mattress
+
+import (
+	"errors"
+	"os"
+)
+
+// inheritedFile returns errors.ErrUnsupported on platforms other than Unix, whose
+// children cannot inherit descriptors through exec.Cmd.ExtraFiles.
+func inheritedFile(string, []byte) (*os.File, error) {
+	return nil, errors.ErrUnsupported
+}
@@ -0,0 +1,33 @@
+//go:build unix && !linux
+
+package mattress
+
+import (
+	"os"
+)
+
+// inheritedFile returns the read end of a pipe through which b is written, to be
+// inherited by a child process. b is copied into guarded memory and written in the
+// background, since it may exceed the capacity of the pipe; the copy is destroyed once
+// the child has read it, or once every copy of the read end has been closed.
+func inheritedFile(_ string, b []byte) (*os.File, error) {
+	buffer, err := guardedCopy(b)
+	if err != nil {
+		return nil, err
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		buffer.Destroy()
+		return nil, err
+	}
+
+	go func() {
+		defer buffer.Destroy()
+		defer w.Close()
+
+		_, _ = w.Write(buffer.Bytes())
+	}()
+
+	return r, nil
+}
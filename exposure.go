@@ -0,0 +1,136 @@
+package mattress
+
+import (
+	"bytes"
+	"reflect"
+	"unsafe"
+
+	"github.com/awnumar/memguard"
+)
+
+// Flush drops the decoded copy of the data kept by WithCachedExposure, wiping it. The
+// next exposure decodes the data again and repopulates the cache.
+func (s *Secret[T]) Flush() {
+	if s == nil {
+		return
+	}
+
+	s.lock.Lock()         // Lock before dropping the cache
+	defer s.lock.Unlock() // Ensure the lock is Unlocked when the method returns
+
+	s.flushCache()
+}
+
+// decode decodes b, copying the value out of the exposure cache instead if it has
+// been populated, and populating it otherwise. The caller must hold the lock.
+func (s *Secret[T]) decode(b []byte) (T, error) {
+	if !s.cacheable {
+		return s.codec.Decode(b)
+	}
+
+	if cache := s.cache.Load(); cache != nil {
+		return fromCache[T](cache.Bytes()), nil
+	}
+
+	data, err := s.codec.Decode(b)
+	if err != nil {
+		return data, err
+	}
+
+	// Empty strings and byte slices are cheap to decode, and a LockedBuffer cannot
+	// hold them, so they are not cached.
+	raw := cacheBytes(&data)
+	if len(raw) == 0 {
+		return data, nil
+	}
+
+	// Exposures only hold the read lock, so a concurrent exposure may have populated
+	// the cache first, in which case this copy is discarded.
+	cache := memguard.NewBuffer(len(raw))
+	cache.Copy(raw)
+
+	if !s.cache.CompareAndSwap(nil, cache) {
+		cache.Destroy()
+	}
+
+	return data, nil
+}
+
+// cacheBytes returns the memory the exposure cache keeps of data: the contents of a
+// string or byte slice, or otherwise data itself, which holds no pointers.
+func cacheBytes[T any](data *T) []byte {
+	switch reflect.TypeFor[T]().Kind() {
+	case reflect.String:
+		str := *(*string)(unsafe.Pointer(data))
+		return unsafe.Slice(unsafe.StringData(str), len(str))
+	case reflect.Slice:
+		return *(*[]byte)(unsafe.Pointer(data))
+	default:
+		return unsafe.Slice((*byte)(unsafe.Pointer(data)), unsafe.Sizeof(*data))
+	}
+}
+
+// fromCache returns the value whose memory cacheBytes returned as raw. Strings and byte
+// slices are copied out of raw, so that the value outlives the cache.
+func fromCache[T any](raw []byte) T {
+	var data T
+
+	switch reflect.TypeFor[T]().Kind() {
+	case reflect.String:
+		*(*string)(unsafe.Pointer(&data)) = string(raw)
+	case reflect.Slice:
+		*(*[]byte)(unsafe.Pointer(&data)) = bytes.Clone(raw)
+	default:
+		copy(unsafe.Slice((*byte)(unsafe.Pointer(&data)), unsafe.Sizeof(data)), raw)
+	}
+
+	return data
+}
+
+// flushCache destroys the exposure cache, if populated. The caller must hold the lock
+// for writing.
+func (s *Secret[T]) flushCache() {
+	if cache := s.cache.Swap(nil); cache != nil {
+		cache.Destroy()
+	}
+}
+
+// cacheable reports whether values of type t can be kept by the exposure cache: strings,
+// byte slices, and types whose values contain no pointers.
+func cacheable(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.String:
+		return true
+	case reflect.Slice:
+		return t.Elem().Kind() == reflect.Uint8
+	default:
+		return pointerFree(t)
+	}
+}
+
+// pointerFree reports whether values of type t contain no pointers, and are non-empty,
+// so that their memory can be copied into and out of a LockedBuffer as-is.
+func pointerFree(t reflect.Type) bool {
+	if t.Size() == 0 {
+		return false
+	}
+
+	switch t.Kind() {
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		return true
+	case reflect.Array:
+		return pointerFree(t.Elem())
+	case reflect.Struct:
+		for i := range t.NumField() {
+			if f := t.Field(i).Type; f.Size() != 0 && !pointerFree(f) {
+				return false
+			}
+		}
+		return true
+	default:
+		return false
+	}
+}
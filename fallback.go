@@ -0,0 +1,227 @@
+package mattress
+
+import (
+	"crypto/rand"
+	"log/slog"
+	"os"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"weak"
+
+	"github.com/awnumar/memcall"
+	"github.com/awnumar/memguard"
+)
+
+// WithUnlockedFallback allows Secrets to be created where memory cannot be locked, such
+// as in containers with a memlock limit of zero or which forbid mlock altogether, rather
+// than memguard panicking on the first allocation. Whether memory can be locked is
+// probed once; if it cannot, a warning is logged with log/slog and Secrets hold their
+// data in regular heap memory, masked with a random one-time pad of the same length.
+//
+// Masking keeps the plaintext out of naive scans of memory, but unlike guarded memory
+// the data may be swapped to disk or included in core dumps, and WithSealedStorage and
+// WithCachedExposure have no effect. Degraded reports whether the fallback is in use.
+func WithUnlockedFallback(enabled bool) ConfigureOption {
+	return func(c *globalConfig) {
+		c.fallback = enabled
+	}
+}
+
+// fallbackAllowed reports whether WithUnlockedFallback is enabled.
+var fallbackAllowed atomic.Bool
+
+// lockable reports whether memory can be locked, probing by locking a single page the
+// first time it is called.
+var lockable = sync.OnceValue(func() bool {
+	page, err := memcall.Alloc(os.Getpagesize())
+	if err != nil {
+		return false
+	}
+	defer memcall.Free(page)
+
+	if err := memcall.Lock(page); err != nil {
+		return false
+	}
+
+	return memcall.Unlock(page) == nil
+})
+
+// warnDegraded logs that the fallback is in use the first time it is called.
+var warnDegraded = sync.OnceFunc(func() {
+	slog.Warn("mattress: memory cannot be locked; secrets are held in masked heap memory")
+})
+
+// Degraded reports whether Secrets are held in masked heap memory rather than guarded
+// memory, because WithUnlockedFallback is enabled and memory cannot be locked.
+func Degraded() bool {
+	if !fallbackAllowed.Load() || lockable() {
+		return false
+	}
+
+	warnDegraded()
+
+	return true
+}
+
+// guardedBuffer holds bytes being assembled before they are secured: a
+// memguard.LockedBuffer, or a heapBuffer if Degraded.
+type guardedBuffer interface {
+	Bytes() []byte
+	Size() int
+	Destroy()
+}
+
+// newGuardedBuffer returns a zeroed guardedBuffer of size bytes, or ErrMemlockExhausted
+// if the pages it needs cannot be locked.
+func newGuardedBuffer(size int) (guardedBuffer, error) {
+	return allocGuarded(size, reserveLocked)
+}
+
+// newViewBuffer is newGuardedBuffer for short-lived buffers, such as those a Secret is
+// decrypted into while exposed, which are checked with reserveView rather than
+// reserveLocked.
+func newViewBuffer(size int) (guardedBuffer, error) {
+	return allocGuarded(size, reserveView)
+}
+
+// allocGuarded returns a zeroed guardedBuffer of size bytes, once reserve has checked
+// that the pages it needs can be locked.
+func allocGuarded(size int, reserve func(size int) error) (guardedBuffer, error) {
+	if Degraded() {
+		return &heapBuffer{b: make([]byte, size)}, nil
+	}
+
+	if err := reserve(size); err != nil {
+		return nil, err
+	}
+
+	return memguard.NewBuffer(size), nil
+}
+
+// heapBuffer stands in for a memguard.LockedBuffer when memory cannot be locked.
+type heapBuffer struct {
+	b []byte
+}
+
+func (h *heapBuffer) Bytes() []byte { return h.b }
+
+func (h *heapBuffer) Size() int { return len(h.b) }
+
+func (h *heapBuffer) Destroy() {
+	memguard.WipeBytes(h.b)
+	h.b = nil
+}
+
+// maskedStorage keeps the data in regular heap memory, XORed with a random mask of the
+// same length, for use when memory cannot be locked. Exposure unmasks the data into a
+// temporary slice which is wiped on release.
+type maskedStorage struct {
+	data []byte     // data is the encoded bytes XORed with mask
+	mask []byte     // mask is the random one-time pad
+	id   uint64     // id identifies the storage in the masked registry
+	lock sync.Mutex // synchronize access to the fields above
+}
+
+// masked records every live maskedStorage, so that Purge can wipe them as memguard
+// wipes its own containers.
+var masked struct {
+	entries map[uint64]weak.Pointer[maskedStorage] // entries maps IDs to live storages
+	next    uint64                                 // next is the ID of the next storage
+	lock    sync.Mutex                             // synchronize access to the fields above
+}
+
+// newMaskedStorage masks a copy of b, wiping b in the process.
+func newMaskedStorage(b []byte) *maskedStorage {
+	// WipeBytes securely erases b once it has been masked.
+	defer memguard.WipeBytes(b)
+
+	m := &maskedStorage{data: make([]byte, len(b)), mask: make([]byte, len(b))}
+
+	// Read never returns an error, crashing the program if randomness is unavailable.
+	rand.Read(m.mask)
+
+	for i := range b {
+		m.data[i] = b[i] ^ m.mask[i]
+	}
+
+	masked.lock.Lock()         // Lock before adding the entry
+	defer masked.lock.Unlock() // Ensure the lock is Unlocked when the function returns
+
+	if masked.entries == nil {
+		masked.entries = make(map[uint64]weak.Pointer[maskedStorage])
+	}
+
+	masked.next++
+	m.id = masked.next
+	masked.entries[m.id] = weak.Make(m)
+
+	// The masked bytes are wiped when the storage is garbage collected, as the
+	// cleanup of a lockedStorage destroys its buffer.
+	runtime.AddCleanup(m, unmask, maskedCleanup{id: m.id, data: m.data, mask: m.mask})
+
+	return m
+}
+
+// maskedCleanup holds what must be wiped once a maskedStorage is garbage collected.
+type maskedCleanup struct {
+	id         uint64
+	data, mask []byte
+}
+
+// unmask wipes the bytes of a garbage collected maskedStorage and removes its entry.
+func unmask(c maskedCleanup) {
+	memguard.WipeBytes(c.data)
+	memguard.WipeBytes(c.mask)
+
+	masked.lock.Lock()         // Lock before removing the entry
+	defer masked.lock.Unlock() // Ensure the lock is Unlocked when the function returns
+
+	delete(masked.entries, c.id)
+}
+
+func (m *maskedStorage) view() ([]byte, func(), error) {
+	m.lock.Lock()         // Lock before unmasking the data
+	defer m.lock.Unlock() // Ensure the lock is Unlocked when the method returns
+
+	// The data is only wiped behind the Secret's back by Purge.
+	if m.data == nil {
+		return nil, nil, ErrDestroyed
+	}
+
+	b := make([]byte, len(m.data))
+	for i := range b {
+		b[i] = m.data[i] ^ m.mask[i]
+	}
+
+	return b, func() { memguard.WipeBytes(b) }, nil
+}
+
+func (m *maskedStorage) destroy() {
+	m.lock.Lock()         // Lock before wiping the data
+	defer m.lock.Unlock() // Ensure the lock is Unlocked when the method returns
+
+	memguard.WipeBytes(m.data)
+	memguard.WipeBytes(m.mask)
+	m.data, m.mask = nil, nil
+}
+
+func (m *maskedStorage) size() int {
+	m.lock.Lock()         // Lock before reading the data
+	defer m.lock.Unlock() // Ensure the lock is Unlocked when the method returns
+
+	return len(m.data)
+}
+
+// purgeMasked wipes every live maskedStorage.
+func purgeMasked() {
+	masked.lock.Lock()         // Lock before reading the entries
+	defer masked.lock.Unlock() // Ensure the lock is Unlocked when the function returns
+
+	for id, entry := range masked.entries {
+		if m := entry.Value(); m != nil {
+			m.destroy()
+		}
+		delete(masked.entries, id)
+	}
+}
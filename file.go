@@ -0,0 +1,68 @@
+package mattress
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"runtime"
+)
+
+// NewSecretFromFile initializes a new Secret with the contents of the file at path.
+// The contents are streamed directly into guarded memory, never passing through a
+// slice on the regular heap. WithSizeLimit bounds the number of bytes read and
+// WithStrictPermissions refuses to read world-readable files. It returns an error
+// wrapping ErrSecretNotFound if the file does not exist.
+func NewSecretFromFile(path string, opts ...Option) (*Secret[[]byte], error) {
+	return newSecretFromFile[[]byte](path, BytesCodec{}, opts)
+}
+
+// FileProvider is a Provider which sources secrets from files, treating each name as
+// a path.
+type FileProvider struct {
+	Options []Option // Options are applied to each Secret read
+}
+
+// Fetch implements Provider.
+func (p FileProvider) Fetch(_ context.Context, name string) (*Secret[string], error) {
+	return newSecretFromFile[string](name, StringCodec{}, p.Options)
+}
+
+// newSecretFromFile reads the file at path into a Secret as described by
+// NewSecretFromFile. codec must interpret the raw file contents as a T.
+func newSecretFromFile[T any](path string, codec Codec[T], opts []Option) (*Secret[T], error) {
+	cfg := newConfig(opts)
+
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("%w: %w", ErrSecretNotFound, err)
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	if cfg.strictPerms && runtime.GOOS != "windows" {
+		info, err := f.Stat()
+		if err != nil {
+			return nil, err
+		}
+
+		if info.Mode().Perm()&0o004 != 0 {
+			return nil, fmt.Errorf("%w: %s", ErrInsecurePermissions, path)
+		}
+	}
+
+	buffer, err := readLocked(f, cfg.sizeLimit)
+	if err != nil {
+		return nil, fmt.Errorf("mattress: reading %s: %w", path, err)
+	}
+
+	store, err := newStorageFromBuffer(buffer, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return newSecret(store, codec, cfg), nil
+}
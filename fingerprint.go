@@ -0,0 +1,53 @@
+package mattress
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+)
+
+// Fingerprint is a SHA-256 digest identifying the data held by a Secret without
+// revealing it, suitable for deduplication, cache keys, and audit logs.
+type Fingerprint [sha256.Size]byte
+
+// String returns the hex encoding of the Fingerprint.
+func (f Fingerprint) String() string {
+	return hex.EncodeToString(f[:])
+}
+
+// Fingerprint returns the SHA-256 digest of the textual form of the data held by the
+// Secret, as passed to WithPlaintext. The data is only decrypted while it is hashed,
+// and the plaintext is wiped once it has been. Computing a Fingerprint does not count
+// as an exposure, so it neither counts against WithMaxExposures nor is reported to
+// OnExpose hooks.
+//
+// An unkeyed digest of a low-entropy secret, such as a password, can be reversed by
+// brute force; use KeyedFingerprint where the Fingerprint may be seen by others.
+func (s *Secret[T]) Fingerprint() (Fingerprint, error) {
+	return s.fingerprint(sha256.New())
+}
+
+// KeyedFingerprint returns the HMAC-SHA256 of the textual form of the data held by the
+// Secret under key, as described by Fingerprint. Without the key, the Fingerprint
+// cannot be used to confirm guesses of the plaintext.
+func (s *Secret[T]) KeyedFingerprint(key []byte) (Fingerprint, error) {
+	return s.fingerprint(hmac.New(sha256.New, key))
+}
+
+// fingerprint computes a Fingerprint of the textual form of the data held by the
+// Secret using h, which must produce a SHA-256 sized digest.
+func (s *Secret[T]) fingerprint(h hash.Hash) (Fingerprint, error) {
+	var f Fingerprint
+
+	err := s.withPlaintext(func(plaintext []byte) error {
+		h.Write(plaintext)
+		h.Sum(f[:0])
+		return nil
+	})
+	if err != nil {
+		return Fingerprint{}, err
+	}
+
+	return f, nil
+}
@@ -0,0 +1,139 @@
+package mattress
+
+import (
+	"sync"
+	"weak"
+)
+
+// PurgeOnFork causes ForkBoundary to wipe every live Secret, as Purge does, before the
+// fork rather than resealing them for its duration. The Secrets cannot be exposed
+// afterwards, which suits processes that fork only once their secrets are no longer
+// needed, such as a launcher which hands off to a child and then exits.
+func PurgeOnFork() ConfigureOption {
+	return func(c *globalConfig) {
+		c.purgeOnFork = true
+	}
+}
+
+// OnFork registers before to be called by ForkBoundary before any Secret is resealed
+// or purged, and after to be called once the fork has completed and the Secrets have
+// been restored. Either may be nil. They allow code holding plaintext outside of
+// Secrets, such as a connection pool holding a decoded password, to drop or restore it
+// around the fork.
+func OnFork(before, after func()) ConfigureOption {
+	return func(c *globalConfig) {
+		c.beforeFork, c.afterFork = before, after
+	}
+}
+
+// forkLock serializes calls to ForkBoundary, so that one never restores Secrets
+// which another is still relying on being resealed.
+var forkLock sync.Mutex
+
+// ForkBoundary calls fn, which is expected to fork the process, such as by starting an
+// exec.Cmd or calling into C code that forks, with no plaintext resident in the memory
+// the child inherits. Before calling fn, every live Secret whose data is held in
+// guarded memory in plaintext, as it is by default, including once compressed by
+// WithCompression, is resealed into an encrypted Enclave, so that the pages copied into
+// the child hold only ciphertext; once fn returns, the Secrets are decrypted back into
+// guarded memory. Secrets may still be exposed while fn runs, from other goroutines, at
+// the cost of decrypting them for each exposure. With PurgeOnFork, every live Secret
+// is instead wiped before fn is called and is not restored.
+//
+// Secrets created with WithSealedStorage, WithChunkedStorage, or WithDPAPI only hold
+// ciphertext between exposures, and need no resealing. Other plaintext is not
+// resealed: the data a Pool holds open, which should be resealed by registering
+// Pool.Reseal with OnFork; masked data held in heap memory by WithUnlockedFallback;
+// decoded copies held by WithCachedExposure, which should be dropped with Flush; and
+// Secrets being exposed while fn runs.
+//
+// Go itself only forks to exec a new program immediately, which replaces the memory
+// of the child; ForkBoundary guards the window in between, and forks it does not
+// control, such as those made by cgo libraries or plugins.
+//
+//	err := mattress.ForkBoundary(cmd.Start)
+func ForkBoundary(fn func() error) error {
+	interrupts.lock.Lock() // Lock before reading the settings
+	cfg := interrupts.cfg
+	interrupts.lock.Unlock()
+
+	forkLock.Lock()         // Lock before resealing any Secret
+	defer forkLock.Unlock() // Ensure the lock is Unlocked when the function returns
+
+	if cfg.beforeFork != nil {
+		cfg.beforeFork()
+	}
+
+	var resealed []*lockedStorage
+
+	if cfg.purgeOnFork {
+		Purge()
+	} else {
+		resealed = forkables()
+
+		for _, l := range resealed {
+			l.reseal()
+		}
+	}
+
+	err := fn()
+
+	for _, l := range resealed {
+		l.unseal()
+	}
+
+	if cfg.afterFork != nil {
+		cfg.afterFork()
+	}
+
+	return err
+}
+
+// forkable holds the lockedStorages which ForkBoundary reseals, referenced weakly so
+// that the registry never keeps one alive.
+var forkable struct {
+	entries map[uint64]weak.Pointer[lockedStorage] // entries maps IDs to live storages
+	next    uint64                                 // next is the ID of the next storage
+	lock    sync.Mutex                             // synchronize access to the fields above
+}
+
+// registerForkable records l in the fork registry, assigning its ID.
+func registerForkable(l *lockedStorage) {
+	forkable.lock.Lock()         // Lock before adding the entry
+	defer forkable.lock.Unlock() // Ensure the lock is Unlocked when the function returns
+
+	if forkable.entries == nil {
+		forkable.entries = make(map[uint64]weak.Pointer[lockedStorage])
+	}
+
+	forkable.next++
+	l.id = forkable.next
+	forkable.entries[l.id] = weak.Make(l)
+}
+
+// unregisterForkable removes the entry with the given ID from the fork registry, if
+// present.
+func unregisterForkable(id uint64) {
+	forkable.lock.Lock()         // Lock before removing the entry
+	defer forkable.lock.Unlock() // Ensure the lock is Unlocked when the function returns
+
+	delete(forkable.entries, id)
+}
+
+// forkables returns every live lockedStorage in the fork registry.
+func forkables() []*lockedStorage {
+	forkable.lock.Lock()         // Lock before reading the entries
+	defer forkable.lock.Unlock() // Ensure the lock is Unlocked when the function returns
+
+	live := make([]*lockedStorage, 0, len(forkable.entries))
+
+	for id, entry := range forkable.entries {
+		if l := entry.Value(); l != nil {
+			live = append(live, l)
+		} else {
+			delete(forkable.entries, id)
+		}
+	}
+
+	return live
+}
@@ -0,0 +1,116 @@
+package mattress
+
+import (
+	"encoding"
+	"encoding/gob"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// gobChecked caches the result of gobSupported for each type, as the check walks the
+// whole type and is repeated on every encode and decode.
+var gobChecked sync.Map // map[reflect.Type]error
+
+// gobSupported returns an error wrapping ErrUnsupportedType, naming the offending field,
+// if encoding/gob cannot serialize values of type T, or nil if it can. It lets GobCodec
+// fail before encoding with an error which says what to fix, rather than with the
+// error encoding/gob reports partway through.
+func gobSupported[T any]() error {
+	t := reflect.TypeFor[T]()
+
+	if err, ok := gobChecked.Load(t); ok {
+		err, _ := err.(error)
+		return err
+	}
+
+	var err error
+	if reason, at := gobUnsupported(t, "", make(map[reflect.Type]bool)); reason != "" {
+		if at != "" {
+			reason = fmt.Sprintf("%s field %s: %s", t, at[1:], reason)
+		}
+
+		err = fmt.Errorf("%w: %s; implement encoding.BinaryMarshaler or use another Codec", ErrUnsupportedType, reason)
+	}
+
+	gobChecked.Store(t, err)
+
+	return err
+}
+
+// gobUnsupported returns why encoding/gob cannot serialize values of type t, found at
+// path, along with the path of the offending field, such as ".Inner.Key", or an empty
+// reason if it can. It follows the rules of encoding/gob: unexported fields and fields
+// of chan or func type are skipped, and types implementing gob.GobEncoder,
+// encoding.BinaryMarshaler, or encoding.TextMarshaler serialize themselves. Types seen
+// before are not walked again.
+func gobUnsupported(t reflect.Type, path string, seen map[reflect.Type]bool) (reason, at string) {
+	if seen[t] {
+		return "", ""
+	}
+	seen[t] = true
+
+	if gobMarshaler(t) {
+		return "", ""
+	}
+
+	switch t.Kind() {
+	case reflect.Chan, reflect.Func, reflect.UnsafePointer:
+		return fmt.Sprintf("%s is not supported", t), path
+	case reflect.Pointer, reflect.Slice, reflect.Array:
+		return gobUnsupported(t.Elem(), path, seen)
+	case reflect.Map:
+		if reason, at := gobUnsupported(t.Key(), path, seen); reason != "" {
+			return reason, at
+		}
+		return gobUnsupported(t.Elem(), path, seen)
+	case reflect.Struct:
+		sent := 0
+
+		for i := range t.NumField() {
+			f := t.Field(i)
+			if !f.IsExported() || gobSkipped(f.Type) {
+				continue
+			}
+			sent++
+
+			if reason, at := gobUnsupported(f.Type, path+"."+f.Name, seen); reason != "" {
+				return reason, at
+			}
+		}
+
+		if sent == 0 && t.NumField() > 0 {
+			return fmt.Sprintf("%s has no exported fields", t), path
+		}
+	}
+
+	// Interfaces are checked when encoded, as the concrete types they hold must be
+	// registered with gob.Register.
+	return "", ""
+}
+
+// gobSkipped reports whether encoding/gob skips struct fields of type t, as it does
+// those of chan or func type, or pointers to them.
+func gobSkipped(t reflect.Type) bool {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	return t.Kind() == reflect.Chan || t.Kind() == reflect.Func
+}
+
+// gobMarshaler reports whether t, or a pointer to it, serializes itself under
+// encoding/gob.
+func gobMarshaler(t reflect.Type) bool {
+	for _, iface := range []reflect.Type{
+		reflect.TypeFor[gob.GobEncoder](),
+		reflect.TypeFor[encoding.BinaryMarshaler](),
+		reflect.TypeFor[encoding.TextMarshaler](),
+	} {
+		if t.Implements(iface) || reflect.PointerTo(t).Implements(iface) {
+			return true
+		}
+	}
+
+	return false
+}
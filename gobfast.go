@@ -0,0 +1,220 @@
+package mattress
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/bits"
+	"unsafe"
+)
+
+// The encoding/gob type IDs of the basic types, fixed by its wire format.
+const (
+	gobIntID    = 2
+	gobUintID   = 3
+	gobBytesID  = 5
+	gobStringID = 6
+)
+
+// gobCountLen is the longest encoding of the byte count which begins each encoding/gob
+// message, and gobHeaderLen the longest header gobPrimitive builds: the count, the type
+// ID, the field delta, and the length of a string or byte slice, or the value of an
+// integer.
+const (
+	gobCountLen  = 9
+	gobHeaderLen = gobCountLen + 1 + 1 + 9
+)
+
+// gobPrimitive returns the encoding/gob encoding of data if T is a string, a byte slice,
+// or a fixed-size integer, as header followed by payload, without involving encoding/gob
+// at all. The encoding is identical to that of a gob.Encoder, so that either can decode
+// the other. header is built in buf, which the caller must wipe, and payload aliases
+// data. ok is false for any other type.
+func gobPrimitive[T any](data T, buf *[gobHeaderLen]byte) (header, payload []byte, ok bool) {
+	body := buf[gobCountLen:gobCountLen]
+
+	switch v := any(data).(type) {
+	case string:
+		payload = unsafe.Slice(unsafe.StringData(v), len(v))
+		body = appendGobUint(append(body, gobStringID<<1, 0), uint64(len(v)))
+	case []byte:
+		payload = v
+		body = appendGobUint(append(body, gobBytesID<<1, 0), uint64(len(v)))
+	case int:
+		body = appendGobInt(append(body, gobIntID<<1, 0), int64(v))
+	case int8:
+		body = appendGobInt(append(body, gobIntID<<1, 0), int64(v))
+	case int16:
+		body = appendGobInt(append(body, gobIntID<<1, 0), int64(v))
+	case int32:
+		body = appendGobInt(append(body, gobIntID<<1, 0), int64(v))
+	case int64:
+		body = appendGobInt(append(body, gobIntID<<1, 0), v)
+	case uint:
+		body = appendGobUint(append(body, gobUintID<<1, 0), uint64(v))
+	case uint8:
+		body = appendGobUint(append(body, gobUintID<<1, 0), uint64(v))
+	case uint16:
+		body = appendGobUint(append(body, gobUintID<<1, 0), uint64(v))
+	case uint32:
+		body = appendGobUint(append(body, gobUintID<<1, 0), uint64(v))
+	case uint64:
+		body = appendGobUint(append(body, gobUintID<<1, 0), v)
+	case uintptr:
+		body = appendGobUint(append(body, gobUintID<<1, 0), uint64(v))
+	default:
+		return nil, nil, false
+	}
+
+	// The count, which precedes the body, is built in the space reserved for it.
+	var count [gobCountLen]byte
+	n := len(appendGobUint(count[:0], uint64(len(body)+len(payload))))
+	start := gobCountLen - n
+	copy(buf[start:], count[:n])
+
+	return buf[start : gobCountLen+len(body)], payload, true
+}
+
+// gobPrimitiveDecode decodes b into dst without involving encoding/gob, if T is a
+// string, a byte slice, or a fixed-size integer and b holds its encoding as gobPrimitive
+// produces it. ok is false otherwise, including for values which overflow T, leaving
+// encoding/gob to decode b or report why it cannot.
+func gobPrimitiveDecode[T any](b []byte, dst *T) (ok bool) {
+	count, body, ok := readGobUint(b)
+	if !ok || count != uint64(len(body)) || len(body) < 2 || body[1] != 0 {
+		return false
+	}
+
+	id, value := body[0]>>1, body[2:]
+	if body[0]&1 != 0 {
+		return false
+	}
+
+	switch d := any(dst).(type) {
+	case *string:
+		return id == gobStringID && decodeGobBytes(value, func(p []byte) { *d = string(p) })
+	case *[]byte:
+		return id == gobBytesID && decodeGobBytes(value, func(p []byte) {
+			// encoding/gob leaves an empty byte slice nil when decoding into the zero value.
+			*d = nil
+			if len(p) > 0 {
+				*d = bytes.Clone(p)
+			}
+		})
+	case *int:
+		return id == gobIntID && decodeGobInt(value, d)
+	case *int8:
+		return id == gobIntID && decodeGobInt(value, d)
+	case *int16:
+		return id == gobIntID && decodeGobInt(value, d)
+	case *int32:
+		return id == gobIntID && decodeGobInt(value, d)
+	case *int64:
+		return id == gobIntID && decodeGobInt(value, d)
+	case *uint:
+		return id == gobUintID && decodeGobUint(value, d)
+	case *uint8:
+		return id == gobUintID && decodeGobUint(value, d)
+	case *uint16:
+		return id == gobUintID && decodeGobUint(value, d)
+	case *uint32:
+		return id == gobUintID && decodeGobUint(value, d)
+	case *uint64:
+		return id == gobUintID && decodeGobUint(value, d)
+	case *uintptr:
+		return id == gobUintID && decodeGobUint(value, d)
+	default:
+		return false
+	}
+}
+
+// decodeGobBytes calls set with the contents of value, the encoding/gob encoding of a
+// string or byte slice, reporting whether it is well formed.
+func decodeGobBytes(value []byte, set func(p []byte)) bool {
+	n, p, ok := readGobUint(value)
+	if !ok || n != uint64(len(p)) {
+		return false
+	}
+
+	set(p)
+
+	return true
+}
+
+// decodeGobInt decodes value, the encoding/gob encoding of a signed integer, into dst,
+// reporting whether it is well formed and fits.
+func decodeGobInt[I int | int8 | int16 | int32 | int64](value []byte, dst *I) bool {
+	x, rest, ok := readGobUint(value)
+	if !ok || len(rest) != 0 {
+		return false
+	}
+
+	i := int64(x >> 1)
+	if x&1 != 0 {
+		i = ^i
+	}
+
+	*dst = I(i)
+
+	return int64(*dst) == i
+}
+
+// decodeGobUint decodes value, the encoding/gob encoding of an unsigned integer, into
+// dst, reporting whether it is well formed and fits.
+func decodeGobUint[U uint | uint8 | uint16 | uint32 | uint64 | uintptr](value []byte, dst *U) bool {
+	x, rest, ok := readGobUint(value)
+	if !ok || len(rest) != 0 {
+		return false
+	}
+
+	*dst = U(x)
+
+	return uint64(*dst) == x
+}
+
+// appendGobInt appends the encoding/gob encoding of i, which folds the sign into the
+// lowest bit, to b.
+func appendGobInt(b []byte, i int64) []byte {
+	if i < 0 {
+		return appendGobUint(b, uint64(^i<<1)|1)
+	}
+
+	return appendGobUint(b, uint64(i<<1))
+}
+
+// appendGobUint appends the encoding/gob encoding of x to b: a single byte below 0x80,
+// or otherwise the negated length of x in bytes followed by its big-endian bytes.
+func appendGobUint(b []byte, x uint64) []byte {
+	if x < 0x80 {
+		return append(b, byte(x))
+	}
+
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], x)
+
+	n := 8 - bits.LeadingZeros64(x)/8
+
+	return append(append(b, byte(-n)), buf[8-n:]...)
+}
+
+// readGobUint reads an unsigned integer encoded by appendGobUint from the start of b,
+// returning it along with the rest of b.
+func readGobUint(b []byte) (x uint64, rest []byte, ok bool) {
+	if len(b) == 0 {
+		return 0, nil, false
+	}
+
+	if b[0] < 0x80 {
+		return uint64(b[0]), b[1:], true
+	}
+
+	n := int(-int8(b[0]))
+	if n < 1 || n > 8 || n > len(b)-1 {
+		return 0, nil, false
+	}
+
+	for _, c := range b[1 : 1+n] {
+		x = x<<8 | uint64(c)
+	}
+
+	return x, b[1+n:], true
+}
@@ -0,0 +1,284 @@
+package mattress
+
+import (
+	"bytes"
+	"encoding/gob"
+	"io"
+	"reflect"
+	"sync"
+
+	"github.com/awnumar/memguard"
+)
+
+// gobStates holds the gobState of each type GobCodec has been used with, or nil for
+// types whose encoders cannot be reused.
+var gobStates sync.Map // map[reflect.Type]*gobState
+
+// gobState reuses encoding/gob encoders and decoders for a single type. A fresh
+// gob.Encoder transmits the definitions of the types it encodes before the first value,
+// and a fresh gob.Decoder must read and compile them again, which dominates the cost of
+// small values. Since every encoding of a type begins with the same definitions, they
+// are encoded once, as prefix, and followed by the value encoded by an encoder which
+// has already sent them; the result is identical to that of a fresh encoder, so that
+// each encoding remains self-contained. Likewise, encodings beginning with prefix are
+// decoded by a decoder which has already read it.
+type gobState struct {
+	prefix   []byte    // prefix holds the type definitions which begin every encoding
+	primer   []byte    // primer holds the encoding of the zero value, used to prime decoders
+	encoders sync.Pool // encoders holds *gobEncoders which have sent prefix
+	decoders sync.Pool // decoders holds *gobDecoders which have read prefix
+}
+
+// gobStateFor returns the gobState for T, or nil if its encoders cannot be reused,
+// either because T is a pointer, whose zero value encoding/gob cannot encode, or
+// because T holds interfaces, whose concrete types are only defined when first sent.
+func gobStateFor[T any]() *gobState {
+	t := reflect.TypeFor[T]()
+
+	if state, ok := gobStates.Load(t); ok {
+		return state.(*gobState)
+	}
+
+	state := newGobState[T](t)
+
+	actual, _ := gobStates.LoadOrStore(t, state)
+
+	return actual.(*gobState)
+}
+
+// newGobState encodes the type definitions of T, returning nil if its encoders cannot
+// be reused.
+func newGobState[T any](t reflect.Type) *gobState {
+	if t.Kind() == reflect.Pointer || gobHoldsInterface(t, make(map[reflect.Type]bool)) {
+		return nil
+	}
+
+	var (
+		zero          T
+		first, second bytes.Buffer
+	)
+
+	// The first encoding of the zero value holds the type definitions followed by the
+	// value, and the second only the value, so the definitions are what precedes it.
+	enc := newGobEncoder()
+
+	enc.w = &first
+	if err := enc.enc.Encode(zero); err != nil {
+		return nil
+	}
+
+	enc.w = &second
+	if err := enc.enc.Encode(zero); err != nil {
+		return nil
+	}
+
+	if !bytes.HasSuffix(first.Bytes(), second.Bytes()) {
+		return nil
+	}
+
+	state := &gobState{
+		prefix: first.Bytes()[:first.Len()-second.Len()],
+		primer: first.Bytes(),
+	}
+
+	state.encoders.New = func() any {
+		enc := newGobEncoder()
+
+		enc.w = io.Discard
+		if err := enc.enc.Encode(zero); err != nil {
+			return nil
+		}
+		enc.w = nil
+
+		return enc
+	}
+
+	state.decoders.New = func() any {
+		dec := newGobDecoder()
+
+		var data T
+		if err := dec.decode(state.primer, &data); err != nil {
+			return nil
+		}
+
+		return dec
+	}
+
+	return state
+}
+
+// gobHoldsInterface reports whether values of type t may hold interfaces, following the
+// fields encoding/gob sends. Types seen before are not walked again.
+func gobHoldsInterface(t reflect.Type, seen map[reflect.Type]bool) bool {
+	if seen[t] {
+		return false
+	}
+	seen[t] = true
+
+	if gobMarshaler(t) {
+		return false
+	}
+
+	switch t.Kind() {
+	case reflect.Interface:
+		return true
+	case reflect.Pointer, reflect.Slice, reflect.Array:
+		return gobHoldsInterface(t.Elem(), seen)
+	case reflect.Map:
+		return gobHoldsInterface(t.Key(), seen) || gobHoldsInterface(t.Elem(), seen)
+	case reflect.Struct:
+		for i := range t.NumField() {
+			f := t.Field(i)
+			if f.IsExported() && !gobSkipped(f.Type) && gobHoldsInterface(f.Type, seen) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// gobEncode writes the encoding/gob encoding of data to w, reusing an encoder if T
+// allows.
+func gobEncode[T any](w io.Writer, data T) error {
+	state := gobStateFor[T]()
+	if state == nil {
+		enc := newGobEncoder()
+		enc.w = w
+
+		return enc.enc.Encode(data)
+	}
+
+	enc, _ := state.encoders.Get().(*gobEncoder)
+	if enc == nil {
+		enc = newGobEncoder()
+		enc.w = w
+
+		return enc.enc.Encode(data)
+	}
+
+	if _, err := w.Write(state.prefix); err != nil {
+		state.encoders.Put(enc)
+		return err
+	}
+
+	enc.w = w
+	err := enc.enc.Encode(data)
+	enc.w = nil
+
+	// An encoder which failed may have sent part of a value, so it is not reused.
+	if err == nil {
+		state.encoders.Put(enc)
+	}
+
+	return err
+}
+
+// gobDecode decodes the encoding/gob encoding b into dst, reusing a decoder if T allows
+// and b begins with the type definitions the decoder has read.
+func gobDecode[T any](b []byte, dst *T) error {
+	state := gobStateFor[T]()
+	if state == nil || !bytes.HasPrefix(b, state.prefix) {
+		return newGobDecoder().decode(b, dst)
+	}
+
+	dec, _ := state.decoders.Get().(*gobDecoder)
+	if dec == nil {
+		return newGobDecoder().decode(b, dst)
+	}
+
+	err := dec.decode(b[len(state.prefix):], dst)
+
+	// A decoder which failed may have read part of a value, so it is not reused.
+	if err == nil {
+		state.decoders.Put(dec)
+	}
+
+	return err
+}
+
+// gobEncoder is a gob.Encoder writing to a writer which can be swapped between values.
+// Each message is wiped from the internal buffer of the encoder once written, so that
+// encoders kept for reuse do not retain the last value they encoded.
+type gobEncoder struct {
+	enc *gob.Encoder // enc encodes values to the gobEncoder
+	w   io.Writer    // w receives the encoding of the current value
+}
+
+// newGobEncoder returns a gobEncoder which has not yet sent any type definitions.
+func newGobEncoder() *gobEncoder {
+	enc := &gobEncoder{}
+	enc.enc = gob.NewEncoder(enc)
+
+	return enc
+}
+
+// Write writes p, a message held in the internal buffer of the encoder, to w, and
+// wipes it. The encoder reuses the buffer for the next message without reading it.
+func (e *gobEncoder) Write(p []byte) (int, error) {
+	// WipeBytes securely erases the message once written.
+	defer memguard.WipeBytes(p)
+
+	return e.w.Write(p)
+}
+
+// gobDecoder is a gob.Decoder reading from a source which can be swapped between
+// values. The buffers the decoder reads each message into are wiped once it has been
+// decoded, so that decoders kept for reuse do not retain the last value they decoded.
+type gobDecoder struct {
+	dec    *gob.Decoder // dec decodes values from the gobDecoder
+	src    []byte       // src holds the unread part of the current encoding
+	filled [][]byte     // filled holds the buffers read into while decoding the current value
+}
+
+// newGobDecoder returns a gobDecoder which has not yet read any type definitions.
+func newGobDecoder() *gobDecoder {
+	dec := &gobDecoder{}
+	dec.dec = gob.NewDecoder(dec)
+
+	return dec
+}
+
+// decode decodes the next value from b into dst, then wipes the buffers it was read
+// into.
+func (d *gobDecoder) decode(b []byte, dst any) error {
+	d.src = b
+
+	defer func() {
+		for _, p := range d.filled {
+			// WipeBytes securely erases the message once decoded.
+			memguard.WipeBytes(p)
+		}
+
+		d.src = nil
+		d.filled = d.filled[:0]
+	}()
+
+	return d.dec.Decode(dst)
+}
+
+// Read copies the next bytes of the current encoding into p, recording p to be wiped.
+func (d *gobDecoder) Read(p []byte) (int, error) {
+	if len(d.src) == 0 {
+		return 0, io.EOF
+	}
+
+	n := copy(p, d.src)
+	d.src = d.src[n:]
+	d.filled = append(d.filled, p[:n])
+
+	return n, nil
+}
+
+// ReadByte returns the next byte of the current encoding. Implementing io.ByteReader
+// stops gob.Decoder from reading ahead through a buffer of its own.
+func (d *gobDecoder) ReadByte() (byte, error) {
+	if len(d.src) == 0 {
+		return 0, io.EOF
+	}
+
+	c := d.src[0]
+	d.src = d.src[1:]
+
+	return c, nil
+}
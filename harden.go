@@ -0,0 +1,19 @@
+package mattress
+
+// Harden prevents the process from writing its memory to disk should it crash, so that
+// a core dump cannot capture the data held by Secrets, whether exposed, cached, or held
+// in masked heap memory by WithUnlockedFallback. It should be called early in main.
+//
+// On Unix systems the core file size limit, RLIMIT_CORE, is set to zero. On Linux the
+// process is additionally marked non-dumpable with PR_SET_DUMPABLE, which also stops
+// other processes of the same user from attaching to it with ptrace and makes the files
+// under /proc/self owned by root, so WithEnvOverwrite may then fail for processes not
+// running as root. The guarded memory allocated by memguard is always excluded from
+// core dumps with MADV_DONTDUMP. On Windows, the process is excluded from Windows Error
+// Reporting, which would otherwise collect a minidump, and crash dialogs are suppressed.
+// Harden is a no-op on other platforms.
+//
+// Every step is attempted, and the errors of any which fail are joined and returned.
+func Harden() error {
+	return harden()
+}
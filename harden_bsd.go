@@ -0,0 +1,9 @@
+//go:build unix && !linux
+
+package mattress
+
+// hardenPlatform is a no-op on Unix systems other than Linux, where RLIMIT_CORE
+// suffices.
+func hardenPlatform() error {
+	return nil
+}
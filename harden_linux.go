@@ -0,0 +1,16 @@
+package mattress
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// hardenPlatform marks the process non-dumpable.
+func hardenPlatform() error {
+	if err := unix.Prctl(unix.PR_SET_DUMPABLE, 0, 0, 0, 0); err != nil {
+		return fmt.Errorf("mattress: setting PR_SET_DUMPABLE: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,8 @@
+//go:build !unix && !windows
+
+package mattress
+
+// harden is a no-op on platforms which are neither Unix nor Windows.
+func harden() error {
+	return nil
+}
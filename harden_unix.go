@@ -0,0 +1,26 @@
+//go:build unix
+
+package mattress
+
+import (
+	"errors"
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// harden disables core dumps by setting RLIMIT_CORE to zero, then applies any
+// platform-specific hardening.
+func harden() error {
+	var errs []error
+
+	if err := unix.Setrlimit(unix.RLIMIT_CORE, &unix.Rlimit{}); err != nil {
+		errs = append(errs, fmt.Errorf("mattress: setting RLIMIT_CORE: %w", err))
+	}
+
+	if err := hardenPlatform(); err != nil {
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
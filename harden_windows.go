@@ -0,0 +1,49 @@
+package mattress
+
+import (
+	"fmt"
+	"os"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// procWerAddExcludedApplication excludes an application from Windows Error Reporting.
+var procWerAddExcludedApplication = windows.NewLazySystemDLL("wer.dll").NewProc("WerAddExcludedApplication")
+
+// harden excludes the executable from Windows Error Reporting, so that no minidump is
+// collected should it crash, and suppresses the crash dialog.
+func harden() error {
+	windows.SetErrorMode(windows.SEM_FAILCRITICALERRORS | windows.SEM_NOGPFAULTERRORBOX)
+
+	if err := excludeFromWER(); err != nil {
+		return fmt.Errorf("mattress: excluding from Windows Error Reporting: %w", err)
+	}
+
+	return nil
+}
+
+// excludeFromWER calls WerAddExcludedApplication for the current executable.
+func excludeFromWER() error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	name, err := windows.UTF16PtrFromString(exe)
+	if err != nil {
+		return err
+	}
+
+	if err := procWerAddExcludedApplication.Find(); err != nil {
+		return err
+	}
+
+	// The second argument, bAllUsers, is FALSE to exclude it for the current user only.
+	hr, _, _ := procWerAddExcludedApplication.Call(uintptr(unsafe.Pointer(name)), 0)
+	if hr != 0 {
+		return windows.Errno(hr)
+	}
+
+	return nil
+}
@@ -0,0 +1,106 @@
+package mattress
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+)
+
+// ConfigureOption adjusts package-wide behaviour when passed to Configure.
+type ConfigureOption func(*globalConfig)
+
+// globalConfig holds the package-wide settings adjusted by Configure.
+type globalConfig struct {
+	signals     []os.Signal     // signals trigger a wipe-and-exit when received
+	onInterrupt func(os.Signal) // onInterrupt runs before the wipe-and-exit
+	tracking    bool            // tracking records live Secrets in the registry
+	fallback    bool            // fallback allows masked heap storage where memory cannot be locked
+
+	debuggerInterval time.Duration // debuggerInterval is how often the watchdog checks for a debugger
+	onDebugger       func()        // onDebugger runs instead of Purge when a debugger attaches
+
+	purgeOnFork bool   // purgeOnFork purges rather than reseals Secrets around a ForkBoundary
+	beforeFork  func() // beforeFork runs before Secrets are resealed or purged
+	afterFork   func() // afterFork runs once Secrets have been restored
+}
+
+// interrupts holds the current settings and the state of the signal listener.
+var interrupts struct {
+	cfg  globalConfig   // cfg holds the settings last applied by Configure
+	ch   chan os.Signal // ch receives the signals being caught, if any
+	stop chan struct{}  // stop is closed to stop the current listener
+	lock sync.Mutex     // synchronize access to the fields above
+}
+
+// CatchSignals causes every live Secret to be wiped, as Purge does, and the process to
+// exit with status 1 when any of signals is received. By default only os.Interrupt is
+// caught. Passing no signals disables signal handling, as DisableSignalHandling does.
+func CatchSignals(signals ...os.Signal) ConfigureOption {
+	return func(c *globalConfig) {
+		c.signals = signals
+	}
+}
+
+// DisableSignalHandling stops the package from catching any signals, leaving them to
+// the application's own shutdown logic. Applications doing so should call Purge or
+// SafeExit once they have shut down.
+func DisableSignalHandling() ConfigureOption {
+	return func(c *globalConfig) {
+		c.signals = nil
+	}
+}
+
+// OnInterrupt registers fn to be called with the caught signal before every live
+// Secret is wiped and the process exits.
+func OnInterrupt(fn func(os.Signal)) ConfigureOption {
+	return func(c *globalConfig) {
+		c.onInterrupt = fn
+	}
+}
+
+// Configure adjusts package-wide behaviour, applying opts on top of the settings from
+// any previous call. Unlike memguard.CatchSignal, it never resets signal handlers
+// registered by other code.
+func Configure(opts ...ConfigureOption) {
+	interrupts.lock.Lock()         // Lock before replacing the settings
+	defer interrupts.lock.Unlock() // Ensure the lock is Unlocked when the function returns
+
+	for _, opt := range opts {
+		opt(&interrupts.cfg)
+	}
+
+	tracking.Store(interrupts.cfg.tracking)
+	fallbackAllowed.Store(interrupts.cfg.fallback)
+	watchDebugger(interrupts.cfg)
+
+	if interrupts.ch != nil {
+		signal.Stop(interrupts.ch)
+		close(interrupts.stop)
+		interrupts.ch, interrupts.stop = nil, nil
+	}
+
+	if len(interrupts.cfg.signals) == 0 {
+		return
+	}
+
+	interrupts.ch = make(chan os.Signal, 1)
+	interrupts.stop = make(chan struct{})
+
+	signal.Notify(interrupts.ch, interrupts.cfg.signals...)
+
+	go listen(interrupts.ch, interrupts.stop, interrupts.cfg.onInterrupt)
+}
+
+// listen waits for a signal on ch, then runs onInterrupt, if set, and wipes every live
+// Secret before exiting. It returns without doing so once stop is closed.
+func listen(ch <-chan os.Signal, stop <-chan struct{}, onInterrupt func(os.Signal)) {
+	select {
+	case sig := <-ch:
+		if onInterrupt != nil {
+			onInterrupt(sig)
+		}
+		SafeExit(1)
+	case <-stop:
+	}
+}
@@ -0,0 +1,161 @@
+package mattress
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unsafe"
+)
+
+// Join concatenates the text of parts, separated by sep, into a new Secret serialized
+// with StringCodec, as NewSecretString does. The text is assembled directly in guarded
+// memory, so the result never exists as a plain string. Each part counts as an
+// exposure; parts created with StringCodec, such as by NewSecretString, are copied as
+// stored, while others are decoded first, leaving a copy on the heap as Expose does.
+func Join(sep string, parts ...*Secret[string]) (*Secret[string], error) {
+	var w guardedWriter
+	defer w.destroy()
+
+	for i, part := range parts {
+		if i > 0 {
+			if _, err := w.Write(unsafe.Slice(unsafe.StringData(sep), len(sep))); err != nil {
+				return nil, err
+			}
+		}
+
+		if err := withText(part, func(text []byte) error {
+			_, err := w.Write(text)
+			return err
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	return newTextSecret(&w, nil)
+}
+
+// Template renders text into a new Secret serialized with StringCodec and created with
+// opts, replacing each placeholder of the form {name} with the text of parts[name], as
+// in "postgres://{user}:{password}@{host}/app". "{{" and "}}" render a literal brace.
+// The result is assembled directly in guarded memory, as Join does. Template returns
+// an error, without exposing any part, if text is malformed or names a part which is
+// not given.
+func Template(text string, parts map[string]*Secret[string], opts ...Option) (*Secret[string], error) {
+	segments, err := parseTemplate(text, parts)
+	if err != nil {
+		return nil, err
+	}
+
+	var w guardedWriter
+	defer w.destroy()
+
+	for _, segment := range segments {
+		if segment.part == nil {
+			if _, err := w.Write(unsafe.Slice(unsafe.StringData(segment.literal), len(segment.literal))); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if err := withText(segment.part, func(text []byte) error {
+			_, err := w.Write(text)
+			return err
+		}); err != nil {
+			return nil, fmt.Errorf("mattress: template part %q: %w", segment.literal, err)
+		}
+	}
+
+	return newTextSecret(&w, opts)
+}
+
+// templateSegment is either a literal run of a template or a placeholder, whose part
+// is set and whose literal holds its name.
+type templateSegment struct {
+	literal string
+	part    *Secret[string]
+}
+
+// parseTemplate splits text into segments, resolving each placeholder within parts.
+func parseTemplate(text string, parts map[string]*Secret[string]) ([]templateSegment, error) {
+	var (
+		segments []templateSegment
+		literal  strings.Builder
+	)
+
+	for i := 0; i < len(text); i++ {
+		switch c := text[i]; {
+		case c == '{' && strings.HasPrefix(text[i:], "{{"), c == '}' && strings.HasPrefix(text[i:], "}}"):
+			literal.WriteByte(c)
+			i++
+		case c == '{':
+			end := strings.IndexByte(text[i:], '}')
+			if end < 0 {
+				return nil, errors.New("mattress: template has an unterminated placeholder")
+			}
+
+			name := text[i+1 : i+end]
+
+			part, ok := parts[name]
+			if !ok || part == nil {
+				return nil, fmt.Errorf("mattress: template part %q: %w", name, ErrSecretNotFound)
+			}
+
+			if literal.Len() > 0 {
+				segments = append(segments, templateSegment{literal: literal.String()})
+				literal.Reset()
+			}
+
+			segments = append(segments, templateSegment{literal: name, part: part})
+			i += end
+		case c == '}':
+			return nil, errors.New("mattress: template has an unmatched '}'")
+		default:
+			literal.WriteByte(c)
+		}
+	}
+
+	if literal.Len() > 0 {
+		segments = append(segments, templateSegment{literal: literal.String()})
+	}
+
+	return segments, nil
+}
+
+// withText calls fn with the text of s, counting the call as an exposure. Secrets
+// serialized with StringCodec are passed as stored, without being decoded; others are
+// decoded and passed a copy, which is wiped once fn returns.
+func withText(s *Secret[string], fn func(text []byte) error) error {
+	return s.withExposure(func(b []byte) error {
+		if _, ok := s.codec.(StringCodec); ok {
+			return fn(b)
+		}
+
+		data, err := s.decode(b)
+		if err != nil {
+			return err
+		}
+
+		text := []byte(data)
+		defer wipe(&text)
+
+		return fn(text)
+	})
+}
+
+// newTextSecret seals the text written to w into a new Secret serialized with
+// StringCodec and created with opts.
+func newTextSecret(w *guardedWriter, opts []Option) (*Secret[string], error) {
+	cfg := newConfig(opts)
+
+	buffer, err := w.finish()
+	if err != nil {
+		return nil, err
+	}
+
+	store, err := newStorageFromBuffer(buffer, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return newSecret[string](store, StringCodec{}, cfg), nil
+}
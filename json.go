@@ -0,0 +1,30 @@
+package mattress
+
+import "encoding/json"
+
+// MarshalJSON implements json.Marshaler. Under the default SerializationPolicy the
+// Secret is encoded as the JSON string Placeholder; under RefuseOnSerialize it returns
+// ErrSerializationRefused.
+func (s *Secret[T]) MarshalJSON() ([]byte, error) {
+	return redactOrRefuse(func() ([]byte, error) {
+		return json.Marshal(Placeholder)
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler, decoding b into a T and securing it within
+// the Secret, so that configuration can be decoded directly into Secrets. The
+// intermediate T is wiped on a best-effort basis once it has been secured. Any data the
+// Secret previously held is destroyed.
+func (s *Secret[T]) UnmarshalJSON(b []byte) error {
+	var data T
+
+	if err := json.Unmarshal(b, &data); err != nil {
+		return err
+	}
+
+	defer wipe(&data)
+
+	codec, cfg := s.settings()
+
+	return s.set(data, codec, cfg)
+}
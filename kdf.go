@@ -0,0 +1,118 @@
+package mattress
+
+import (
+	"fmt"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/scrypt"
+)
+
+// KDF identifies a password-based key derivation function.
+type KDF int
+
+const (
+	// Argon2id derives keys with Argon2id, as specified in RFC 9106.
+	Argon2id KDF = iota
+	// Scrypt derives keys with scrypt, as specified in RFC 7914.
+	Scrypt
+)
+
+// String returns the name of the key derivation function.
+func (k KDF) String() string {
+	switch k {
+	case Argon2id:
+		return "argon2id"
+	case Scrypt:
+		return "scrypt"
+	default:
+		return fmt.Sprintf("KDF(%d)", int(k))
+	}
+}
+
+// KDFParams configures DeriveKey. Only the parameters of the selected KDF are used.
+type KDFParams struct {
+	KDF    KDF    // KDF is the key derivation function to run
+	KeyLen uint32 // KeyLen is the length of the derived key in bytes
+
+	Time    uint32 // Time is the number of Argon2id passes over memory
+	Memory  uint32 // Memory is the Argon2id memory cost in KiB
+	Threads uint8  // Threads is the Argon2id degree of parallelism
+
+	N int // N is the scrypt CPU/memory cost, a power of two greater than one
+	R int // R is the scrypt block size
+	P int // P is the scrypt parallelization factor
+}
+
+// DefaultArgon2idParams are the Argon2id parameters recommended by RFC 9106 for
+// memory-constrained environments, deriving a 32-byte key.
+var DefaultArgon2idParams = KDFParams{
+	KDF:     Argon2id,
+	KeyLen:  32,
+	Time:    3,
+	Memory:  64 * 1024,
+	Threads: 4,
+}
+
+// The largest Argon2id parameters accepted from the header of a file written by Save
+// or from a hash checked by VerifyPassword: 1 GiB of memory, 16 passes, and 64
+// threads, well beyond DefaultArgon2idParams. Neither is authenticated until the key
+// has been derived, so without these bounds a crafted file or hash could demand
+// arbitrary memory and time.
+const (
+	maxArgon2idMemory  = 1 << 20
+	maxArgon2idTime    = 16
+	maxArgon2idThreads = 64
+)
+
+// argon2idAccepted reports whether the Argon2id parameters of params are within the
+// maximum accepted from files and hashes.
+func argon2idAccepted(params KDFParams) bool {
+	return params.Memory <= maxArgon2idMemory && params.Time <= maxArgon2idTime && params.Threads <= maxArgon2idThreads
+}
+
+// DefaultScryptParams are the scrypt parameters recommended for interactive logins,
+// deriving a 32-byte key.
+var DefaultScryptParams = KDFParams{
+	KDF:    Scrypt,
+	KeyLen: 32,
+	N:      1 << 15,
+	R:      8,
+	P:      1,
+}
+
+// DeriveKey runs the KDF selected by params over password and salt and returns the
+// derived key as a new Secret. The password is only exposed for the duration of the
+// derivation, and the derived key is wiped from ordinary memory as soon as it has been
+// secured, so that encryption pipelines can be built entirely within protected memory.
+func DeriveKey(password *Secret[string], salt []byte, params KDFParams, opts ...Option) (*Secret[[]byte], error) {
+	if params.KeyLen == 0 {
+		return nil, fmt.Errorf("mattress: %s key length must be positive", params.KDF)
+	}
+
+	var key []byte
+
+	err := password.WithPlaintext(func(plaintext []byte) error {
+		switch params.KDF {
+		case Argon2id:
+			if params.Time == 0 || params.Threads == 0 {
+				return fmt.Errorf("mattress: %s time and threads must be positive", params.KDF)
+			}
+
+			key = argon2.IDKey(plaintext, salt, params.Time, params.Memory, params.Threads, params.KeyLen)
+		case Scrypt:
+			var err error
+			if key, err = scrypt.Key(plaintext, salt, params.N, params.R, params.P, int(params.KeyLen)); err != nil {
+				return fmt.Errorf("mattress: %s: %w", params.KDF, err)
+			}
+		default:
+			return fmt.Errorf("mattress: unsupported %s", params.KDF)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return NewSecretBytes(key, opts...)
+}
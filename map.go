@@ -0,0 +1,43 @@
+package mattress
+
+// Map derives a new Secret from s, such as a connection string from a password or a
+// token with surrounding whitespace trimmed, without the plaintext of either leaving
+// the protected path. It exposes the data of s, passes it to f, and seals the value f
+// returns into a Secret created with opts, as NewSecret does. Once the result has been
+// sealed, both the exposed data and the value f returned are wiped, as WithExposed
+// does, so f may return a value sharing memory with its argument, such as a subslice.
+// f must not retain either value.
+//
+// Map counts as an exposure of s, and returns any error from exposing s or sealing
+// the result; s itself is left untouched.
+func Map[T, U any](s *Secret[T], f func(T) U, opts ...Option) (*Secret[U], error) {
+	codec, err := codecFor[U](newConfig(opts))
+	if err != nil {
+		return nil, err
+	}
+
+	return MapWithCodec(s, f, codec, opts...)
+}
+
+// MapWithCodec derives a new Secret from s as described by Map, serializing the result
+// with codec rather than encoding/gob, such as StringCodec for a derived string.
+func MapWithCodec[T, U any](s *Secret[T], f func(T) U, codec Codec[U], opts ...Option) (*Secret[U], error) {
+	var derived *Secret[U]
+
+	err := s.WithExposed(func(data T) error {
+		value := f(data)
+
+		// The value is wiped before the exposed data, which it may share memory with.
+		defer wipe(&value)
+
+		var err error
+		derived, err = NewSecretWithCodec(value, codec, opts...)
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return derived, nil
+}
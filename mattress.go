@@ -8,8 +8,8 @@
 // acknowledge that no method is entirely foolproof. Users are encouraged to employ this
 // package in conjunction with other security best practices for more comprehensive protection.
 //
-// Warning: This package utilizes runtime finalizers to ensure cleanup of sensitive data. Due
-// to the nature of Go's runtime, which does not guarantee immediate execution of finalizers,
+// Warning: This package utilizes runtime cleanups to ensure cleanup of sensitive data. Due
+// to the nature of Go's runtime, which does not guarantee immediate execution of cleanups,
 // sensitive data may reside in memory longer than anticipated. Users should proceed with
 // caution and ensure they fully comprehend the potential implications.
 //
@@ -33,95 +33,599 @@
 //	    Password: password,
 //	  }
 //
-//	  fmt.Println(user.Password) // Output: memory address
+//	  fmt.Printf("%+v\n", user) // Output: {Username:username Password:[SECRET]}
 //	  fmt.Println(user.Password.String()) // Output: "[SECRET]"
 //	  fmt.Println(user.Password.Expose()) // Output: "password"
 //	}
 package mattress
 
 import (
-	"bytes"
-	"encoding/gob"
-	"runtime"
+	"fmt"
+	"log/slog"
+	"os"
+	"reflect"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/awnumar/memguard"
 )
 
 // init is called on package load and sets up a signal handler to catch interrupts.
 // This ensures that sensitive data is securely wiped from memory if the application
-// is interrupted.
+// is interrupted. Applications with their own shutdown logic can change or disable
+// this with Configure.
 func init() {
-	// CatchInterrupt ensures that if the application is interrupted, any sensitive data
+	// CatchSignals ensures that if the application is interrupted, any sensitive data
 	// handled by memguard will be securely wiped from memory before exit.
-	memguard.CatchInterrupt()
+	Configure(CatchSignals(os.Interrupt))
 }
 
 // Secret holds a reference to a securely stored piece of data of any type.
-// The data is stored within a memguard.LockedBuffer, providing encryption at rest
-// and secure memory handling.
+// By default the data is stored within a memguard.LockedBuffer, providing encryption
+// at rest and secure memory handling. WithSealedStorage keeps the data encrypted
+// within a memguard.Enclave instead, decrypting it only while it is being exposed.
+//
+// A Secret is safe for concurrent use. Exposures share a read lock, so a Secret used on
+// every request, such as an HMAC key, does not serialize the goroutines exposing it.
+//
+// A Secret must always be handled through a pointer and never copied once it holds
+// data, since a copy would share its guarded memory. go vet reports copies, and a copy
+// refuses to expose the data, returning ErrCopied, or to destroy it.
 type Secret[T any] struct {
-	buffer *memguard.LockedBuffer // buffer holds the encrypted data
-	lock   sync.RWMutex           // synchronize access to the buffer
+	noCopy noCopy // noCopy lets go vet report copies
+
+	store     storage                               // store holds the encoded data
+	codec     Codec[T]                              // codec converts between T and the stored bytes
+	cfg       config                                // cfg holds the options the Secret was created with
+	lock      sync.RWMutex                          // synchronize access to the store
+	destroyed bool                                  // destroyed reports whether the store has been wiped
+	exposures int                                   // exposures counts exposures against cfg.maxExposures
+	expired   bool                                  // expired reports whether the store was wiped by a limit
+	deadline  time.Time                             // deadline is when the store expires, if cfg.ttl is set
+	timer     *time.Timer                           // timer wipes the store at the deadline
+	cacheable bool                                  // cacheable reports whether decoded values are cached
+	cache     atomic.Pointer[memguard.LockedBuffer] // cache holds the decoded value, if cacheable
+	addr      *Secret[T]                            // addr is the address of the Secret, to detect copies
+	trackID   uint64                                // trackID identifies the store in the registry, if tracked
+	label     atomic.Pointer[string]                // label is the label set by WithLabel, readable without the lock
+	limiter   exposureLimiter                       // limiter enforces the limit set by WithExposureRateLimit
 }
 
 // NewSecret initializes a new Secret with the provided data. It serializes the data using
-// encoding/gob and stores it securely using memguard. This function returns an error if
-// encoding the data fails or if there is an issue securing the data in memory.
-func NewSecret[T any](data T) (*Secret[T], error) {
-	var buf bytes.Buffer
+// encoding/gob, or the Codec set by WithCodec, and stores it securely using memguard.
+// This function returns an error if encoding the data fails or if there is an issue
+// securing the data in memory. Types which encoding/gob cannot serialize, such as
+// structs with only unexported fields, are rejected up front with an error wrapping
+// ErrUnsupportedType which names the offending field.
+func NewSecret[T any](data T, opts ...Option) (*Secret[T], error) {
+	codec, err := codecFor[T](newConfig(opts))
+	if err != nil {
+		return nil, err
+	}
 
-	enc := gob.NewEncoder(&buf)
+	return NewSecretWithCodec(data, codec, opts...)
+}
 
-	err := enc.Encode(data)
+// MustNewSecret initializes a new Secret with the provided data, as NewSecret does, and
+// panics if that fails. It is intended for initialization, such as of package-level
+// variables, where failing to secure the data is unrecoverable.
+func MustNewSecret[T any](data T, opts ...Option) *Secret[T] {
+	secret, err := NewSecret(data, opts...)
 	if err != nil {
+		panic(err)
+	}
+
+	return secret
+}
+
+// NewSecretWithCodec initializes a new Secret with the provided data, serializing it
+// with codec rather than encoding/gob. This function returns an error if encoding the
+// data fails or if there is an issue securing the data in memory.
+func NewSecretWithCodec[T any](data T, codec Codec[T], opts ...Option) (*Secret[T], error) {
+	secret := &Secret[T]{}
+
+	if err := secret.set(data, codec, newConfig(opts)); err != nil {
 		return nil, err
 	}
 
-	bytes := buf.Bytes()
+	return secret, nil
+}
+
+// NewOneTimeSecret initializes a new Secret with the provided data, as NewSecret does,
+// whose data can be exposed exactly once. The Secret is destroyed as soon as the first
+// exposure completes, and subsequent exposures fail with ErrSecretExpired. This suits
+// bootstrap tokens and invitation codes, which should not outlive their first use.
+func NewOneTimeSecret[T any](data T, opts ...Option) (*Secret[T], error) {
+	return NewSecret[T](data, append(opts, WithMaxExposures(1))...)
+}
+
+// set encodes data with codec and secures it according to cfg, replacing (and
+// destroying) any data the Secret previously held.
+func (s *Secret[T]) set(data T, codec Codec[T], cfg config) error {
+	// Codecs which can encode directly into guarded memory skip the heap copy.
+	if encoder, ok := codec.(guardedEncoder[T]); ok {
+		buffer, err := encoder.encodeGuarded(data)
+		if err != nil {
+			return err
+		}
 
-	enclave := memguard.NewEnclave(bytes)
+		store, err := newStorageFromBuffer(buffer, cfg)
+		if err != nil {
+			return err
+		}
 
-	buffer, err := enclave.Open()
+		s.replace(store, codec, cfg)
+
+		return nil
+	}
+
+	bytes, err := codec.Encode(data)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	// WipeBytes securely erases the original byte slice to minimize the risk of data leakage.
-	memguard.WipeBytes(bytes)
+	defer memguard.WipeBytes(bytes)
+
+	store, err := newStorage(bytes, cfg)
+	if err != nil {
+		return err
+	}
+
+	s.replace(store, codec, cfg)
+
+	return nil
+}
+
+// newSecret returns a Secret holding store, which codec decodes, configured by cfg.
+func newSecret[T any](store storage, codec Codec[T], cfg config) *Secret[T] {
+	secret := &Secret[T]{}
+	secret.replace(store, codec, cfg)
+
+	return secret
+}
+
+// replace installs store as the data held by the Secret, destroying any data it
+// previously held and arming its TTL, if any.
+func (s *Secret[T]) replace(store storage, codec Codec[T], cfg config) {
+	s.lock.Lock()         // Lock before replacing the store
+	defer s.lock.Unlock() // Ensure the lock is Unlocked when the method returns
+
+	if s.copied() {
+		// A copy shares the store, cache, and timer of the original, which remains
+		// responsible for them.
+		s.cache.Store(nil)
+		s.timer = nil
+		s.trackID = 0
+	} else {
+		if s.store != nil && !s.destroyed {
+			s.destroyStore()
+		}
+
+		s.flushCache()
+
+		if s.timer != nil {
+			s.timer.Stop()
+			s.timer = nil
+		}
+	}
+
+	s.addr = s
+	s.store = store
+	s.codec = codec
+	s.cfg = cfg
+	s.destroyed = false
+	s.expired = false
+	s.exposures = 0
+	s.deadline = time.Time{}
+	s.limiter.reset()
+	s.cacheable = cfg.cachedExposure && cacheable(reflect.TypeFor[T]()) && !Degraded()
+
+	s.label.Store(&cfg.label)
+	s.trackID = track(store, cfg.label)
+
+	if cfg.ttl > 0 {
+		s.deadline = time.Now().Add(cfg.ttl)
+		s.timer = time.AfterFunc(cfg.ttl, func() { s.expire(store) })
+	}
+}
+
+// settings returns the Codec and configuration the Secret was created with, or the
+// defaults if the Secret has not yet been initialized. It allows the Secret to be
+// resealed in place, for example by an unmarshaler, without losing its options.
+func (s *Secret[T]) settings() (Codec[T], config) {
+	s.lock.RLock()         // RLock before reading the settings
+	defer s.lock.RUnlock() // Ensure the lock is RUnlocked when the method returns
+
+	if s.codec == nil {
+		return GobCodec[T]{}, config{}
+	}
 
-	// Assign a runtime finalizer to ensure the secure buffer is wiped when the Secret is
-	// garbage collected.
-	secret := &Secret[T]{buffer: buffer}
-	runtime.SetFinalizer(secret, func(s *Secret[T]) {
-		s.zero()
+	return s.codec, s.cfg
+}
+
+// withBytes calls fn with the encoded bytes held by the Secret, holding a read lock for
+// the duration of fn. The bytes are only valid until fn returns. It returns
+// ErrDestroyed if the Secret has been destroyed, or ErrSecretExpired if it has expired.
+func (s *Secret[T]) withBytes(fn func(b []byte) error) error {
+	if s == nil {
+		return ErrUninitialized
+	}
+
+	s.lock.RLock()         // RLock before reading the store
+	defer s.lock.RUnlock() // Ensure the lock is RUnlocked when the method returns
+
+	return s.view(fn)
+}
+
+// withExposure calls fn with the encoded bytes held by the Secret, as withBytes does,
+// counting the call as an exposure and reporting it to any OnExpose hooks once the
+// lock has been released.
+func (s *Secret[T]) withExposure(fn func(b []byte) error) error {
+	return s.expose(s.view, fn)
+}
+
+// expose calls fn with the encoded bytes held by the Secret through view, which is
+// either view or viewChunks, counting the call as an exposure and reporting it to any
+// OnExpose hooks once the lock has been released.
+func (s *Secret[T]) expose(view func(fn func(b []byte) error) error, fn func(b []byte) error) error {
+	if !auditing() {
+		return s.countExposure(view, fn)
+	}
+
+	var (
+		fingerprint Fingerprint
+		exposed     bool
+	)
+
+	h := auditHash()
+
+	err := s.countExposure(view, func(b []byte) error {
+		h.Write(b)
+		exposed = true
+		return fn(b)
 	})
+	if exposed {
+		h.Sum(fingerprint[:0])
+	}
 
-	return secret, nil
+	file, line := caller()
+
+	emit(ExposeEvent{
+		Label:       s.Label(),
+		Fingerprint: fingerprint,
+		File:        file,
+		Line:        line,
+		Time:        time.Now(),
+		Err:         err,
+	})
+
+	return err
+}
+
+// countExposure calls fn with the encoded bytes held by the Secret through view,
+// counting the call as an exposure. Once a Secret created with an exposure limit has
+// reached it, its store is destroyed as soon as fn returns. Exposures of Secrets
+// without a limit, the common case, only ever take the read lock, so they proceed
+// concurrently.
+func (s *Secret[T]) countExposure(view func(fn func(b []byte) error) error, fn func(b []byte) error) error {
+	if s == nil {
+		return ErrUninitialized
+	}
+
+	s.lock.RLock() // RLock before reading the settings
+
+	if s.cfg.maxExposures == 0 {
+		defer s.lock.RUnlock() // Ensure the lock is RUnlocked when the method returns
+
+		return s.throttled(view)(fn)
+	}
+
+	s.lock.RUnlock()
+
+	s.lock.Lock()         // Lock before counting the exposure
+	defer s.lock.Unlock() // Ensure the lock is Unlocked when the method returns
+
+	exposed := false
+
+	err := s.throttled(view)(func(b []byte) error {
+		exposed = true
+		return fn(b)
+	})
+	if !exposed {
+		return err
+	}
+
+	s.exposures++
+	if s.exposures >= s.cfg.maxExposures {
+		s.destroyStore()
+		s.destroyed = true
+		s.expired = true
+	}
+
+	return err
+}
+
+// view calls fn with the encoded bytes held by the Secret, releasing them once fn
+// returns. The caller must hold the lock.
+func (s *Secret[T]) view(fn func(b []byte) error) error {
+	if err := s.viewable(); err != nil {
+		return err
+	}
+
+	b, release, err := s.store.view()
+	if err != nil {
+		return s.labelled(err)
+	}
+	defer release()
+
+	return fn(b)
+}
+
+// viewChunks calls fn with the encoded bytes held by the Secret a chunk at a time, if it
+// was created with WithChunkedStorage, or all at once otherwise, releasing each chunk
+// once fn returns. The caller must hold the lock.
+func (s *Secret[T]) viewChunks(fn func(chunk []byte) error) error {
+	if err := s.viewable(); err != nil {
+		return err
+	}
+
+	chunked, ok := s.store.(*chunkedStorage)
+	if !ok {
+		return s.view(fn)
+	}
+
+	var fnErr error
+
+	err := chunked.each(func(chunk []byte) error {
+		fnErr = fn(chunk)
+		return fnErr
+	})
+	if err != nil && fnErr == nil {
+		return s.labelled(err)
+	}
+
+	return err
+}
+
+// viewable returns the error, labelled, that viewing the Secret fails with, or nil if
+// it may be viewed. The caller must hold the lock.
+func (s *Secret[T]) viewable() error {
+	if s.copied() {
+		return s.labelled(ErrCopied)
+	}
+
+	if err := s.unavailable(); err != nil {
+		return s.labelled(err)
+	}
+
+	if s.store == nil {
+		return s.labelled(ErrUninitialized)
+	}
+
+	return nil
+}
+
+// unavailable returns the error an exposure of the Secret fails with, or nil if its
+// data may be exposed. The caller must hold the lock.
+func (s *Secret[T]) unavailable() error {
+	switch {
+	case s.expired, !s.deadline.IsZero() && !time.Now().Before(s.deadline):
+		return ErrSecretExpired
+	case s.destroyed:
+		return ErrDestroyed
+	default:
+		return nil
+	}
+}
+
+// expire destroys store once the TTL of the Secret has elapsed, provided it has not
+// since been replaced.
+func (s *Secret[T]) expire(store storage) {
+	s.lock.Lock()         // Lock before destroying the store
+	defer s.lock.Unlock() // Ensure the lock is Unlocked when the method returns
+
+	if s.store != store || s.destroyed {
+		return
+	}
+
+	s.destroyStore()
+	s.destroyed = true
+	s.expired = true
 }
 
 // zero securely wipes the memory area holding the sensitive data, ensuring it cannot
 // be accessed once the Secret is no longer needed.
 func (s *Secret[T]) zero() {
-	s.buffer.Destroy()
+	if s == nil {
+		return
+	}
+
+	s.lock.Lock()         // Lock before destroying the store
+	defer s.lock.Unlock() // Ensure the lock is Unlocked when the method returns
+
+	// A copy shares the store and timer of the original, which remains responsible
+	// for them.
+	if s.copied() {
+		return
+	}
+
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+
+	if s.destroyed || s.store == nil {
+		return
+	}
+
+	s.destroyStore()
+	s.destroyed = true
+}
+
+// destroyStore destroys the store along with the exposure cache, and stops tracking
+// it. The caller must hold the lock for writing.
+func (s *Secret[T]) destroyStore() {
+	s.store.destroy()
+	s.flushCache()
+
+	untrack(s.trackID)
+	s.trackID = 0
+}
+
+// copied reports whether the Secret is a copy of the Secret which was populated. The
+// caller must hold the lock.
+func (s *Secret[T]) copied() bool {
+	return s.addr != nil && s.addr != s
+}
+
+// Destroy securely wipes the sensitive data held by the Secret. Unlike the runtime
+// cleanup, Destroy takes effect immediately and should be preferred whenever the
+// lifetime of the Secret is known. Calling Destroy more than once is a no-op.
+func (s *Secret[T]) Destroy() {
+	s.zero()
+}
+
+// IsDestroyed reports whether the Secret has been destroyed and its data wiped.
+func (s *Secret[T]) IsDestroyed() bool {
+	if s == nil {
+		return false
+	}
+
+	s.lock.RLock()         // RLock before reading the destroyed state
+	defer s.lock.RUnlock() // Ensure the lock is RUnlocked when the method returns
+
+	return s.destroyed
 }
 
 // Expose decrypts and returns the stored data. Note that this operation potentially
 // exposes sensitive data in memory. Ensure that the returned data is handled securely
 // and is wiped from memory when no longer needed.
+//
+// If the Secret is nil, uninitialized, or destroyed, or the data cannot be decoded,
+// Expose returns the zero value of T. Use ExposeErr to distinguish these cases from a
+// genuine zero value.
 func (s *Secret[T]) Expose() T {
-	s.lock.RLock()         // RLock before reading the buffer
-	defer s.lock.RUnlock() // Ensure the lock is RUnlocked when the method returns
+	data, _ := s.ExposeErr()
 
+	return data
+}
+
+// ExposeErr decrypts and returns the stored data, reporting any failure to do so. It
+// returns ErrUninitialized if the Secret is nil or was never given any data,
+// ErrDestroyed if it has been destroyed, ErrSecretExpired if it has expired, or the
+// decoding error if the stored data could not be decoded into a T. The same care must
+// be taken with the returned data as with Expose.
+func (s *Secret[T]) ExposeErr() (T, error) {
 	var data T
 
-	gob.NewDecoder(bytes.NewReader(s.buffer.Bytes())).Decode(&data)
+	err := s.withExposure(func(b []byte) error {
+		var err error
+		data, err = s.decode(b)
+		return err
+	})
+	if err != nil {
+		var zero T
+		return zero, err
+	}
 
-	return data
+	return data, nil
+}
+
+// ExposeInto decrypts the stored data into dst, reporting any failure as ExposeErr
+// does. If the Codec of the Secret implements IntoDecoder, dst is decoded into in place,
+// reusing its storage where possible, such as the capacity of a byte slice; otherwise
+// it is overwritten with a newly decoded value. This lets hot paths avoid allocating on
+// every exposure, and lets callers wipe dst themselves once finished with it.
+func (s *Secret[T]) ExposeInto(dst *T) error {
+	return s.withExposure(func(b []byte) error {
+		if decoder, ok := s.codec.(IntoDecoder[T]); ok && !s.cacheable {
+			return decoder.DecodeInto(b, dst)
+		}
+
+		data, err := s.decode(b)
+		if err != nil {
+			return err
+		}
+
+		*dst = data
+
+		return nil
+	})
+}
+
+// WithExposed decrypts the stored data and passes it to fn, returning any error from
+// either the exposure or fn itself. Once fn returns, WithExposed makes a best-effort
+// attempt to wipe the plaintext it handed out: byte slices reachable from the value
+// are overwritten and the value is reset to its zero value. fn must not retain the
+// value, or any part of it, beyond its own execution.
+func (s *Secret[T]) WithExposed(fn func(T) error) error {
+	data, err := s.ExposeErr()
+	if err != nil {
+		return err
+	}
+
+	defer wipe(&data)
+
+	return fn(data)
 }
 
 // String provides a safe string representation of the Secret, ensuring that sensitive
 // data is not accidentally exposed via logging or other string handling mechanisms.
+// It returns Placeholder, or [SECRET:label] if the Secret was created with WithLabel.
 func (s *Secret[T]) String() string {
-	return "[SECRET]"
+	return s.placeholder()
+}
+
+// GoString implements fmt.GoStringer, ensuring that the %#v verb does not expose the
+// internals of the Secret.
+func (s *Secret[T]) GoString() string {
+	return s.placeholder()
+}
+
+// Format implements fmt.Formatter, ensuring that every formatting verb, including %+v
+// and %#v, renders the Secret as Placeholder, or as [SECRET:label] if it was created
+// with WithLabel. Width and padding flags are honoured.
+func (s *Secret[T]) Format(f fmt.State, verb rune) {
+	fmt.Fprintf(f, fmt.FormatString(f, 's'), s.placeholder())
+}
+
+// LogValue implements slog.LogValuer, ensuring that the Secret is redacted when it is
+// logged using log/slog.
+func (s *Secret[T]) LogValue() slog.Value {
+	return slog.StringValue(s.placeholder())
+}
+
+// Label returns the label the Secret was created with using WithLabel, or the empty
+// string if it has none.
+func (s *Secret[T]) Label() string {
+	if s == nil {
+		return ""
+	}
+
+	// The label is read atomically, as String may be called while the Secret is locked.
+	if label := s.label.Load(); label != nil {
+		return *label
+	}
+
+	return ""
+}
+
+// placeholder returns the text that stands in for the Secret in formatted output.
+func (s *Secret[T]) placeholder() string {
+	if label := s.Label(); label != "" {
+		return "[SECRET:" + label + "]"
+	}
+
+	return Placeholder
+}
+
+// labelled returns err annotated with the label of the Secret, if it has one.
+func (s *Secret[T]) labelled(err error) error {
+	if label := s.Label(); label != "" {
+		return &labelError{label: label, err: err}
+	}
+
+	return err
 }
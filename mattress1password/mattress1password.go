@@ -0,0 +1,227 @@
+// mattress1password sources mattress Secrets from 1Password through a 1Password Connect
+// server.
+//
+// Responses are read into memory that is wiped once decoded, and each field is sealed
+// into a Secret as soon as it has been decoded.
+//
+// Example Usage:
+//
+//	import (
+//	  m "github.com/garrettladley/mattress"
+//	  "github.com/garrettladley/mattress/mattress1password"
+//	)
+//
+//	func main() {
+//	  token, err := m.NewSecretFromEnv("OP_CONNECT_TOKEN")
+//	  if err != nil {
+//	    // handle error
+//	  }
+//
+//	  client := mattress1password.NewClient("http://localhost:8080", token)
+//	  m.Register("op", client)
+//
+//	  password, err := m.Fetch(ctx, "op", "Production/Database/password")
+//	  if err != nil {
+//	    // handle error
+//	  }
+//	  defer password.Destroy()
+//	}
+package mattress1password
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/awnumar/memguard"
+	m "github.com/garrettladley/mattress"
+)
+
+// Client fetches secrets from a 1Password Connect server. It implements
+// mattress.Provider.
+type Client struct {
+	addr  string            // addr is the address of the Connect server
+	token *m.Secret[string] // token authenticates requests to the Connect server
+	http  *http.Client      // http performs requests to the Connect server
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithHTTPClient sets the http.Client used to make requests to the Connect server. It
+// defaults to http.DefaultClient.
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *Client) {
+		c.http = client
+	}
+}
+
+// NewClient returns a Client for the Connect server at addr, authenticating with the
+// Connect token token. The token remains owned by the caller and must outlive the
+// Client.
+func NewClient(addr string, token *m.Secret[string], opts ...Option) *Client {
+	c := &Client{
+		addr:  strings.TrimRight(addr, "/"),
+		token: token,
+		http:  http.DefaultClient,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// Fetch implements mattress.Provider. name takes the form "vault/item/field", as in a
+// 1Password secret reference, which may be prefixed with "op://". The vault and item
+// may be given by name or ID, and the field by label or ID; if "/field" is omitted the
+// field defaults to "password".
+func (c *Client) Fetch(ctx context.Context, name string) (*m.Secret[string], error) {
+	parts := strings.SplitN(strings.TrimPrefix(name, "op://"), "/", 3)
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("mattress1password: invalid secret reference %q", name)
+	}
+
+	field := "password"
+	if len(parts) == 3 && parts[2] != "" {
+		field = parts[2]
+	}
+
+	vault, err := c.resolve(ctx, "vaults", "name", parts[0])
+	if err != nil {
+		return nil, err
+	}
+
+	item, err := c.resolve(ctx, "vaults/"+vault+"/items", "title", parts[1])
+	if err != nil {
+		return nil, err
+	}
+
+	var response struct {
+		Fields []struct {
+			ID    string          `json:"id"`
+			Label string          `json:"label"`
+			Value json.RawMessage `json:"value"`
+		} `json:"fields"`
+	}
+
+	if err := c.do(ctx, "vaults/"+vault+"/items/"+item, nil, &response); err != nil {
+		return nil, err
+	}
+
+	var secret *m.Secret[string]
+
+	for _, f := range response.Fields {
+		if secret == nil && (f.ID == field || f.Label == field) && len(f.Value) > 0 {
+			var value string
+			err = json.Unmarshal(f.Value, &value)
+			if err == nil {
+				secret, err = m.NewSecretString(value)
+			}
+		}
+
+		// WipeBytes securely erases every value once the requested field has been
+		// sealed.
+		memguard.WipeBytes(f.Value)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("mattress1password: decoding field %q of %q: %w", field, name, err)
+	}
+
+	if secret == nil {
+		return nil, fmt.Errorf("mattress1password: field %q of %q: %w", field, name, m.ErrSecretNotFound)
+	}
+
+	return secret, nil
+}
+
+// id matches the IDs of 1Password vaults and items.
+var id = regexp.MustCompile(`^[a-z0-9]{26}$`)
+
+// resolve returns the ID of the vault or item in collection whose attribute is
+// nameOrID, which is returned as is if it is already an ID.
+func (c *Client) resolve(ctx context.Context, collection, attribute, nameOrID string) (string, error) {
+	if id.MatchString(nameOrID) {
+		return nameOrID, nil
+	}
+
+	var response []struct {
+		ID string `json:"id"`
+	}
+
+	filter := url.Values{"filter": {fmt.Sprintf("%s eq %q", attribute, nameOrID)}}
+
+	if err := c.do(ctx, collection, filter, &response); err != nil {
+		return "", err
+	}
+
+	switch len(response) {
+	case 0:
+		return "", fmt.Errorf("mattress1password: %q: %w", nameOrID, m.ErrSecretNotFound)
+	case 1:
+		return response[0].ID, nil
+	default:
+		return "", fmt.Errorf("mattress1password: %q is ambiguous, use its ID", nameOrID)
+	}
+}
+
+// do performs a GET request against the Connect API and decodes the JSON response into
+// out. The response body is wiped once decoded.
+func (c *Client) do(ctx context.Context, endpoint string, query url.Values, out any) error {
+	u, err := url.JoinPath(c.addr, "v1", endpoint)
+	if err != nil {
+		return err
+	}
+
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := c.token.WithExposed(func(token string) error {
+		req.Header.Set("Authorization", "Bearer "+token)
+		return nil
+	}); err != nil {
+		return fmt.Errorf("mattress1password: exposing token: %w", err)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(resp.Body)
+
+	// WipeBytes securely erases the response body once it has been decoded.
+	defer memguard.WipeBytes(raw)
+
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return fmt.Errorf("mattress1password: %s: %w", endpoint, m.ErrSecretNotFound)
+	case resp.StatusCode >= http.StatusBadRequest:
+		var response struct {
+			Message string `json:"message"`
+		}
+		_ = json.Unmarshal(raw, &response)
+
+		return fmt.Errorf("mattress1password: unexpected status %d: %s", resp.StatusCode, response.Message)
+	}
+
+	return json.Unmarshal(raw, out)
+}
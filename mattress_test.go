@@ -0,0 +1,109 @@
+package mattress_test
+
+import (
+	"math"
+	"testing"
+
+	m "github.com/garrettladley/mattress"
+)
+
+// benchmarkStorage lists the storage a Secret may be backed by, as the options selecting
+// it.
+var benchmarkStorage = []struct {
+	name string
+	opts []m.Option
+}{
+	{name: "locked"},
+	{name: "sealed", opts: []m.Option{m.WithSealedStorage()}},
+}
+
+func BenchmarkNewSecret(b *testing.B) {
+	for _, storage := range benchmarkStorage {
+		b.Run(storage.name, func(b *testing.B) {
+			b.ReportAllocs()
+
+			for b.Loop() {
+				secret, err := m.NewSecret("correct horse battery staple", storage.opts...)
+				if err != nil {
+					b.Fatal(err)
+				}
+				secret.Destroy()
+			}
+		})
+	}
+}
+
+func BenchmarkExpose(b *testing.B) {
+	for _, storage := range benchmarkStorage {
+		b.Run(storage.name, func(b *testing.B) {
+			secret, err := m.NewSecret("correct horse battery staple", storage.opts...)
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer secret.Destroy()
+
+			b.ReportAllocs()
+
+			for b.Loop() {
+				if _, err := secret.ExposeErr(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkExposeParallel(b *testing.B) {
+	for _, storage := range benchmarkStorage {
+		b.Run(storage.name, func(b *testing.B) {
+			secret, err := m.NewSecret("correct horse battery staple", storage.opts...)
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer secret.Destroy()
+
+			b.ReportAllocs()
+
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					if _, err := secret.ExposeErr(); err != nil {
+						b.Error(err)
+						return
+					}
+				}
+			})
+		})
+	}
+}
+
+// BenchmarkExposeShared compares concurrent exposures of a Secret without an exposure
+// limit, which share a read lock, with those of one created with WithMaxExposures,
+// which must each take the write lock to count themselves.
+func BenchmarkExposeShared(b *testing.B) {
+	for _, bench := range []struct {
+		name string
+		opts []m.Option
+	}{
+		{name: "read-locked"},
+		{name: "write-locked", opts: []m.Option{m.WithMaxExposures(math.MaxInt)}},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			secret, err := m.NewSecret("correct horse battery staple", bench.opts...)
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer secret.Destroy()
+
+			b.ReportAllocs()
+
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					if _, err := secret.ExposeErr(); err != nil {
+						b.Error(err)
+						return
+					}
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,199 @@
+// mattressage wraps data keys with age, for envelope encryption of mattress Secrets
+// without a cloud KMS, and loads age and SOPS encrypted files directly into Secrets, so
+// that encrypted configuration managed with GitOps never touches disk in plaintext.
+//
+// Data keys are encrypted to one or more age recipients, and decrypted with identities
+// held in a Secret, which are only parsed for the duration of each unwrap.
+//
+// Example Usage:
+//
+//	import (
+//	  m "github.com/garrettladley/mattress"
+//	  "github.com/garrettladley/mattress/mattressage"
+//	)
+//
+//	func main() {
+//	  identity, err := m.NewSecretFromFile("/etc/app/age.key", m.WithStrictPermissions())
+//	  if err != nil {
+//	    // handle error
+//	  }
+//
+//	  wrapper, err := mattressage.NewWrapper(identity)
+//	  if err != nil {
+//	    // handle error
+//	  }
+//
+//	  blob, err := secret.Export(ctx, wrapper)
+//	  if err != nil {
+//	    // handle error
+//	  }
+//	}
+package mattressage
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	m "github.com/garrettladley/mattress"
+)
+
+// maxKeySize bounds the size of an unwrapped data key.
+const maxKeySize = 1 << 10
+
+// Wrapper is a mattress.Wrapper which encrypts data keys to age recipients and
+// decrypts them with age identities held in a Secret.
+type Wrapper struct {
+	recipients []age.Recipient   // recipients are the recipients data keys are wrapped for
+	identities *m.Secret[[]byte] // identities holds the encoded identities, if any
+}
+
+// NewWrapper returns a Wrapper for the age identities held by identities, in the format
+// of an age identity file. Data keys are wrapped for the recipients corresponding to
+// the identities, along with any additional recipients. identities remains owned by
+// the caller and must outlive the Wrapper.
+func NewWrapper(identities *m.Secret[[]byte], recipients ...age.Recipient) (*Wrapper, error) {
+	w := &Wrapper{identities: identities, recipients: recipients}
+
+	err := w.withIdentities(func(ids []age.Identity) error {
+		for _, id := range ids {
+			x25519, ok := id.(*age.X25519Identity)
+			if !ok {
+				return errors.New("mattressage: only X25519 identities can be used to derive recipients")
+			}
+
+			w.recipients = append(w.recipients, x25519.Recipient())
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// NewRecipientWrapper returns a Wrapper which can only wrap data keys, for the given
+// recipients. Its UnwrapKey always fails.
+func NewRecipientWrapper(recipients ...age.Recipient) (*Wrapper, error) {
+	if len(recipients) == 0 {
+		return nil, errors.New("mattressage: at least one recipient is required")
+	}
+
+	return &Wrapper{recipients: recipients}, nil
+}
+
+// WrapKey implements mattress.Wrapper, encrypting key to the recipients of the
+// Wrapper.
+func (w *Wrapper) WrapKey(_ context.Context, key *m.Secret[[]byte]) ([]byte, error) {
+	var wrapped bytes.Buffer
+
+	err := key.WithExposed(func(plaintext []byte) error {
+		writer, err := age.Encrypt(&wrapped, w.recipients...)
+		if err != nil {
+			return err
+		}
+
+		if _, err := writer.Write(plaintext); err != nil {
+			return err
+		}
+
+		return writer.Close()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return wrapped.Bytes(), nil
+}
+
+// UnwrapKey implements mattress.Wrapper, decrypting wrapped with the identities of the
+// Wrapper. The data key is read directly into protected memory.
+func (w *Wrapper) UnwrapKey(_ context.Context, wrapped []byte) (*m.Secret[[]byte], error) {
+	if w.identities == nil {
+		return nil, errors.New("mattressage: wrapper has no identities")
+	}
+
+	var key *m.Secret[[]byte]
+
+	err := w.withIdentities(func(ids []age.Identity) error {
+		reader, err := age.Decrypt(bytes.NewReader(wrapped), ids...)
+		if err != nil {
+			return err
+		}
+
+		key, err = m.NewSecretFromReader(reader, maxKeySize)
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// LoadFile decrypts the age encrypted file at path, which may be ASCII armored, with
+// the age identities held by identities, in the format of an age identity file. The
+// plaintext is read directly into protected memory. An error wrapping
+// mattress.ErrSecretNotFound is returned if the file does not exist.
+func LoadFile(path string, identities *m.Secret[[]byte], opts ...m.Option) (*m.Secret[[]byte], error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("%w: %w", m.ErrSecretNotFound, err)
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var secret *m.Secret[[]byte]
+
+	err = withIdentities(identities, func(ids []age.Identity) error {
+		buffered := bufio.NewReader(f)
+
+		var src io.Reader = buffered
+		if header, _ := buffered.Peek(len(armor.Header)); string(header) == armor.Header {
+			src = armor.NewReader(buffered)
+		}
+
+		reader, err := age.Decrypt(src, ids...)
+		if err != nil {
+			return err
+		}
+
+		secret, err = m.NewSecretFromReader(reader, 0, opts...)
+
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("mattressage: decrypting %s: %w", path, err)
+	}
+
+	return secret, nil
+}
+
+// withIdentities parses the identities of the Wrapper and calls fn with them.
+func (w *Wrapper) withIdentities(fn func([]age.Identity) error) error {
+	return withIdentities(w.identities, fn)
+}
+
+// withIdentities parses the identities held by identities and calls fn with them.
+func withIdentities(identities *m.Secret[[]byte], fn func([]age.Identity) error) error {
+	return identities.WithExposed(func(plaintext []byte) error {
+		ids, err := age.ParseIdentities(bytes.NewReader(plaintext))
+		if err != nil {
+			return err
+		}
+
+		return fn(ids)
+	})
+}
@@ -0,0 +1,374 @@
+package mattressage
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unsafe"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/awnumar/memguard"
+	m "github.com/garrettladley/mattress"
+	"gopkg.in/yaml.v3"
+)
+
+// sopsDataKeySize is the size of the AES-256 data key of a SOPS file.
+const sopsDataKeySize = 32
+
+// sopsValue matches a value encrypted by SOPS.
+var sopsValue = regexp.MustCompile(`^ENC\[AES256_GCM,data:(.*),iv:(.+),tag:(.+),type:(.+)\]$`)
+
+// sopsMetadata is the subset of the sops key of a SOPS file needed to decrypt it with
+// age.
+type sopsMetadata struct {
+	Age []struct {
+		Recipient string `yaml:"recipient"`
+		Enc       string `yaml:"enc"`
+	} `yaml:"age"`
+	LastModified     string `yaml:"lastmodified"`
+	MAC              string `yaml:"mac"`
+	MACOnlyEncrypted bool   `yaml:"mac_only_encrypted"`
+}
+
+// LoadSOPS decrypts the SOPS encrypted YAML or JSON file at path, whose data key is
+// encrypted to an age recipient, with the age identities held by identities, in the
+// format of an age identity file. Every scalar value in the file is returned as a
+// Secret created with opts, keyed by its path with keys and list indices joined by
+// dots, such as "database.password" or "hosts.0". Each value is decrypted in place and
+// secured immediately, and the integrity of the file as a whole is verified against its
+// MAC before anything is returned.
+//
+// Only age is supported: files whose data key is encrypted solely with a KMS or PGP
+// return an error. An error wrapping mattress.ErrSecretNotFound is returned if the
+// file does not exist, and one wrapping mattress.ErrDecryptionFailed if the file has
+// been tampered with.
+func LoadSOPS(path string, identities *m.Secret[[]byte], opts ...m.Option) (map[string]*m.Secret[string], error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("%w: %w", m.ErrSecretNotFound, err)
+		}
+		return nil, err
+	}
+
+	secrets, err := decryptSOPS(raw, identities, opts)
+	if err != nil {
+		return nil, fmt.Errorf("mattressage: decrypting %s: %w", path, err)
+	}
+
+	return secrets, nil
+}
+
+// decryptSOPS decrypts the SOPS encrypted document raw, as described by LoadSOPS.
+func decryptSOPS(raw []byte, identities *m.Secret[[]byte], opts []m.Option) (map[string]*m.Secret[string], error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(raw, &doc); err != nil {
+		return nil, err
+	}
+
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, errors.New("not a SOPS encrypted document")
+	}
+
+	root := doc.Content[0]
+
+	var metadata sopsMetadata
+
+	found := false
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == "sops" {
+			if err := root.Content[i+1].Decode(&metadata); err != nil {
+				return nil, fmt.Errorf("decoding SOPS metadata: %w", err)
+			}
+			found = true
+		}
+	}
+
+	if !found {
+		return nil, errors.New("not a SOPS encrypted document")
+	}
+
+	if len(metadata.Age) == 0 {
+		return nil, errors.New("data key is not encrypted to any age recipient")
+	}
+
+	key, err := sopsDataKey(metadata, identities)
+	if err != nil {
+		return nil, err
+	}
+	defer key.Destroy()
+
+	d := &sopsDecrypter{
+		secrets:       make(map[string]*m.Secret[string]),
+		mac:           sha512.New(),
+		encryptedOnly: metadata.MACOnlyEncrypted,
+		opts:          opts,
+	}
+
+	err = key.WithExposed(func(k []byte) error {
+		if len(k) != sopsDataKeySize {
+			return fmt.Errorf("decrypting data key: %w", m.ErrDecryptionFailed)
+		}
+
+		block, err := aes.NewCipher(k)
+		if err != nil {
+			return err
+		}
+
+		d.block = block
+
+		if err := d.walkMapping(root, nil, "", true); err != nil {
+			return err
+		}
+
+		return d.verify(metadata)
+	})
+	if err != nil {
+		for _, secret := range d.secrets {
+			secret.Destroy()
+		}
+		return nil, err
+	}
+
+	return d.secrets, nil
+}
+
+// sopsDataKey decrypts the data key of a SOPS file with the first of its age
+// recipients which identities can decrypt.
+func sopsDataKey(metadata sopsMetadata, identities *m.Secret[[]byte]) (*m.Secret[[]byte], error) {
+	var key *m.Secret[[]byte]
+
+	err := withIdentities(identities, func(ids []age.Identity) error {
+		var err error
+
+		for _, recipient := range metadata.Age {
+			var reader io.Reader
+
+			reader, err = age.Decrypt(armor.NewReader(strings.NewReader(recipient.Enc)), ids...)
+			if err != nil {
+				continue
+			}
+
+			key, err = m.NewSecretFromReader(reader, sopsDataKeySize)
+			if err == nil {
+				return nil
+			}
+		}
+
+		return fmt.Errorf("decrypting data key: %w", err)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// sopsDecrypter decrypts the values of a SOPS document while computing its MAC.
+type sopsDecrypter struct {
+	block         cipher.Block                 // block is the AES cipher keyed with the data key
+	secrets       map[string]*m.Secret[string] // secrets holds the values decrypted so far
+	mac           hash.Hash                    // mac hashes the values in document order
+	encryptedOnly bool                         // encryptedOnly is whether only encrypted values are hashed
+	opts          []m.Option                   // opts are the options each Secret is created with
+}
+
+// walkMapping decrypts the values of node, whose keys are at path, skipping the SOPS
+// metadata at the root.
+func (d *sopsDecrypter) walkMapping(node *yaml.Node, path []string, name string, root bool) error {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := node.Content[i].Value
+		if root && key == "sops" {
+			continue
+		}
+
+		if err := d.walk(node.Content[i+1], append(path, key), join(name, key)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// walk decrypts node, which is at path, storing its scalar values under name. As in
+// SOPS, the elements of a list share the path of the list itself.
+func (d *sopsDecrypter) walk(node *yaml.Node, path []string, name string) error {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return d.walkMapping(node, path, name, false)
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			if err := d.walk(item, path, join(name, strconv.Itoa(i))); err != nil {
+				return err
+			}
+		}
+		return nil
+	case yaml.ScalarNode:
+		return d.scalar(node, path, name)
+	default:
+		return fmt.Errorf("%s: unsupported YAML node", name)
+	}
+}
+
+// scalar decrypts the scalar node at path, if it is encrypted, and stores it under
+// name.
+func (d *sopsDecrypter) scalar(node *yaml.Node, path []string, name string) error {
+	if node.Tag == "!!null" {
+		return nil
+	}
+
+	match := sopsValue.FindStringSubmatch(node.Value)
+	if node.Tag != "!!str" || match == nil {
+		if !d.encryptedOnly {
+			plaintext, err := sopsBytes(node)
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			d.mac.Write(plaintext)
+		}
+
+		secret, err := m.NewSecretString(node.Value, d.opts...)
+		if err != nil {
+			return err
+		}
+
+		d.secrets[name] = secret
+
+		return nil
+	}
+
+	plaintext, err := d.decrypt(match, strings.Join(path, ":")+":")
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+
+	// WipeBytes securely erases the plaintext once it has been secured.
+	defer memguard.WipeBytes(plaintext)
+
+	d.mac.Write(plaintext)
+
+	// SOPS encrypts booleans formatted as Python does, rather than as YAML and JSON do.
+	if match[4] == "bool" {
+		for i, c := range plaintext {
+			plaintext[i] = byte(unicode.ToLower(rune(c)))
+		}
+	}
+
+	secret, err := m.NewSecretString(unsafe.String(unsafe.SliceData(plaintext), len(plaintext)), d.opts...)
+	if err != nil {
+		return err
+	}
+
+	d.secrets[name] = secret
+
+	return nil
+}
+
+// decrypt decrypts the value matched by sopsValue, authenticating it with
+// additionalData. The plaintext must be wiped by the caller.
+func (d *sopsDecrypter) decrypt(match []string, additionalData string) ([]byte, error) {
+	var fields [3][]byte
+	for i := range fields {
+		field, err := base64.StdEncoding.DecodeString(match[i+1])
+		if err != nil {
+			return nil, m.ErrDecryptionFailed
+		}
+		fields[i] = field
+	}
+
+	data, iv, tag := fields[0], fields[1], fields[2]
+
+	gcm, err := cipher.NewGCMWithNonceSize(d.block, len(iv))
+	if err != nil || len(tag) != gcm.Overhead() {
+		return nil, m.ErrDecryptionFailed
+	}
+
+	// Open decrypts in place, so that the plaintext is never copied.
+	plaintext, err := gcm.Open(data[:0], iv, append(data, tag...), []byte(additionalData))
+	if err != nil {
+		return nil, m.ErrDecryptionFailed
+	}
+
+	return plaintext, nil
+}
+
+// verify checks the MAC of the document against the hash of its values.
+func (d *sopsDecrypter) verify(metadata sopsMetadata) error {
+	match := sopsValue.FindStringSubmatch(metadata.MAC)
+	if match == nil {
+		return fmt.Errorf("verifying MAC: %w", m.ErrDecryptionFailed)
+	}
+
+	// SOPS authenticates the MAC with the modification time, formatted as RFC 3339.
+	modified, err := time.Parse(time.RFC3339, metadata.LastModified)
+	if err != nil {
+		return fmt.Errorf("verifying MAC: %w", m.ErrDecryptionFailed)
+	}
+
+	expected, err := d.decrypt(match, modified.Format(time.RFC3339))
+	if err != nil {
+		return fmt.Errorf("verifying MAC: %w", err)
+	}
+
+	computed := strings.ToUpper(hex.EncodeToString(d.mac.Sum(nil)))
+
+	if subtle.ConstantTimeCompare([]byte(computed), bytes.ToUpper(expected)) != 1 {
+		return fmt.Errorf("verifying MAC: %w", m.ErrDecryptionFailed)
+	}
+
+	return nil
+}
+
+// sopsBytes returns the bytes SOPS hashes into the MAC for the unencrypted scalar node.
+func sopsBytes(node *yaml.Node) ([]byte, error) {
+	switch node.Tag {
+	case "!!int":
+		var n int
+		if err := node.Decode(&n); err != nil {
+			return nil, err
+		}
+		return []byte(strconv.Itoa(n)), nil
+	case "!!float":
+		var f float64
+		if err := node.Decode(&f); err != nil {
+			return nil, err
+		}
+		return []byte(strconv.FormatFloat(f, 'f', -1, 64)), nil
+	case "!!bool":
+		var b bool
+		if err := node.Decode(&b); err != nil {
+			return nil, err
+		}
+		// SOPS formats booleans as Python does.
+		if b {
+			return []byte("True"), nil
+		}
+		return []byte("False"), nil
+	default:
+		return []byte(node.Value), nil
+	}
+}
+
+// join appends key to the dotted path name.
+func join(name, key string) string {
+	if name == "" {
+		return key
+	}
+	return name + "." + key
+}
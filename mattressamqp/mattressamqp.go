@@ -0,0 +1,70 @@
+// mattressamqp supplies passwords held in mattress Secrets to amqp091-go connections.
+//
+// The password is read afresh for every dial, so reconnect logic which dials again
+// with the same PlainAuth picks up a password held by a Rotator, or any other source
+// refreshed in the background, as soon as it changes.
+//
+// Example Usage:
+//
+//	import (
+//	  m "github.com/garrettladley/mattress"
+//	  "github.com/garrettladley/mattress/mattressamqp"
+//	)
+//
+//	func main() {
+//	  password, err := m.NewSecretFromEnv("AMQP_PASSWORD")
+//	  if err != nil {
+//	    // handle error
+//	  }
+//
+//	  conn, err := amqp.DialConfig("amqps://broker:5671/", amqp.Config{
+//	    SASL:      []amqp.Authentication{mattressamqp.NewPlainAuth("app", password)},
+//	    Heartbeat: 10 * time.Second,
+//	  })
+//	}
+package mattressamqp
+
+// Password is a source of the broker password. It is implemented by
+// *m.Secret[string], *m.Rotator[string], and *mattressvault.LeasedSecret, among others.
+type Password interface {
+	// WithExposed calls fn with the current password, which must not be retained.
+	WithExposed(fn func(password string) error) error
+}
+
+// PlainAuth is an amqp.Authentication which authenticates with the PLAIN mechanism,
+// as amqp.PlainAuth does, using the current password of a Password. Credentials given
+// in the URL passed to amqp.DialConfig are ignored when it is set in the SASL field of
+// amqp.Config.
+type PlainAuth struct {
+	username string   // username is the user to authenticate as
+	password Password // password is read afresh for every dial
+}
+
+// NewPlainAuth returns a PlainAuth which authenticates as username with the current
+// password of password. The password remains owned by the caller.
+func NewPlainAuth(username string, password Password) *PlainAuth {
+	return &PlainAuth{username: username, password: password}
+}
+
+// Mechanism returns "PLAIN".
+func (a *PlainAuth) Mechanism() string {
+	return "PLAIN"
+}
+
+// Response returns the PLAIN response carrying the username and current password. If
+// the password cannot be exposed, for example because its Secret has been destroyed,
+// Response returns an empty response, which the broker rejects. amqp091-go holds the
+// response in an ordinary string only for the duration of the handshake.
+func (a *PlainAuth) Response() string {
+	var response string
+
+	err := a.password.WithExposed(func(password string) error {
+		response = "\x00" + a.username + "\x00" + password
+		return nil
+	})
+	if err != nil {
+		return ""
+	}
+
+	return response
+}
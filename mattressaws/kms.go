@@ -0,0 +1,68 @@
+package mattressaws
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	m "github.com/garrettladley/mattress"
+)
+
+// KMSWrapper is a mattress.Wrapper which wraps data keys with a symmetric AWS KMS key,
+// for use with Secret.Export and mattress.ImportSecret.
+type KMSWrapper struct {
+	client KMSAPI // client performs KMS requests
+	keyID  string // keyID identifies the key encryption key
+}
+
+// NewKMSWrapper returns a KMSWrapper which wraps data keys with the KMS key identified
+// by keyID, which may be a key ID, key ARN, alias name, or alias ARN.
+func NewKMSWrapper(ctx context.Context, keyID string, opts ...Option) (*KMSWrapper, error) {
+	o := newOptions(opts)
+
+	client, err := o.kmsClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &KMSWrapper{client: client, keyID: keyID}, nil
+}
+
+// WrapKey implements mattress.Wrapper, encrypting key with KMS.
+func (w *KMSWrapper) WrapKey(ctx context.Context, key *m.Secret[[]byte]) ([]byte, error) {
+	var wrapped []byte
+
+	err := key.WithExposed(func(plaintext []byte) error {
+		out, err := w.client.Encrypt(ctx, &kms.EncryptInput{
+			KeyId:     aws.String(w.keyID),
+			Plaintext: plaintext,
+		})
+		if err != nil {
+			return err
+		}
+
+		wrapped = out.CiphertextBlob
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return wrapped, nil
+}
+
+// UnwrapKey implements mattress.Wrapper, decrypting wrapped with KMS and sealing the
+// data key into a Secret owned by the caller.
+func (w *KMSWrapper) UnwrapKey(ctx context.Context, wrapped []byte) (*m.Secret[[]byte], error) {
+	out, err := w.client.Decrypt(ctx, &kms.DecryptInput{
+		KeyId:          aws.String(w.keyID),
+		CiphertextBlob: wrapped,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// NewSecretBytes wipes the decrypted data key once it has been sealed.
+	return m.NewSecretBytes(out.Plaintext)
+}
@@ -0,0 +1,188 @@
+// mattressaws sources mattress Secrets from AWS Secrets Manager and AWS Systems Manager
+// Parameter Store, and wraps data keys with AWS KMS for envelope encryption.
+//
+// Values are sealed into a Secret as soon as they are returned by the AWS SDK, and any
+// binary payloads are wiped once sealed. Watchers optionally keep a Secret up to date
+// in the background, fetching a new value only once a new version has been published.
+//
+// Example Usage:
+//
+//	import (
+//	  "github.com/garrettladley/mattress/mattressaws"
+//	)
+//
+//	func main() {
+//	  password, err := mattressaws.NewSecretFromSecretsManager(ctx, "arn:aws:secretsmanager:...")
+//	  if err != nil {
+//	    // handle error
+//	  }
+//	  defer password.Destroy()
+//	}
+package mattressaws
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+)
+
+// SecretsManagerAPI is the subset of the Secrets Manager client used by this package.
+// It is satisfied by *secretsmanager.Client.
+type SecretsManagerAPI interface {
+	GetSecretValue(ctx context.Context, params *secretsmanager.GetSecretValueInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error)
+	DescribeSecret(ctx context.Context, params *secretsmanager.DescribeSecretInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.DescribeSecretOutput, error)
+}
+
+// SSMAPI is the subset of the Systems Manager client used by this package. It is
+// satisfied by *ssm.Client.
+type SSMAPI interface {
+	GetParameter(ctx context.Context, params *ssm.GetParameterInput, optFns ...func(*ssm.Options)) (*ssm.GetParameterOutput, error)
+	DescribeParameters(ctx context.Context, params *ssm.DescribeParametersInput, optFns ...func(*ssm.Options)) (*ssm.DescribeParametersOutput, error)
+}
+
+// KMSAPI is the subset of the KMS client used by this package. It is satisfied by
+// *kms.Client.
+type KMSAPI interface {
+	Encrypt(ctx context.Context, params *kms.EncryptInput, optFns ...func(*kms.Options)) (*kms.EncryptOutput, error)
+	Decrypt(ctx context.Context, params *kms.DecryptInput, optFns ...func(*kms.Options)) (*kms.DecryptOutput, error)
+}
+
+// Option configures how secrets are fetched from AWS.
+type Option func(*options)
+
+// options holds the settings accumulated from the Options passed to a function.
+type options struct {
+	config         *aws.Config       // config is used to construct clients
+	secretsManager SecretsManagerAPI // secretsManager overrides the Secrets Manager client
+	ssm            SSMAPI            // ssm overrides the Systems Manager client
+	kms            KMSAPI            // kms overrides the KMS client
+	versionStage   string            // versionStage selects a Secrets Manager version
+	onError        func(error)       // onError is notified of background failures
+}
+
+// WithConfig sets the aws.Config clients are constructed from. By default the
+// configuration is loaded with config.LoadDefaultConfig.
+func WithConfig(cfg aws.Config) Option {
+	return func(o *options) {
+		o.config = &cfg
+	}
+}
+
+// WithSecretsManagerClient sets the Secrets Manager client used, taking precedence
+// over WithConfig.
+func WithSecretsManagerClient(client SecretsManagerAPI) Option {
+	return func(o *options) {
+		o.secretsManager = client
+	}
+}
+
+// WithSSMClient sets the Systems Manager client used, taking precedence over
+// WithConfig.
+func WithSSMClient(client SSMAPI) Option {
+	return func(o *options) {
+		o.ssm = client
+	}
+}
+
+// WithKMSClient sets the KMS client used, taking precedence over WithConfig.
+func WithKMSClient(client KMSAPI) Option {
+	return func(o *options) {
+		o.kms = client
+	}
+}
+
+// WithVersionStage selects the staging label of the Secrets Manager version to fetch.
+// It defaults to "AWSCURRENT".
+func WithVersionStage(stage string) Option {
+	return func(o *options) {
+		o.versionStage = stage
+	}
+}
+
+// OnError registers fn to be called with any error a Watcher encounters while
+// refreshing its secret in the background. Failed refreshes are retried at the next
+// interval.
+func OnError(fn func(error)) Option {
+	return func(o *options) {
+		o.onError = fn
+	}
+}
+
+// newOptions applies opts on top of the default options.
+func newOptions(opts []Option) options {
+	o := options{
+		versionStage: "AWSCURRENT",
+		onError:      func(error) {},
+	}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+// awsConfig returns the configured aws.Config, loading the default configuration if
+// none was provided.
+func (o *options) awsConfig(ctx context.Context) (aws.Config, error) {
+	if o.config != nil {
+		return *o.config, nil
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return aws.Config{}, err
+	}
+
+	o.config = &cfg
+
+	return cfg, nil
+}
+
+// secretsManagerClient returns the configured Secrets Manager client, constructing
+// one if none was provided.
+func (o *options) secretsManagerClient(ctx context.Context) (SecretsManagerAPI, error) {
+	if o.secretsManager != nil {
+		return o.secretsManager, nil
+	}
+
+	cfg, err := o.awsConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return secretsmanager.NewFromConfig(cfg), nil
+}
+
+// ssmClient returns the configured Systems Manager client, constructing one if none
+// was provided.
+func (o *options) ssmClient(ctx context.Context) (SSMAPI, error) {
+	if o.ssm != nil {
+		return o.ssm, nil
+	}
+
+	cfg, err := o.awsConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return ssm.NewFromConfig(cfg), nil
+}
+
+// kmsClient returns the configured KMS client, constructing one if none was provided.
+func (o *options) kmsClient(ctx context.Context) (KMSAPI, error) {
+	if o.kms != nil {
+		return o.kms, nil
+	}
+
+	cfg, err := o.awsConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return kms.NewFromConfig(cfg), nil
+}
@@ -0,0 +1,125 @@
+package mattressaws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	m "github.com/garrettladley/mattress"
+)
+
+// NewSecretFromParameterStore fetches the parameter called name from AWS Systems
+// Manager Parameter Store, decrypting SecureString parameters. The returned Secret is
+// owned by the caller.
+func NewSecretFromParameterStore(ctx context.Context, name string, opts ...Option) (*m.Secret[string], error) {
+	o := newOptions(opts)
+
+	client, err := o.ssmClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, _, err := getParameter(ctx, client, name)
+
+	return secret, err
+}
+
+// ParameterStoreProvider is a mattress.Provider which fetches parameters by name from
+// AWS Systems Manager Parameter Store.
+type ParameterStoreProvider struct {
+	client SSMAPI
+}
+
+// NewParameterStoreProvider returns a ParameterStoreProvider.
+func NewParameterStoreProvider(ctx context.Context, opts ...Option) (*ParameterStoreProvider, error) {
+	o := newOptions(opts)
+
+	client, err := o.ssmClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ParameterStoreProvider{client: client}, nil
+}
+
+// Fetch implements mattress.Provider.
+func (p *ParameterStoreProvider) Fetch(ctx context.Context, name string) (*m.Secret[string], error) {
+	secret, _, err := getParameter(ctx, p.client, name)
+
+	return secret, err
+}
+
+// WatchParameterStore fetches the parameter called name from AWS Systems Manager
+// Parameter Store and checks every interval whether a new version has been published,
+// fetching it if so. Checks use DescribeParameters, so the parameter value is only
+// transferred when it has changed.
+func WatchParameterStore(ctx context.Context, name string, interval time.Duration, opts ...Option) (*Watcher, error) {
+	o := newOptions(opts)
+
+	client, err := o.ssmClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return watch(ctx, interval, o.onError, source{
+		fetch: func(ctx context.Context) (*m.Secret[string], string, error) {
+			return getParameter(ctx, client, name)
+		},
+		version: func(ctx context.Context) (string, error) {
+			return describeParameterVersion(ctx, client, name)
+		},
+	})
+}
+
+// getParameter fetches and seals a parameter from Parameter Store, returning it along
+// with its version.
+func getParameter(ctx context.Context, client SSMAPI, name string) (*m.Secret[string], string, error) {
+	out, err := client.GetParameter(ctx, &ssm.GetParameterInput{
+		Name:           aws.String(name),
+		WithDecryption: aws.Bool(true),
+	})
+	if err != nil {
+		var notFound *types.ParameterNotFound
+		if errors.As(err, &notFound) {
+			return nil, "", fmt.Errorf("mattressaws: %s: %w", name, m.ErrSecretNotFound)
+		}
+		return nil, "", err
+	}
+
+	if out.Parameter == nil {
+		return nil, "", fmt.Errorf("mattressaws: %s: %w", name, m.ErrSecretNotFound)
+	}
+
+	version := strconv.FormatInt(out.Parameter.Version, 10)
+
+	secret, err := m.NewSecretString(aws.ToString(out.Parameter.Value))
+	out.Parameter.Value = nil
+
+	return secret, version, err
+}
+
+// describeParameterVersion returns the latest version of the parameter called name,
+// without fetching its value.
+func describeParameterVersion(ctx context.Context, client SSMAPI, name string) (string, error) {
+	out, err := client.DescribeParameters(ctx, &ssm.DescribeParametersInput{
+		ParameterFilters: []types.ParameterStringFilter{{
+			Key:    aws.String("Name"),
+			Option: aws.String("Equals"),
+			Values: []string{name},
+		}},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if len(out.Parameters) == 0 {
+		return "", fmt.Errorf("mattressaws: %s: %w", name, m.ErrSecretNotFound)
+	}
+
+	return strconv.FormatInt(out.Parameters[0].Version, 10), nil
+}
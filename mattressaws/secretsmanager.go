@@ -0,0 +1,130 @@
+package mattressaws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/awnumar/memguard"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+	m "github.com/garrettladley/mattress"
+)
+
+// NewSecretFromSecretsManager fetches the secret identified by id, which may be a name
+// or ARN, from AWS Secrets Manager. Both string and binary secrets are supported; the
+// returned Secret is owned by the caller.
+func NewSecretFromSecretsManager(ctx context.Context, id string, opts ...Option) (*m.Secret[string], error) {
+	o := newOptions(opts)
+
+	client, err := o.secretsManagerClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, _, err := getSecretValue(ctx, client, id, o.versionStage)
+
+	return secret, err
+}
+
+// SecretsManagerProvider is a mattress.Provider which fetches secrets by name or ARN
+// from AWS Secrets Manager.
+type SecretsManagerProvider struct {
+	client       SecretsManagerAPI
+	versionStage string
+}
+
+// NewSecretsManagerProvider returns a SecretsManagerProvider.
+func NewSecretsManagerProvider(ctx context.Context, opts ...Option) (*SecretsManagerProvider, error) {
+	o := newOptions(opts)
+
+	client, err := o.secretsManagerClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SecretsManagerProvider{client: client, versionStage: o.versionStage}, nil
+}
+
+// Fetch implements mattress.Provider.
+func (p *SecretsManagerProvider) Fetch(ctx context.Context, name string) (*m.Secret[string], error) {
+	secret, _, err := getSecretValue(ctx, p.client, name, p.versionStage)
+
+	return secret, err
+}
+
+// WatchSecretsManager fetches the secret identified by id from AWS Secrets Manager and
+// checks every interval whether a new version has been promoted to the selected
+// staging label, fetching it if so. Checks use DescribeSecret, so the secret value is
+// only transferred when it has changed.
+func WatchSecretsManager(ctx context.Context, id string, interval time.Duration, opts ...Option) (*Watcher, error) {
+	o := newOptions(opts)
+
+	client, err := o.secretsManagerClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return watch(ctx, interval, o.onError, source{
+		fetch: func(ctx context.Context) (*m.Secret[string], string, error) {
+			return getSecretValue(ctx, client, id, o.versionStage)
+		},
+		version: func(ctx context.Context) (string, error) {
+			return describeSecretVersion(ctx, client, id, o.versionStage)
+		},
+	})
+}
+
+// getSecretValue fetches and seals a secret from Secrets Manager, returning it along
+// with its version ID.
+func getSecretValue(ctx context.Context, client SecretsManagerAPI, id string, stage string) (*m.Secret[string], string, error) {
+	out, err := client.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{
+		SecretId:     aws.String(id),
+		VersionStage: aws.String(stage),
+	})
+	if err != nil {
+		var notFound *types.ResourceNotFoundException
+		if errors.As(err, &notFound) {
+			return nil, "", fmt.Errorf("mattressaws: %s: %w", id, m.ErrSecretNotFound)
+		}
+		return nil, "", err
+	}
+
+	version := aws.ToString(out.VersionId)
+
+	if out.SecretBinary != nil {
+		// WipeBytes securely erases the binary payload once it has been sealed.
+		defer memguard.WipeBytes(out.SecretBinary)
+
+		secret, err := m.NewSecretString(string(out.SecretBinary))
+
+		return secret, version, err
+	}
+
+	secret, err := m.NewSecretString(aws.ToString(out.SecretString))
+	out.SecretString = nil
+
+	return secret, version, err
+}
+
+// describeSecretVersion returns the ID of the version carrying the staging label stage,
+// without fetching the secret value.
+func describeSecretVersion(ctx context.Context, client SecretsManagerAPI, id string, stage string) (string, error) {
+	out, err := client.DescribeSecret(ctx, &secretsmanager.DescribeSecretInput{
+		SecretId: aws.String(id),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	for version, stages := range out.VersionIdsToStages {
+		if slices.Contains(stages, stage) {
+			return version, nil
+		}
+	}
+
+	return "", fmt.Errorf("mattressaws: %s has no version labelled %s", id, stage)
+}
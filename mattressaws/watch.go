@@ -0,0 +1,131 @@
+package mattressaws
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	m "github.com/garrettladley/mattress"
+)
+
+// Watcher keeps a secret fetched from AWS up to date in the background, replacing it
+// whenever a new version is published.
+type Watcher struct {
+	source  source            // source fetches the secret and checks its version
+	current *m.Secret[string] // current is the most recently fetched secret
+	version string            // version identifies the version of current
+	onError func(error)       // onError is notified of background failures
+	lock    sync.RWMutex      // synchronize access to current and version
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+// source describes how a Watcher fetches its secret and checks for new versions.
+type source struct {
+	fetch   func(ctx context.Context) (*m.Secret[string], string, error)
+	version func(ctx context.Context) (string, error)
+}
+
+// watch fetches the secret from src and starts checking for new versions every
+// interval until the Watcher is closed or ctx is cancelled.
+func watch(ctx context.Context, interval time.Duration, onError func(error), src source) (*Watcher, error) {
+	secret, version, err := src.fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+
+	w := &Watcher{
+		source:  src,
+		current: secret,
+		version: version,
+		onError: onError,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+
+	go w.run(ctx, interval)
+
+	return w, nil
+}
+
+// Current returns the most recently fetched secret. The Secret remains owned by the
+// Watcher and is destroyed once it is replaced, so callers should call Current each
+// time the secret is needed rather than retaining it.
+func (w *Watcher) Current() *m.Secret[string] {
+	w.lock.RLock()         // RLock before reading the current secret
+	defer w.lock.RUnlock() // Ensure the lock is RUnlocked when the method returns
+
+	return w.current
+}
+
+// WithExposed exposes the most recently fetched secret to fn, guaranteeing that it is
+// not replaced while fn runs.
+func (w *Watcher) WithExposed(fn func(string) error) error {
+	w.lock.RLock()         // RLock before reading the current secret
+	defer w.lock.RUnlock() // Ensure the lock is RUnlocked when the method returns
+
+	return w.current.WithExposed(fn)
+}
+
+// Close stops watching for new versions and destroys the secret.
+func (w *Watcher) Close() {
+	w.cancel()
+	<-w.done
+
+	w.lock.Lock()         // Lock before destroying the current secret
+	defer w.lock.Unlock() // Ensure the lock is Unlocked when the method returns
+
+	w.current.Destroy()
+}
+
+// run checks for new versions every interval until ctx is cancelled.
+func (w *Watcher) run(ctx context.Context, interval time.Duration) {
+	defer close(w.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := w.refresh(ctx); err != nil && ctx.Err() == nil {
+			w.onError(err)
+		}
+	}
+}
+
+// refresh fetches the secret if its version has changed.
+func (w *Watcher) refresh(ctx context.Context) error {
+	version, err := w.source.version(ctx)
+	if err != nil {
+		return err
+	}
+
+	w.lock.RLock()
+	unchanged := version == w.version
+	w.lock.RUnlock()
+
+	if unchanged {
+		return nil
+	}
+
+	secret, version, err := w.source.fetch(ctx)
+	if err != nil {
+		return err
+	}
+
+	w.lock.Lock()         // Lock before replacing the current secret
+	defer w.lock.Unlock() // Ensure the lock is Unlocked when the method returns
+
+	w.current.Destroy()
+	w.current = secret
+	w.version = version
+
+	return nil
+}
@@ -0,0 +1,241 @@
+// mattressazure sources mattress Secrets from Azure Key Vault.
+//
+// Requests are authenticated with DefaultAzureCredential unless configured otherwise,
+// which covers workload identity and managed identity on AKS, so vault values can be
+// consumed without ever being copied into environment variables. Response bodies are
+// wiped once their value has been sealed into a Secret.
+//
+// Example Usage:
+//
+//	import (
+//	  "github.com/garrettladley/mattress/mattressazure"
+//	)
+//
+//	func main() {
+//	  client, err := mattressazure.NewClient("https://my-vault.vault.azure.net")
+//	  if err != nil {
+//	    // handle error
+//	  }
+//
+//	  password, err := client.GetSecret(ctx, "db-password", "")
+//	  if err != nil {
+//	    // handle error
+//	  }
+//	  defer password.Destroy()
+//	}
+package mattressazure
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/awnumar/memguard"
+	m "github.com/garrettladley/mattress"
+)
+
+// keyVaultScope is the OAuth 2.0 scope required to access Azure Key Vault.
+const keyVaultScope = "https://vault.azure.net/.default"
+
+// apiVersion is the version of the Key Vault REST API used.
+const apiVersion = "7.4"
+
+// Client reads secrets and releases exportable keys from an Azure Key Vault. It
+// implements mattress.Provider.
+type Client struct {
+	vaultURL   string                 // vaultURL is the URL of the vault
+	credential azcore.TokenCredential // credential authenticates requests
+	http       *http.Client           // http performs requests
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithCredential authenticates requests with credential rather than
+// DefaultAzureCredential.
+func WithCredential(credential azcore.TokenCredential) Option {
+	return func(c *Client) {
+		c.credential = credential
+	}
+}
+
+// WithHTTPClient sets the http.Client used to make requests. It defaults to
+// http.DefaultClient.
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *Client) {
+		c.http = client
+	}
+}
+
+// NewClient returns a Client for the vault at vaultURL, such as
+// "https://my-vault.vault.azure.net". Unless configured otherwise, requests are
+// authenticated with DefaultAzureCredential.
+func NewClient(vaultURL string, opts ...Option) (*Client, error) {
+	c := &Client{
+		vaultURL: strings.TrimRight(vaultURL, "/"),
+		http:     http.DefaultClient,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.credential == nil {
+		credential, err := azidentity.NewDefaultAzureCredential(nil)
+		if err != nil {
+			return nil, fmt.Errorf("mattressazure: creating default credential: %w", err)
+		}
+		c.credential = credential
+	}
+
+	return c, nil
+}
+
+// GetSecret reads the secret called name from the vault, sealing its value into a
+// Secret owned by the caller. An empty version reads the latest version.
+func (c *Client) GetSecret(ctx context.Context, name string, version string) (*m.Secret[string], error) {
+	var response struct {
+		Value string `json:"value"`
+	}
+
+	if err := c.do(ctx, http.MethodGet, "secrets/"+name+"/"+version, nil, &response); err != nil {
+		return nil, err
+	}
+
+	secret, err := m.NewSecretString(response.Value)
+	response.Value = ""
+
+	return secret, err
+}
+
+// Fetch implements mattress.Provider. name takes the form "name" or "name/version".
+func (c *Client) Fetch(ctx context.Context, name string) (*m.Secret[string], error) {
+	name, version, _ := strings.Cut(name, "/")
+
+	return c.GetSecret(ctx, name, version)
+}
+
+// ReleaseKey releases the exportable key called name from the vault, sealing the
+// released key into a Secret owned by the caller. The released value is a JWS whose
+// payload holds the key material wrapped for the environment attested to by
+// attestation, as required by the key's release policy. An empty version releases the
+// latest version.
+func (c *Client) ReleaseKey(ctx context.Context, name string, version string, attestation string) (*m.Secret[string], error) {
+	var response struct {
+		Value string `json:"value"`
+	}
+
+	body, err := json.Marshal(map[string]string{"target": attestation})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.do(ctx, http.MethodPost, "keys/"+name+"/"+version+"/release", body, &response); err != nil {
+		return nil, err
+	}
+
+	secret, err := m.NewSecretString(response.Value)
+	response.Value = ""
+
+	return secret, err
+}
+
+// do performs a request against the Key Vault REST API and decodes the JSON response
+// into out. The response body is wiped once decoded.
+func (c *Client) do(ctx context.Context, method string, resource string, body []byte, out any) error {
+	resource = strings.TrimRight(resource, "/")
+
+	u, err := url.Parse(c.vaultURL + "/" + resource)
+	if err != nil {
+		return err
+	}
+
+	u.RawQuery = url.Values{"api-version": {apiVersion}}.Encode()
+
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), reader)
+	if err != nil {
+		return err
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	token, err := c.credential.GetToken(ctx, policy.TokenRequestOptions{Scopes: []string{keyVaultScope}})
+	if err != nil {
+		return fmt.Errorf("mattressazure: acquiring token: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+token.Token)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(resp.Body)
+
+	// WipeBytes securely erases the response body once it has been decoded.
+	defer memguard.WipeBytes(raw)
+
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return fmt.Errorf("mattressazure: %s: %w", resource, m.ErrSecretNotFound)
+	case resp.StatusCode >= http.StatusBadRequest:
+		return decodeError(resp.StatusCode, raw)
+	}
+
+	return json.Unmarshal(raw, out)
+}
+
+// ResponseError is returned when Key Vault responds with an error status.
+type ResponseError struct {
+	StatusCode int    // StatusCode is the HTTP status code of the response
+	Code       string // Code is the error code reported by Key Vault
+	Message    string // Message is the error message reported by Key Vault
+}
+
+func (e *ResponseError) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("mattressazure: unexpected status %d", e.StatusCode)
+	}
+
+	return fmt.Sprintf("mattressazure: unexpected status %d: %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// decodeError decodes the body of a Key Vault error response.
+func decodeError(status int, raw []byte) *ResponseError {
+	var response struct {
+		Error struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+
+	// A body which cannot be decoded still yields the status code.
+	_ = json.Unmarshal(raw, &response)
+
+	return &ResponseError{
+		StatusCode: status,
+		Code:       response.Error.Code,
+		Message:    response.Error.Message,
+	}
+}
@@ -0,0 +1,151 @@
+// mattressconfig decodes sensitive configuration keys loaded by viper or koanf into
+// mattress Secrets.
+//
+// Both libraries decode configuration with mapstructure, whose default handling of
+// encoding.TextUnmarshaler copies the decoded value into the destination field. A
+// Secret must never be copied, so DecodeHook instead secures each value within the
+// destination Secret in place. UnmarshalViper and UnmarshalKoanf install DecodeHook
+// alongside each library's default hooks, and then remove the sensitive keys from the
+// loaded configuration so that their plaintext cannot be read back out of it.
+//
+// Example Usage:
+//
+//	import (
+//	  m "github.com/garrettladley/mattress"
+//	  "github.com/garrettladley/mattress/mattressconfig"
+//	)
+//
+//	type Config struct {
+//	  Addr       string            `mapstructure:"addr"`
+//	  DBPassword *m.Secret[string] `mapstructure:"db_password"`
+//	}
+//
+//	func main() {
+//	  viper.SetConfigFile("config.yaml")
+//	  if err := viper.ReadInConfig(); err != nil {
+//	    // handle error
+//	  }
+//
+//	  var cfg Config
+//	  if err := mattressconfig.UnmarshalViper(viper.GetViper(), &cfg, "db_password"); err != nil {
+//	    // handle error
+//	  }
+//	}
+package mattressconfig
+
+import (
+	"encoding"
+	"reflect"
+
+	"github.com/awnumar/memguard"
+	m "github.com/garrettladley/mattress"
+	"github.com/go-viper/mapstructure/v2"
+	"github.com/knadh/koanf/v2"
+	"github.com/spf13/viper"
+)
+
+// secret is implemented by every *m.Secret.
+type secret interface {
+	encoding.TextUnmarshaler
+	m.Redactable
+	Destroy()
+}
+
+// DecodeHook returns a mapstructure decode hook which decodes strings and byte slices
+// into *m.Secret fields of any type accepted by m.Secret.UnmarshalText. The value is
+// secured within the destination Secret in place, rather than copied into it, and byte
+// slices in the source configuration are wiped once secured. Strings are immutable, so
+// the source configuration retains their plaintext until it is discarded.
+//
+// DecodeHook must run before any hook which handles encoding.TextUnmarshaler, such as
+// mapstructure.TextUnmarshallerHookFunc, as those copy the decoded Secret.
+func DecodeHook() mapstructure.DecodeHookFuncValue {
+	return func(from reflect.Value, to reflect.Value) (any, error) {
+		if !from.IsValid() {
+			return nil, nil
+		}
+
+		data := from.Interface()
+
+		if !to.CanAddr() {
+			return data, nil
+		}
+
+		dst, ok := to.Addr().Interface().(secret)
+		if !ok {
+			return data, nil
+		}
+
+		var text []byte
+
+		switch v := data.(type) {
+		case string:
+			text = []byte(v)
+		case []byte:
+			text = v
+		default:
+			return data, nil
+		}
+
+		// WipeBytes securely erases the text, including byte slices held by the source
+		// configuration, once it has been secured.
+		defer memguard.WipeBytes(text)
+
+		if err := dst.UnmarshalText(text); err != nil {
+			return nil, err
+		}
+
+		// Returning the destination itself leaves mapstructure nothing to copy.
+		return dst, nil
+	}
+}
+
+// UnmarshalViper unmarshals the configuration held by v into out, as v.Unmarshal does
+// with viper's default decode hooks, decoding values into *m.Secret fields with
+// DecodeHook. The keys listed as sensitive are then overridden with m.Placeholder, as
+// viper offers no means of deleting a key, so that their plaintext can no longer be
+// read from v.
+func UnmarshalViper(v *viper.Viper, out any, sensitive ...string) error {
+	hook := mapstructure.ComposeDecodeHookFunc(
+		DecodeHook(),
+		mapstructure.StringToTimeDurationHookFunc(),
+		mapstructure.StringToSliceHookFunc(","),
+	)
+
+	if err := v.Unmarshal(out, viper.DecodeHook(hook)); err != nil {
+		return err
+	}
+
+	for _, key := range sensitive {
+		v.Set(key, m.Placeholder)
+	}
+
+	return nil
+}
+
+// UnmarshalKoanf unmarshals the configuration held by k under path into out, as
+// k.Unmarshal does with koanf's default decode hooks, decoding values into *m.Secret
+// fields with DecodeHook. The keys listed as sensitive, given as full paths, are then
+// deleted from k.
+func UnmarshalKoanf(k *koanf.Koanf, path string, out any, sensitive ...string) error {
+	conf := koanf.UnmarshalConf{
+		DecoderConfig: &mapstructure.DecoderConfig{
+			DecodeHook: mapstructure.ComposeDecodeHookFunc(
+				DecodeHook(),
+				mapstructure.StringToTimeDurationHookFunc(),
+				mapstructure.TextUnmarshallerHookFunc(),
+			),
+			WeaklyTypedInput: true,
+		},
+	}
+
+	if err := k.UnmarshalWithConf(path, out, conf); err != nil {
+		return err
+	}
+
+	for _, key := range sensitive {
+		k.Delete(key)
+	}
+
+	return nil
+}
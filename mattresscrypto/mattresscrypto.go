@@ -0,0 +1,232 @@
+// mattresscrypto provides crypto.Signer implementations whose private keys live in
+// mattress Secrets.
+//
+// The private key is held as PKCS #8 DER within a Secret and is only parsed for the
+// duration of each signing operation, after which the parsed key material is wiped on
+// a best-effort basis. A Signer can be used anywhere a crypto.Signer is accepted,
+// including TLS, JWT, and SSH libraries.
+//
+// Example Usage:
+//
+//	import (
+//	  m "github.com/garrettladley/mattress"
+//	  "github.com/garrettladley/mattress/mattresscrypto"
+//	)
+//
+//	func main() {
+//	  pem, err := m.NewSecretFromFile("/etc/myapp/key.pem", m.WithStrictPermissions())
+//	  if err != nil {
+//	    // handle error
+//	  }
+//
+//	  signer, err := mattresscrypto.NewSignerFromPEM(pem)
+//	  if err != nil {
+//	    // handle error
+//	  }
+//	  defer signer.Destroy()
+//	}
+package mattresscrypto
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/awnumar/memguard"
+	m "github.com/garrettladley/mattress"
+)
+
+// ErrUnsupportedKey is returned when a private key is not an RSA, ECDSA, or Ed25519
+// key.
+var ErrUnsupportedKey = errors.New("mattresscrypto: unsupported private key type")
+
+// Signer is a crypto.Signer whose private key is held in a mattress Secret and only
+// unwrapped for the duration of each call to Sign.
+type Signer struct {
+	key    *m.Secret[[]byte] // key holds the PKCS #8 DER encoded private key
+	public crypto.PublicKey  // public is the public half of key
+}
+
+// NewSignerFromPKCS8 returns a Signer for the PKCS #8 DER encoded private key held by
+// der. RSA, ECDSA, and Ed25519 keys are supported. On success, the Signer takes
+// ownership of der.
+func NewSignerFromPKCS8(der *m.Secret[[]byte]) (*Signer, error) {
+	var public crypto.PublicKey
+
+	err := der.WithExposed(func(b []byte) error {
+		return withPrivateKey(b, func(key crypto.Signer) error {
+			public = key.Public()
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Signer{key: der, public: public}, nil
+}
+
+// NewSignerFromPEM returns a Signer for the PEM encoded private key held by
+// pemBytes, which may be in PKCS #8, PKCS #1 ("RSA PRIVATE KEY"), or SEC 1 ("EC
+// PRIVATE KEY") form. pemBytes is destroyed once the key has been re-sealed as PKCS #8.
+func NewSignerFromPEM(pemBytes *m.Secret[[]byte]) (*Signer, error) {
+	defer pemBytes.Destroy()
+
+	var der *m.Secret[[]byte]
+
+	err := pemBytes.WithExposed(func(b []byte) error {
+		block, _ := pem.Decode(b)
+		if block == nil {
+			return errors.New("mattresscrypto: no PEM block found")
+		}
+
+		// WipeBytes securely erases the decoded block once it has been re-sealed.
+		defer memguard.WipeBytes(block.Bytes)
+
+		key, err := parsePrivateKey(block.Type, block.Bytes)
+		if err != nil {
+			return err
+		}
+		defer wipeKey(key)
+
+		pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			return err
+		}
+
+		// NewSecretBytes wipes the marshaled key once it has been sealed.
+		der, err = m.NewSecretBytes(pkcs8)
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return NewSignerFromPKCS8(der)
+}
+
+// NewSigner returns a Signer for key, which must be an RSA, ECDSA, or Ed25519 private
+// key. The key is marshaled into a Secret and then wiped on a best-effort basis, so the
+// caller must not use key afterwards.
+func NewSigner(key crypto.PrivateKey) (*Signer, error) {
+	defer wipeKey(key)
+
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUnsupportedKey, err)
+	}
+
+	// NewSecretBytes wipes the marshaled key once it has been sealed.
+	der, err := m.NewSecretBytes(pkcs8)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewSignerFromPKCS8(der)
+}
+
+// Public returns the public key corresponding to the private key.
+func (s *Signer) Public() crypto.PublicKey {
+	return s.public
+}
+
+// Sign signs digest with the private key, as described by crypto.Signer. The private
+// key is unwrapped from its Secret for the duration of the call and wiped on a
+// best-effort basis before Sign returns.
+func (s *Signer) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	var signature []byte
+
+	err := s.key.WithExposed(func(b []byte) error {
+		return withPrivateKey(b, func(key crypto.Signer) error {
+			var err error
+			signature, err = key.Sign(rand, digest, opts)
+			return err
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return signature, nil
+}
+
+// Destroy destroys the Secret holding the private key. The Signer cannot be used
+// afterwards.
+func (s *Signer) Destroy() {
+	s.key.Destroy()
+}
+
+// withPrivateKey parses the PKCS #8 DER encoded private key der and passes it to fn,
+// wiping the parsed key once fn returns.
+func withPrivateKey(der []byte, fn func(key crypto.Signer) error) error {
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return err
+	}
+	defer wipeKey(key)
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return ErrUnsupportedKey
+	}
+
+	switch signer.(type) {
+	case *rsa.PrivateKey, *ecdsa.PrivateKey, ed25519.PrivateKey:
+	default:
+		return ErrUnsupportedKey
+	}
+
+	return fn(signer)
+}
+
+// parsePrivateKey parses a DER encoded private key according to its PEM block type.
+func parsePrivateKey(blockType string, der []byte) (crypto.PrivateKey, error) {
+	switch blockType {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(der)
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(der)
+	default:
+		return x509.ParsePKCS8PrivateKey(der)
+	}
+}
+
+// wipeKey makes a best-effort attempt to overwrite the private material of key. Some
+// copies, such as values precomputed internally by the standard library, cannot be
+// reached and are left to the garbage collector.
+func wipeKey(key crypto.PrivateKey) {
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		wipeInt(k.D)
+		for _, prime := range k.Primes {
+			wipeInt(prime)
+		}
+		wipeInt(k.Precomputed.Dp)
+		wipeInt(k.Precomputed.Dq)
+		wipeInt(k.Precomputed.Qinv)
+	case *ecdsa.PrivateKey:
+		wipeInt(k.D)
+	case ed25519.PrivateKey:
+		memguard.WipeBytes(k)
+	case *ed25519.PrivateKey:
+		memguard.WipeBytes(*k)
+	}
+}
+
+// wipeInt overwrites the words backing n.
+func wipeInt(n *big.Int) {
+	if n == nil {
+		return
+	}
+
+	clear(n.Bits())
+	n.SetInt64(0)
+}
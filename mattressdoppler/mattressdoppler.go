@@ -0,0 +1,169 @@
+// mattressdoppler sources mattress Secrets from Doppler.
+//
+// Responses are read into memory that is wiped once decoded, and each value is sealed
+// into a Secret as soon as it has been decoded.
+//
+// Example Usage:
+//
+//	import (
+//	  m "github.com/garrettladley/mattress"
+//	  "github.com/garrettladley/mattress/mattressdoppler"
+//	)
+//
+//	func main() {
+//	  token, err := m.NewSecretFromEnv("DOPPLER_TOKEN")
+//	  if err != nil {
+//	    // handle error
+//	  }
+//
+//	  client := mattressdoppler.NewClient(token)
+//	  m.Register("doppler", client)
+//
+//	  password, err := m.Fetch(ctx, "doppler", "DATABASE_PASSWORD")
+//	  if err != nil {
+//	    // handle error
+//	  }
+//	  defer password.Destroy()
+//	}
+package mattressdoppler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/awnumar/memguard"
+	m "github.com/garrettladley/mattress"
+)
+
+// DefaultAddr is the address of the Doppler API.
+const DefaultAddr = "https://api.doppler.com"
+
+// Client fetches secrets from a Doppler config. It implements mattress.Provider.
+type Client struct {
+	addr    string            // addr is the address of the Doppler API
+	token   *m.Secret[string] // token authenticates requests to Doppler
+	project string            // project is the project secrets are fetched from, if any
+	config  string            // config is the config secrets are fetched from, if any
+	http    *http.Client      // http performs requests to Doppler
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithProject sets the project and config secrets are fetched from. They are required
+// unless the token is a service token, which is scoped to a single config.
+func WithProject(project, config string) Option {
+	return func(c *Client) {
+		c.project = project
+		c.config = config
+	}
+}
+
+// WithAddr sets the address of the Doppler API. It defaults to DefaultAddr.
+func WithAddr(addr string) Option {
+	return func(c *Client) {
+		c.addr = strings.TrimRight(addr, "/")
+	}
+}
+
+// WithHTTPClient sets the http.Client used to make requests to Doppler. It defaults to
+// http.DefaultClient.
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *Client) {
+		c.http = client
+	}
+}
+
+// NewClient returns a Client authenticating with token, which may be a service token
+// or a personal or service account token. The token remains owned by the caller and
+// must outlive the Client.
+func NewClient(token *m.Secret[string], opts ...Option) *Client {
+	c := &Client{
+		addr:  DefaultAddr,
+		token: token,
+		http:  http.DefaultClient,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// Fetch implements mattress.Provider, fetching the secret called name from the config
+// of the Client. References to other secrets within its value are resolved by Doppler.
+func (c *Client) Fetch(ctx context.Context, name string) (*m.Secret[string], error) {
+	query := url.Values{"name": {name}}
+	if c.project != "" {
+		query.Set("project", c.project)
+		query.Set("config", c.config)
+	}
+
+	u, err := url.JoinPath(c.addr, "v3/configs/config/secret")
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Accept", "application/json")
+
+	if err := c.token.WithExposed(func(token string) error {
+		req.Header.Set("Authorization", "Bearer "+token)
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("mattressdoppler: exposing token: %w", err)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(resp.Body)
+
+	// WipeBytes securely erases the response body once it has been decoded.
+	defer memguard.WipeBytes(raw)
+
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, fmt.Errorf("mattressdoppler: %q: %w", name, m.ErrSecretNotFound)
+	case resp.StatusCode >= http.StatusBadRequest:
+		var response struct {
+			Messages []string `json:"messages"`
+		}
+		_ = json.Unmarshal(raw, &response)
+
+		return nil, fmt.Errorf("mattressdoppler: unexpected status %d: %s", resp.StatusCode, strings.Join(response.Messages, "; "))
+	}
+
+	var response struct {
+		Value struct {
+			Computed *string `json:"computed"`
+		} `json:"value"`
+	}
+
+	if err := json.Unmarshal(raw, &response); err != nil {
+		return nil, fmt.Errorf("mattressdoppler: decoding %q: %w", name, err)
+	}
+
+	if response.Value.Computed == nil {
+		return nil, fmt.Errorf("mattressdoppler: %q: %w", name, m.ErrSecretNotFound)
+	}
+
+	return m.NewSecretString(*response.Value.Computed)
+}
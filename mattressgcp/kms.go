@@ -0,0 +1,88 @@
+package mattressgcp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/awnumar/memguard"
+	m "github.com/garrettladley/mattress"
+)
+
+// defaultKMSEndpoint is the base URL of the Cloud KMS REST API.
+const defaultKMSEndpoint = "https://cloudkms.googleapis.com/v1"
+
+// KMSWrapper is a mattress.Wrapper which wraps data keys with a symmetric Cloud KMS
+// key, for use with Secret.Export and mattress.ImportSecret.
+type KMSWrapper struct {
+	client  *Client // client performs Cloud KMS requests
+	keyName string  // keyName identifies the key encryption key
+}
+
+// NewKMSWrapper returns a KMSWrapper which wraps data keys with the Cloud KMS key called
+// keyName, which takes the form "projects/*/locations/*/keyRings/*/cryptoKeys/*". Unless
+// configured otherwise, requests are authenticated with Application Default
+// Credentials.
+func NewKMSWrapper(ctx context.Context, keyName string, opts ...Option) (*KMSWrapper, error) {
+	client, err := newClient(ctx, defaultKMSEndpoint, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &KMSWrapper{client: client, keyName: keyName}, nil
+}
+
+// WrapKey implements mattress.Wrapper, encrypting key with Cloud KMS.
+func (w *KMSWrapper) WrapKey(ctx context.Context, key *m.Secret[[]byte]) ([]byte, error) {
+	var response struct {
+		Ciphertext []byte `json:"ciphertext"`
+	}
+
+	err := key.WithExposed(func(plaintext []byte) error {
+		body, err := json.Marshal(struct {
+			Plaintext []byte `json:"plaintext"`
+		}{plaintext})
+
+		// WipeBytes securely erases the encoded request body once it has been sent.
+		defer memguard.WipeBytes(body)
+
+		if err != nil {
+			return err
+		}
+
+		return w.client.do(ctx, http.MethodPost, w.keyName+":encrypt", body, &response)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return response.Ciphertext, nil
+}
+
+// UnwrapKey implements mattress.Wrapper, decrypting wrapped with Cloud KMS and sealing
+// the data key into a Secret owned by the caller.
+func (w *KMSWrapper) UnwrapKey(ctx context.Context, wrapped []byte) (*m.Secret[[]byte], error) {
+	body, err := json.Marshal(struct {
+		Ciphertext []byte `json:"ciphertext"`
+	}{wrapped})
+	if err != nil {
+		return nil, err
+	}
+
+	var response struct {
+		Plaintext       []byte `json:"plaintext"`
+		PlaintextCRC32C string `json:"plaintextCrc32c"`
+	}
+
+	if err := w.client.do(ctx, http.MethodPost, w.keyName+":decrypt", body, &response); err != nil {
+		return nil, err
+	}
+
+	if err := (payload{Data: response.Plaintext, DataCRC32C: response.PlaintextCRC32C}).verify(); err != nil {
+		memguard.WipeBytes(response.Plaintext)
+		return nil, err
+	}
+
+	// NewSecretBytes wipes the decrypted data key once it has been sealed.
+	return m.NewSecretBytes(response.Plaintext)
+}
@@ -0,0 +1,290 @@
+// mattressgcp sources mattress Secrets from Google Cloud Secret Manager, and wraps data
+// keys with Cloud KMS for envelope encryption.
+//
+// Secret versions are accessed through the Secret Manager REST API, authenticating with
+// Application Default Credentials, which covers Workload Identity on GKE as well as
+// Workload Identity Federation. Response bodies and decoded payloads are wiped once the
+// payload has been sealed into a Secret.
+//
+// Example Usage:
+//
+//	import (
+//	  "github.com/garrettladley/mattress/mattressgcp"
+//	)
+//
+//	func main() {
+//	  client, err := mattressgcp.NewClient(ctx)
+//	  if err != nil {
+//	    // handle error
+//	  }
+//
+//	  key, err := client.Access(ctx, "projects/my-project/secrets/signing-key/versions/latest")
+//	  if err != nil {
+//	    // handle error
+//	  }
+//	  defer key.Destroy()
+//	}
+package mattressgcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/awnumar/memguard"
+	m "github.com/garrettladley/mattress"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+// cloudPlatformScope is the OAuth 2.0 scope required to access Secret Manager.
+const cloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+
+// defaultEndpoint is the base URL of the Secret Manager REST API.
+const defaultEndpoint = "https://secretmanager.googleapis.com/v1"
+
+// Client accesses secret versions in Google Cloud Secret Manager. It implements
+// mattress.Provider.
+type Client struct {
+	http        *http.Client       // http performs authenticated requests
+	tokenSource oauth2.TokenSource // tokenSource authenticates requests, if http is unset
+	endpoint    string             // endpoint is the base URL of the REST API
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithHTTPClient sets the http.Client used to make requests. The client must already
+// authenticate its requests, for example one returned by oauth2.NewClient.
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *Client) {
+		c.http = client
+	}
+}
+
+// WithTokenSource authenticates requests with ts rather than Application Default
+// Credentials.
+func WithTokenSource(ts oauth2.TokenSource) Option {
+	return func(c *Client) {
+		c.tokenSource = ts
+	}
+}
+
+// WithEndpoint sets the base URL of the REST API, for example to use a regional
+// endpoint.
+func WithEndpoint(endpoint string) Option {
+	return func(c *Client) {
+		c.endpoint = strings.TrimRight(endpoint, "/")
+	}
+}
+
+// NewClient returns a Client. Unless configured otherwise, requests are authenticated
+// with Application Default Credentials.
+func NewClient(ctx context.Context, opts ...Option) (*Client, error) {
+	return newClient(ctx, defaultEndpoint, opts)
+}
+
+// newClient returns a Client for the REST API at endpoint with opts applied.
+func newClient(ctx context.Context, endpoint string, opts []Option) (*Client, error) {
+	c := &Client{endpoint: endpoint}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	switch {
+	case c.http != nil:
+	case c.tokenSource != nil:
+		c.http = oauth2.NewClient(ctx, c.tokenSource)
+	default:
+		client, err := google.DefaultClient(ctx, cloudPlatformScope)
+		if err != nil {
+			return nil, fmt.Errorf("mattressgcp: finding default credentials: %w", err)
+		}
+		c.http = client
+	}
+
+	return c, nil
+}
+
+// Access accesses the secret version called name, which takes the form
+// "projects/*/secrets/*/versions/*", and seals its payload into a Secret owned by the
+// caller. The version may be an alias such as "latest".
+func (c *Client) Access(ctx context.Context, name string) (*m.Secret[[]byte], error) {
+	secret, _, err := c.access(ctx, name)
+
+	return secret, err
+}
+
+// Fetch implements mattress.Provider, accessing the secret version called name as
+// described by Access.
+func (c *Client) Fetch(ctx context.Context, name string) (*m.Secret[string], error) {
+	var response accessResponse
+
+	if err := c.get(ctx, name+":access", &response); err != nil {
+		return nil, err
+	}
+
+	// WipeBytes securely erases the decoded payload once it has been sealed.
+	defer memguard.WipeBytes(response.Payload.Data)
+
+	if err := response.Payload.verify(); err != nil {
+		return nil, err
+	}
+
+	return m.NewSecretString(string(response.Payload.Data))
+}
+
+// accessResponse is the response to accessing a secret version.
+type accessResponse struct {
+	Name    string  `json:"name"`
+	Payload payload `json:"payload"`
+}
+
+// payload is the payload of a secret version.
+type payload struct {
+	Data       []byte `json:"data"`
+	DataCRC32C string `json:"dataCrc32c"`
+}
+
+// verify checks the payload against its CRC32C checksum, if one was provided.
+func (p payload) verify() error {
+	if p.DataCRC32C == "" {
+		return nil
+	}
+
+	want, err := strconv.ParseUint(p.DataCRC32C, 10, 32)
+	if err != nil {
+		return fmt.Errorf("mattressgcp: parsing checksum: %w", err)
+	}
+
+	if crc32.Checksum(p.Data, crc32.MakeTable(crc32.Castagnoli)) != uint32(want) {
+		return fmt.Errorf("mattressgcp: payload failed checksum verification")
+	}
+
+	return nil
+}
+
+// access accesses and seals the secret version called name, returning it along with
+// the resolved name of the version.
+func (c *Client) access(ctx context.Context, name string) (*m.Secret[[]byte], string, error) {
+	var response accessResponse
+
+	if err := c.get(ctx, name+":access", &response); err != nil {
+		return nil, "", err
+	}
+
+	if err := response.Payload.verify(); err != nil {
+		memguard.WipeBytes(response.Payload.Data)
+		return nil, "", err
+	}
+
+	// NewSecretBytes wipes the decoded payload once it has been sealed.
+	secret, err := m.NewSecretBytes(response.Payload.Data)
+
+	return secret, response.Name, err
+}
+
+// resolve returns the resolved name of the secret version called name, without
+// accessing its payload.
+func (c *Client) resolve(ctx context.Context, name string) (string, error) {
+	var response struct {
+		Name string `json:"name"`
+	}
+
+	if err := c.get(ctx, name, &response); err != nil {
+		return "", err
+	}
+
+	return response.Name, nil
+}
+
+// get performs a GET request against the REST API and decodes the JSON response into
+// out. The response body is wiped once decoded.
+func (c *Client) get(ctx context.Context, resource string, out any) error {
+	return c.do(ctx, http.MethodGet, resource, nil, out)
+}
+
+// do performs a request against the REST API, sending body as JSON if it is non-nil,
+// and decodes the JSON response into out. The response body is wiped once decoded.
+func (c *Client) do(ctx context.Context, method string, resource string, body []byte, out any) error {
+	u, err := url.JoinPath(c.endpoint, resource)
+	if err != nil {
+		return err
+	}
+
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, reader)
+	if err != nil {
+		return err
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(resp.Body)
+
+	// WipeBytes securely erases the response body once it has been decoded.
+	defer memguard.WipeBytes(raw)
+
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return fmt.Errorf("mattressgcp: %s: %w", resource, m.ErrSecretNotFound)
+	case resp.StatusCode >= http.StatusBadRequest:
+		return &ResponseError{StatusCode: resp.StatusCode, Message: decodeMessage(raw)}
+	}
+
+	return json.Unmarshal(raw, out)
+}
+
+// ResponseError is returned when Secret Manager or Cloud KMS responds with an error
+// status.
+type ResponseError struct {
+	StatusCode int    // StatusCode is the HTTP status code of the response
+	Message    string // Message is the error message reported by the API
+}
+
+func (e *ResponseError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("mattressgcp: unexpected status %d", e.StatusCode)
+	}
+
+	return fmt.Sprintf("mattressgcp: unexpected status %d: %s", e.StatusCode, e.Message)
+}
+
+// decodeMessage extracts the error message from the body of an error response.
+func decodeMessage(raw []byte) string {
+	var response struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+
+	if err := json.Unmarshal(raw, &response); err != nil {
+		return ""
+	}
+
+	return response.Error.Message
+}
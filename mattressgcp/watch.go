@@ -0,0 +1,148 @@
+package mattressgcp
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	m "github.com/garrettladley/mattress"
+)
+
+// Watcher keeps a secret accessed from Secret Manager up to date in the background,
+// atomically swapping in each new version as it is published.
+type Watcher struct {
+	client  *Client           // client accesses the secret
+	name    string            // name is the, typically aliased, secret version name
+	current *m.Secret[[]byte] // current is the most recently accessed version
+	version string            // version is the resolved name of current
+	onError func(error)       // onError is notified of background failures
+	lock    sync.RWMutex      // synchronize access to current and version
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+// WatchOption configures a Watcher.
+type WatchOption func(*Watcher)
+
+// OnError registers fn to be called with any error encountered while checking for new
+// versions in the background. Failed checks are retried at the next interval.
+func OnError(fn func(error)) WatchOption {
+	return func(w *Watcher) {
+		w.onError = fn
+	}
+}
+
+// Watch accesses the secret version called name, typically an alias such as
+// "projects/*/secrets/*/versions/latest", and checks every interval whether the alias
+// resolves to a new version, accessing it if so. Checks only read version metadata, so
+// the payload is only transferred when it has changed.
+func (c *Client) Watch(ctx context.Context, name string, interval time.Duration, opts ...WatchOption) (*Watcher, error) {
+	secret, version, err := c.access(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+
+	w := &Watcher{
+		client:  c,
+		name:    name,
+		current: secret,
+		version: version,
+		onError: func(error) {},
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(w)
+	}
+
+	go w.run(ctx, interval)
+
+	return w, nil
+}
+
+// Current returns the most recently accessed version. The Secret remains owned by the
+// Watcher and is destroyed once it is replaced, so callers should call Current each
+// time the secret is needed rather than retaining it.
+func (w *Watcher) Current() *m.Secret[[]byte] {
+	w.lock.RLock()         // RLock before reading the current secret
+	defer w.lock.RUnlock() // Ensure the lock is RUnlocked when the method returns
+
+	return w.current
+}
+
+// Version returns the resolved name of the most recently accessed version.
+func (w *Watcher) Version() string {
+	w.lock.RLock()         // RLock before reading the version
+	defer w.lock.RUnlock() // Ensure the lock is RUnlocked when the method returns
+
+	return w.version
+}
+
+// WithExposed exposes the most recently accessed version to fn, guaranteeing that it
+// is not replaced while fn runs.
+func (w *Watcher) WithExposed(fn func([]byte) error) error {
+	w.lock.RLock()         // RLock before reading the current secret
+	defer w.lock.RUnlock() // Ensure the lock is RUnlocked when the method returns
+
+	return w.current.WithExposed(fn)
+}
+
+// Close stops watching for new versions and destroys the secret.
+func (w *Watcher) Close() {
+	w.cancel()
+	<-w.done
+
+	w.lock.Lock()         // Lock before destroying the current secret
+	defer w.lock.Unlock() // Ensure the lock is Unlocked when the method returns
+
+	w.current.Destroy()
+}
+
+// run checks for new versions every interval until ctx is cancelled.
+func (w *Watcher) run(ctx context.Context, interval time.Duration) {
+	defer close(w.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := w.refresh(ctx); err != nil && ctx.Err() == nil {
+			w.onError(err)
+		}
+	}
+}
+
+// refresh accesses the secret if its alias resolves to a new version.
+func (w *Watcher) refresh(ctx context.Context) error {
+	version, err := w.client.resolve(ctx, w.name)
+	if err != nil {
+		return err
+	}
+
+	if version == w.Version() {
+		return nil
+	}
+
+	secret, version, err := w.client.access(ctx, w.name)
+	if err != nil {
+		return err
+	}
+
+	w.lock.Lock()         // Lock before replacing the current secret
+	defer w.lock.Unlock() // Ensure the lock is Unlocked when the method returns
+
+	w.current.Destroy()
+	w.current = secret
+	w.version = version
+
+	return nil
+}
@@ -0,0 +1,129 @@
+// mattressgrpc provides gRPC credentials backed by mattress Secrets.
+//
+// PerRPCCredentials reads its token afresh for every call, so the token is never
+// cached in a plain field of a long-lived client, and a token held by a Rotator is
+// picked up as soon as it is rotated.
+//
+// Example Usage:
+//
+//	import (
+//	  m "github.com/garrettladley/mattress"
+//	  "github.com/garrettladley/mattress/mattressgrpc"
+//	)
+//
+//	func main() {
+//	  token, err := m.NewSecretFromEnv("API_TOKEN")
+//	  if err != nil {
+//	    // handle error
+//	  }
+//
+//	  conn, err := grpc.NewClient("api.example.com:443",
+//	    grpc.WithTransportCredentials(credentials.NewTLS(nil)),
+//	    grpc.WithPerRPCCredentials(mattressgrpc.NewPerRPCCredentials(token)),
+//	  )
+//	}
+package mattressgrpc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"google.golang.org/grpc/credentials"
+)
+
+// Token is a source of the token presented on each call. It is implemented by
+// *m.Secret[string], *m.Rotator[string], and *mattressvault.LeasedSecret, among others.
+type Token interface {
+	// WithExposed calls fn with the current token, which must not be retained.
+	WithExposed(fn func(token string) error) error
+}
+
+// PerRPCCredentials is a credentials.PerRPCCredentials which attaches a token held in
+// a Secret to the metadata of every call.
+type PerRPCCredentials struct {
+	token    Token  // token is read afresh for every call
+	header   string // header is the metadata key the token is sent under
+	scheme   string // scheme prefixes the token, if not empty
+	insecure bool   // insecure permits the token to be sent without transport security
+}
+
+// Option configures a PerRPCCredentials.
+type Option func(*PerRPCCredentials)
+
+// WithHeader sends the token under the metadata key name rather than "authorization".
+func WithHeader(name string) Option {
+	return func(c *PerRPCCredentials) {
+		c.header = strings.ToLower(name)
+	}
+}
+
+// WithScheme prefixes the token with scheme rather than "Bearer". An empty scheme
+// sends the token as-is, as many APIs expect of custom headers.
+func WithScheme(scheme string) Option {
+	return func(c *PerRPCCredentials) {
+		c.scheme = scheme
+	}
+}
+
+// WithInsecureTransport permits the token to be sent over connections without
+// transport security, such as to a local sidecar. It should not otherwise be used.
+func WithInsecureTransport() Option {
+	return func(c *PerRPCCredentials) {
+		c.insecure = true
+	}
+}
+
+// NewPerRPCCredentials returns a PerRPCCredentials which sends the current token of
+// token as a bearer token in the authorization metadata of every call. The token
+// remains owned by the caller.
+func NewPerRPCCredentials(token Token, opts ...Option) *PerRPCCredentials {
+	c := &PerRPCCredentials{
+		token:  token,
+		header: "authorization",
+		scheme: "Bearer",
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// GetRequestMetadata exposes the current token and returns the metadata carrying it.
+// The metadata necessarily holds the token in an ordinary string, which lives only as
+// long as the call.
+func (c *PerRPCCredentials) GetRequestMetadata(ctx context.Context, _ ...string) (map[string]string, error) {
+	if !c.insecure {
+		ri, _ := credentials.RequestInfoFromContext(ctx)
+		if err := credentials.CheckSecurityLevel(ri.AuthInfo, credentials.PrivacyAndIntegrity); err != nil {
+			return nil, fmt.Errorf("mattressgrpc: refusing to send token: %w", err)
+		}
+	}
+
+	var value string
+
+	err := c.token.WithExposed(func(token string) error {
+		if c.scheme == "" {
+			// The exposed string is wiped when fn returns, so the metadata is given its
+			// own copy.
+			value = strings.Clone(token)
+		} else {
+			value = c.scheme + " " + token
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]string{c.header: value}, nil
+}
+
+// RequireTransportSecurity reports whether the credentials require transport security,
+// which they do unless WithInsecureTransport was given.
+func (c *PerRPCCredentials) RequireTransportSecurity() bool {
+	return !c.insecure
+}
@@ -0,0 +1,100 @@
+package mattresshttp
+
+import (
+	"encoding/base64"
+	"net/http"
+	"strings"
+
+	"github.com/awnumar/memguard"
+	m "github.com/garrettladley/mattress"
+)
+
+// BearerToken returns the token presented by r in its Authorization header under the
+// Bearer scheme, reporting whether there was one.
+func BearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+
+	return token, true
+}
+
+// VerifyBearer reports whether r presents token as its bearer token. The comparison
+// is performed in constant time, as described by m.Secret.EqualString. VerifyBearer
+// returns false if r presents no bearer token or token has been destroyed.
+func VerifyBearer(r *http.Request, token *m.Secret[string]) bool {
+	presented, ok := BearerToken(r)
+	if !ok {
+		return false
+	}
+
+	return token.EqualString(presented)
+}
+
+// RequireBearer returns middleware which answers any request that does not present
+// token as its bearer token, as checked by VerifyBearer, with 401 Unauthorized.
+func RequireBearer(token *m.Secret[string]) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !VerifyBearer(r, token) {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// SetBearer sets the Authorization header of req to present token under the Bearer
+// scheme. The header value is built directly from the exposed Secret, so the token
+// never appears in a variable owned by the caller, and the intermediate copy is wiped
+// once the header has been set. The header itself necessarily holds an ordinary
+// string, so req should be discarded once it has been sent. SetBearer returns an error
+// if token cannot be exposed, in which case req is left unmodified.
+func SetBearer(req *http.Request, token *m.Secret[string]) error {
+	return token.WithPlaintext(func(plaintext []byte) error {
+		value := append([]byte("Bearer "), plaintext...)
+
+		// WipeBytes securely erases the intermediate header value once it has been set.
+		defer memguard.WipeBytes(value)
+
+		req.Header.Set("Authorization", string(value))
+
+		return nil
+	})
+}
+
+// SetBasicAuth sets the Authorization header of req to present user and pass under the
+// Basic scheme, as http.Request.SetBasicAuth does, without the password appearing in a
+// variable owned by the caller. The intermediate copies of the credentials are wiped
+// once the header has been set; as with SetBearer, req should be discarded once it has
+// been sent. SetBasicAuth returns an error if pass cannot be exposed, in which case req
+// is left unmodified.
+func SetBasicAuth(req *http.Request, user string, pass *m.Secret[string]) error {
+	return pass.WithPlaintext(func(plaintext []byte) error {
+		credentials := append([]byte(user+":"), plaintext...)
+
+		// WipeBytes securely erases the unencoded credentials once they are encoded.
+		defer memguard.WipeBytes(credentials)
+
+		value := base64.StdEncoding.AppendEncode([]byte("Basic "), credentials)
+
+		// WipeBytes securely erases the intermediate header value once it has been set.
+		defer memguard.WipeBytes(value)
+
+		req.Header.Set("Authorization", string(value))
+
+		return nil
+	})
+}
+
+// setHeader sets the header of req called name to the value held by secret.
+func setHeader(req *http.Request, name string, secret *m.Secret[string]) error {
+	return secret.WithPlaintext(func(plaintext []byte) error {
+		req.Header.Set(name, string(plaintext))
+		return nil
+	})
+}
@@ -0,0 +1,134 @@
+// mattresshttp provides helpers for keeping mattress Secrets out of net/http logs, for
+// authenticating requests against Secrets, and for sending credentials held in
+// Secrets.
+//
+// Middleware masks sensitive headers, such as Authorization and Cookie, in the access
+// logs it writes and in the reports of panics it recovers, while RequireBearer and
+// VerifyBearer compare bearer tokens against a Secret in constant time. On the client
+// side, Transport sets credentials held in Secrets on outbound requests as they are
+// sent.
+//
+// Example Usage:
+//
+//	import (
+//	  m "github.com/garrettladley/mattress"
+//	  "github.com/garrettladley/mattress/mattresshttp"
+//	)
+//
+//	func main() {
+//	  token, err := m.NewSecretFromEnv("API_TOKEN")
+//	  if err != nil {
+//	    // handle error
+//	  }
+//
+//	  handler := mattresshttp.RequireBearer(token)(mux)
+//	  handler = mattresshttp.Middleware(mattresshttp.WithSensitiveHeaders("X-Api-Key"))(handler)
+//
+//	  http.ListenAndServe(":8080", handler)
+//	}
+package mattresshttp
+
+import (
+	"log/slog"
+	"net/http"
+
+	m "github.com/garrettladley/mattress"
+)
+
+// sensitiveHeaders are the headers masked by default, in canonical form.
+var sensitiveHeaders = []string{
+	"Authorization",
+	"Proxy-Authorization",
+	"Cookie",
+	"Set-Cookie",
+}
+
+// Option configures Middleware and RedactHeaders.
+type Option func(*config)
+
+// config holds the settings accumulated from the Options passed to Middleware.
+type config struct {
+	sensitive []string     // sensitive are the canonical names of the headers to mask
+	strip     bool         // strip removes sensitive headers rather than masking them
+	logger    *slog.Logger // logger receives access logs and recovered panics
+}
+
+// newConfig applies opts on top of the default configuration.
+func newConfig(opts []Option) config {
+	cfg := config{sensitive: sensitiveHeaders}
+
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	if cfg.logger == nil {
+		cfg.logger = slog.Default()
+	}
+
+	return cfg
+}
+
+// WithSensitiveHeaders masks the headers called names in addition to Authorization,
+// Proxy-Authorization, Cookie, and Set-Cookie.
+func WithSensitiveHeaders(names ...string) Option {
+	return func(c *config) {
+		sensitive := append([]string(nil), c.sensitive...)
+		for _, name := range names {
+			sensitive = append(sensitive, http.CanonicalHeaderKey(name))
+		}
+
+		c.sensitive = sensitive
+	}
+}
+
+// WithStrippedHeaders removes sensitive headers entirely rather than replacing their
+// values with m.Placeholder, so that not even their presence is recorded.
+func WithStrippedHeaders() Option {
+	return func(c *config) {
+		c.strip = true
+	}
+}
+
+// WithLogger sets the logger Middleware writes access logs and recovered panics to.
+// It defaults to slog.Default.
+func WithLogger(logger *slog.Logger) Option {
+	return func(c *config) {
+		c.logger = logger
+	}
+}
+
+// RedactHeaders returns a copy of h in which the values of sensitive headers are
+// replaced with m.Placeholder, or removed under WithStrippedHeaders, suitable for
+// logging. h itself is not modified.
+func RedactHeaders(h http.Header, opts ...Option) http.Header {
+	return newConfig(opts).redact(h)
+}
+
+// redact returns a copy of h with its sensitive headers masked or stripped.
+func (c config) redact(h http.Header) http.Header {
+	redacted := h.Clone()
+	if redacted == nil {
+		return nil
+	}
+
+	for _, name := range c.sensitive {
+		values, ok := redacted[name]
+		if !ok {
+			continue
+		}
+
+		if c.strip {
+			delete(redacted, name)
+			continue
+		}
+
+		masked := make([]string, len(values))
+		for i := range masked {
+			masked[i] = m.Placeholder
+		}
+
+		redacted[name] = masked
+	}
+
+	return redacted
+}
@@ -0,0 +1,118 @@
+package mattresshttp
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"time"
+
+	m "github.com/garrettladley/mattress"
+)
+
+// Middleware returns middleware which logs each request it serves, and recovers any
+// panic raised while serving it, with sensitive headers masked as described by
+// RedactHeaders. The values of the sensitive headers of the request are also masked
+// out of the panic value and the stack trace before they are logged, as handlers
+// frequently panic with messages built from the credentials they were given.
+//
+// A recovered panic is answered with 500 Internal Server Error, unless a response has
+// already been started. http.ErrAbortHandler is re-raised, as net/http expects.
+func Middleware(opts ...Option) func(http.Handler) http.Handler {
+	cfg := newConfig(opts)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rec := &recorder{ResponseWriter: w, status: http.StatusOK}
+			start := time.Now()
+
+			defer func() {
+				if v := recover(); v != nil {
+					if err, ok := v.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+						panic(v)
+					}
+
+					cfg.logger.ErrorContext(r.Context(), "mattresshttp: recovered panic",
+						slog.String("method", r.Method),
+						slog.String("path", r.URL.Path),
+						slog.Any("headers", cfg.redact(r.Header)),
+						slog.String("panic", cfg.scrub(r, fmt.Sprint(v))),
+						slog.String("stack", cfg.scrub(r, string(debug.Stack()))),
+					)
+
+					if !rec.wrote {
+						http.Error(rec, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+					}
+				}
+
+				cfg.logger.InfoContext(r.Context(), "mattresshttp: served request",
+					slog.String("method", r.Method),
+					slog.String("path", r.URL.Path),
+					slog.Int("status", rec.status),
+					slog.Duration("duration", time.Since(start)),
+					slog.Any("headers", cfg.redact(r.Header)),
+				)
+			}()
+
+			next.ServeHTTP(rec, r)
+		})
+	}
+}
+
+// scrub replaces the value of every sensitive header of r within text with
+// m.Placeholder. Bearer and Basic credentials are also masked on their own, without
+// their scheme, as are the values of individual cookies.
+func (c config) scrub(r *http.Request, text string) string {
+	if slices.Contains(c.sensitive, "Cookie") {
+		for _, cookie := range r.Cookies() {
+			if cookie.Value != "" {
+				text = strings.ReplaceAll(text, cookie.Value, m.Placeholder)
+			}
+		}
+	}
+
+	for _, name := range c.sensitive {
+		for _, value := range r.Header.Values(name) {
+			if value == "" {
+				continue
+			}
+
+			text = strings.ReplaceAll(text, value, m.Placeholder)
+
+			if _, credentials, ok := strings.Cut(value, " "); ok && credentials != "" {
+				text = strings.ReplaceAll(text, credentials, m.Placeholder)
+			}
+		}
+	}
+
+	return text
+}
+
+// recorder records the status of the response written by a handler.
+type recorder struct {
+	http.ResponseWriter
+	status int  // status is the status code written, or 200 if none was
+	wrote  bool // wrote reports whether the response has been started
+}
+
+func (r *recorder) WriteHeader(status int) {
+	if !r.wrote {
+		r.status = status
+		r.wrote = true
+	}
+
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	r.wrote = true
+	return r.ResponseWriter.Write(b)
+}
+
+// Unwrap allows http.ResponseController to reach the underlying ResponseWriter.
+func (r *recorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,85 @@
+package mattresshttp
+
+import (
+	"net/http"
+
+	m "github.com/garrettladley/mattress"
+)
+
+// Transport is an http.RoundTripper which sets credentials held in Secrets on each
+// outbound request as it is sent, so that tokens never live in plain fields of a
+// long-lived client. The request passed to RoundTrip is never modified.
+type Transport struct {
+	base    http.RoundTripper               // base sends the authenticated requests
+	setters []func(req *http.Request) error // setters set the credentials on each request
+}
+
+// TransportOption configures a Transport.
+type TransportOption func(*Transport)
+
+// WithBearer presents token in the Authorization header under the Bearer scheme.
+func WithBearer(token *m.Secret[string]) TransportOption {
+	return func(t *Transport) {
+		t.setters = append(t.setters, func(req *http.Request) error {
+			return SetBearer(req, token)
+		})
+	}
+}
+
+// WithBasicAuth presents user and pass in the Authorization header under the Basic
+// scheme.
+func WithBasicAuth(user string, pass *m.Secret[string]) TransportOption {
+	return func(t *Transport) {
+		t.setters = append(t.setters, func(req *http.Request) error {
+			return SetBasicAuth(req, user, pass)
+		})
+	}
+}
+
+// WithHeader sets the header called name to the value held by secret, for APIs which
+// expect credentials in a custom header such as X-Api-Key.
+func WithHeader(name string, secret *m.Secret[string]) TransportOption {
+	return func(t *Transport) {
+		t.setters = append(t.setters, func(req *http.Request) error {
+			return setHeader(req, name, secret)
+		})
+	}
+}
+
+// NewTransport returns a Transport which sets the credentials configured by opts on
+// each request before sending it with base, or http.DefaultTransport if base is nil.
+// The Secrets remain owned by the caller and are exposed afresh for every request, so
+// a Secret replaced or destroyed by its owner takes effect immediately.
+func NewTransport(base http.RoundTripper, opts ...TransportOption) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	t := &Transport{base: base}
+
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	return t
+}
+
+// RoundTrip sets the configured credentials on a clone of req and sends it. If a
+// credential cannot be exposed, for example because its Secret has been destroyed, the
+// request is not sent.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	authenticated := req.Clone(req.Context())
+
+	for _, set := range t.setters {
+		if err := set(authenticated); err != nil {
+			// RoundTrip must always close the request body, even on error.
+			if req.Body != nil {
+				req.Body.Close()
+			}
+
+			return nil, err
+		}
+	}
+
+	return t.base.RoundTrip(authenticated)
+}
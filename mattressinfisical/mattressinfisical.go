@@ -0,0 +1,172 @@
+// mattressinfisical sources mattress Secrets from Infisical.
+//
+// Responses are read into memory that is wiped once decoded, and each value is sealed
+// into a Secret as soon as it has been decoded.
+//
+// Example Usage:
+//
+//	import (
+//	  m "github.com/garrettladley/mattress"
+//	  "github.com/garrettladley/mattress/mattressinfisical"
+//	)
+//
+//	func main() {
+//	  token, err := m.NewSecretFromEnv("INFISICAL_TOKEN")
+//	  if err != nil {
+//	    // handle error
+//	  }
+//
+//	  client := mattressinfisical.NewClient(token, "6612b7c4c6b5f1b0e8d7a3f2", "prod")
+//	  m.Register("infisical", client)
+//
+//	  password, err := m.Fetch(ctx, "infisical", "/database/PASSWORD")
+//	  if err != nil {
+//	    // handle error
+//	  }
+//	  defer password.Destroy()
+//	}
+package mattressinfisical
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/awnumar/memguard"
+	m "github.com/garrettladley/mattress"
+)
+
+// DefaultAddr is the address of Infisical Cloud.
+const DefaultAddr = "https://app.infisical.com"
+
+// Client fetches secrets from an environment of an Infisical project. It implements
+// mattress.Provider.
+type Client struct {
+	addr        string            // addr is the address of the Infisical server
+	token       *m.Secret[string] // token authenticates requests to Infisical
+	project     string            // project is the ID of the project secrets are fetched from
+	environment string            // environment is the slug of the environment secrets are fetched from
+	http        *http.Client      // http performs requests to Infisical
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithAddr sets the address of the Infisical server, for self-hosted instances. It
+// defaults to DefaultAddr.
+func WithAddr(addr string) Option {
+	return func(c *Client) {
+		c.addr = strings.TrimRight(addr, "/")
+	}
+}
+
+// WithHTTPClient sets the http.Client used to make requests to Infisical. It defaults
+// to http.DefaultClient.
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *Client) {
+		c.http = client
+	}
+}
+
+// NewClient returns a Client fetching secrets from the environment with the slug
+// environment, such as "dev" or "prod", of the project with the ID project. It
+// authenticates with token, an access token obtained with a machine identity or a
+// service token. The token remains owned by the caller and must outlive the Client.
+func NewClient(token *m.Secret[string], project, environment string, opts ...Option) *Client {
+	c := &Client{
+		addr:        DefaultAddr,
+		token:       token,
+		project:     project,
+		environment: environment,
+		http:        http.DefaultClient,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// Fetch implements mattress.Provider. name is the key of the secret, optionally
+// preceded by the path of the folder holding it, as in "/database/PASSWORD"; secrets
+// without a folder are fetched from the root of the environment. References to other
+// secrets within its value are expanded by Infisical.
+func (c *Client) Fetch(ctx context.Context, name string) (*m.Secret[string], error) {
+	folder, key := path.Split("/" + strings.TrimPrefix(name, "/"))
+	if key == "" {
+		return nil, fmt.Errorf("mattressinfisical: invalid secret name %q", name)
+	}
+
+	query := url.Values{
+		"workspaceId":            {c.project},
+		"environment":            {c.environment},
+		"secretPath":             {path.Clean(folder)},
+		"expandSecretReferences": {"true"},
+	}
+
+	u, err := url.JoinPath(c.addr, "api/v3/secrets/raw", url.PathEscape(key))
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.token.WithExposed(func(token string) error {
+		req.Header.Set("Authorization", "Bearer "+token)
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("mattressinfisical: exposing token: %w", err)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(resp.Body)
+
+	// WipeBytes securely erases the response body once it has been decoded.
+	defer memguard.WipeBytes(raw)
+
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, fmt.Errorf("mattressinfisical: %q: %w", name, m.ErrSecretNotFound)
+	case resp.StatusCode >= http.StatusBadRequest:
+		var response struct {
+			Message string `json:"message"`
+		}
+		_ = json.Unmarshal(raw, &response)
+
+		return nil, fmt.Errorf("mattressinfisical: unexpected status %d: %s", resp.StatusCode, response.Message)
+	}
+
+	var response struct {
+		Secret struct {
+			Value *string `json:"secretValue"`
+		} `json:"secret"`
+	}
+
+	if err := json.Unmarshal(raw, &response); err != nil {
+		return nil, fmt.Errorf("mattressinfisical: decoding %q: %w", name, err)
+	}
+
+	if response.Secret.Value == nil {
+		return nil, fmt.Errorf("mattressinfisical: %q: %w", name, m.ErrSecretNotFound)
+	}
+
+	return m.NewSecretString(*response.Secret.Value)
+}
@@ -0,0 +1,292 @@
+// mattressipc transfers mattress Secrets between processes over Unix domain sockets, for
+// privilege-separated architectures in which one process holds credentials on behalf
+// of others, such as a supervisor handing a database password to a worker it spawned.
+//
+// Before anything is sent, each side verifies the credentials of the process at the
+// other end of the socket, as reported by the kernel, rather than trusting anything the
+// peer claims about itself. The secret is then encrypted with ChaCha20-Poly1305 under a
+// key agreed with ephemeral X25519 keys, so that it never crosses the socket in
+// plaintext. The key exchange is not authenticated beyond the kernel's report of the
+// peer, which is what binds the channel to the verified process.
+//
+// Peer credentials are supported on Linux and macOS; other platforms return
+// errors.ErrUnsupported.
+//
+// Example Usage:
+//
+//	import (
+//	  m "github.com/garrettladley/mattress"
+//	  "github.com/garrettladley/mattress/mattressipc"
+//	)
+//
+//	// In the process holding the secret.
+//	func serve(listener *net.UnixListener, password *m.Secret[string]) error {
+//	  conn, err := listener.AcceptUnix()
+//	  if err != nil {
+//	    return err
+//	  }
+//	  defer conn.Close()
+//
+//	  return mattressipc.Send(conn, password, mattressipc.AllowUIDs(workerUID))
+//	}
+//
+//	// In the process receiving it.
+//	func fetch(path string) (*m.Secret[[]byte], error) {
+//	  conn, err := net.DialUnix("unix", nil, &net.UnixAddr{Name: path, Net: "unix"})
+//	  if err != nil {
+//	    return nil, err
+//	  }
+//	  defer conn.Close()
+//
+//	  return mattressipc.Receive(conn, mattressipc.AllowUIDs(0))
+//	}
+package mattressipc
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"slices"
+
+	"github.com/awnumar/memguard"
+	m "github.com/garrettladley/mattress"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// MaxSize is the largest secret, in bytes, that Receive accepts.
+const MaxSize = 1 << 20
+
+// magic prefixes the handshake of each side, identifying the protocol and its version.
+var magic = [5]byte{'M', 'T', 'R', 'I', 1}
+
+// ack is sent by the receiver once the secret has been secured.
+const ack = 0x01
+
+// Peer holds the credentials of the process at the other end of a socket, as reported
+// by the kernel.
+type Peer struct {
+	PID int // PID is the process ID of the peer, or 0 if the platform does not report it
+	UID int // UID is the effective user ID of the peer
+	GID int // GID is the effective group ID of the peer
+}
+
+// Verifier decides whether a Peer may take part in a transfer, returning an error if
+// not.
+type Verifier func(peer Peer) error
+
+// ErrPeerRejected is returned when a Verifier rejects the peer of a socket.
+var ErrPeerRejected = errors.New("mattressipc: peer rejected")
+
+// SameUser is a Verifier which accepts only peers running as the same effective user
+// as the current process. It is used when no Verifier is given.
+func SameUser(peer Peer) error {
+	if peer.UID != os.Geteuid() {
+		return fmt.Errorf("%w: uid %d", ErrPeerRejected, peer.UID)
+	}
+
+	return nil
+}
+
+// AllowUIDs returns a Verifier which accepts only peers running as one of uids.
+func AllowUIDs(uids ...int) Verifier {
+	return func(peer Peer) error {
+		if !slices.Contains(uids, peer.UID) {
+			return fmt.Errorf("%w: uid %d", ErrPeerRejected, peer.UID)
+		}
+
+		return nil
+	}
+}
+
+// AllowPIDs returns a Verifier which accepts only the processes with the given IDs,
+// such as a child the caller has just started.
+func AllowPIDs(pids ...int) Verifier {
+	return func(peer Peer) error {
+		if peer.PID == 0 || !slices.Contains(pids, peer.PID) {
+			return fmt.Errorf("%w: pid %d", ErrPeerRejected, peer.PID)
+		}
+
+		return nil
+	}
+}
+
+// PeerCredentials returns the credentials of the process at the other end of conn.
+func PeerCredentials(conn *net.UnixConn) (Peer, error) {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return Peer{}, err
+	}
+
+	var (
+		peer    Peer
+		peerErr error
+	)
+
+	if err := raw.Control(func(fd uintptr) {
+		peer, peerErr = peerCredentials(int(fd))
+	}); err != nil {
+		return Peer{}, err
+	}
+
+	if peerErr != nil {
+		return Peer{}, fmt.Errorf("mattressipc: reading peer credentials: %w", peerErr)
+	}
+
+	return peer, nil
+}
+
+// Send verifies the peer of conn with verify, or SameUser if verify is nil, and sends
+// it the plaintext of secret, encrypted as described in the package documentation.
+// Send returns once the peer has acknowledged that the secret has been secured.
+func Send(conn *net.UnixConn, secret m.Redactable, verify Verifier) error {
+	aead, err := handshake(conn, verify, true)
+	if err != nil {
+		return err
+	}
+
+	err = secret.WithPlaintext(func(plaintext []byte) error {
+		if len(plaintext) > MaxSize {
+			return fmt.Errorf("mattressipc: secret of %d bytes exceeds the maximum of %d", len(plaintext), MaxSize)
+		}
+
+		header := binary.BigEndian.AppendUint32(nil, uint32(len(plaintext)+aead.Overhead()))
+
+		// The key is unique to the connection, so a zero nonce is never reused.
+		message := aead.Seal(header, make([]byte, aead.NonceSize()), plaintext, header)
+
+		_, err := conn.Write(message)
+
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("mattressipc: sending: %w", err)
+	}
+
+	var reply [1]byte
+	if _, err := io.ReadFull(conn, reply[:]); err != nil || reply[0] != ack {
+		return errors.New("mattressipc: peer did not acknowledge the secret")
+	}
+
+	return nil
+}
+
+// Receive verifies the peer of conn with verify, or SameUser if verify is nil, and
+// receives a secret sent with Send into a Secret created with opts. The secret is
+// decrypted in place and secured immediately.
+func Receive(conn *net.UnixConn, verify Verifier, opts ...m.Option) (*m.Secret[[]byte], error) {
+	aead, err := handshake(conn, verify, false)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return nil, fmt.Errorf("mattressipc: receiving: %w", err)
+	}
+
+	size := binary.BigEndian.Uint32(header)
+	if size < uint32(aead.Overhead()) || size > MaxSize+uint32(aead.Overhead()) {
+		return nil, fmt.Errorf("mattressipc: receiving: invalid size %d", size)
+	}
+
+	ciphertext := make([]byte, size)
+	if _, err := io.ReadFull(conn, ciphertext); err != nil {
+		return nil, fmt.Errorf("mattressipc: receiving: %w", err)
+	}
+
+	// Open decrypts in place, so that the plaintext is never copied.
+	plaintext, err := aead.Open(ciphertext[:0], make([]byte, aead.NonceSize()), ciphertext, header)
+	if err != nil {
+		return nil, fmt.Errorf("mattressipc: receiving: %w", m.ErrDecryptionFailed)
+	}
+
+	// NewSecretBytes wipes the plaintext once it has been secured.
+	secret, err := m.NewSecretBytes(plaintext, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := conn.Write([]byte{ack}); err != nil {
+		secret.Destroy()
+		return nil, fmt.Errorf("mattressipc: acknowledging: %w", err)
+	}
+
+	return secret, nil
+}
+
+// handshake verifies the peer of conn and exchanges ephemeral X25519 public keys with
+// it, returning an AEAD keyed with the agreed key. sender orders the public keys, so
+// that both sides derive the same key.
+func handshake(conn *net.UnixConn, verify Verifier, sender bool) (cipher.AEAD, error) {
+	if verify == nil {
+		verify = SameUser
+	}
+
+	peer, err := PeerCredentials(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := verify(peer); err != nil {
+		return nil, err
+	}
+
+	private, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	public := private.PublicKey().Bytes()
+
+	if _, err := conn.Write(append(magic[:], public...)); err != nil {
+		return nil, fmt.Errorf("mattressipc: handshake: %w", err)
+	}
+
+	reply := make([]byte, len(magic)+len(public))
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return nil, fmt.Errorf("mattressipc: handshake: %w", err)
+	}
+
+	if !bytes.HasPrefix(reply, magic[:]) {
+		return nil, errors.New("mattressipc: handshake: unexpected protocol")
+	}
+
+	peerPublic, err := ecdh.X25519().NewPublicKey(reply[len(magic):])
+	if err != nil {
+		return nil, fmt.Errorf("mattressipc: handshake: %w", err)
+	}
+
+	shared, err := private.ECDH(peerPublic)
+	if err != nil {
+		return nil, fmt.Errorf("mattressipc: handshake: %w", err)
+	}
+
+	// WipeBytes securely erases the shared secret once the key has been derived.
+	defer memguard.WipeBytes(shared)
+
+	senderPublic, receiverPublic := public, peerPublic.Bytes()
+	if !sender {
+		senderPublic, receiverPublic = receiverPublic, senderPublic
+	}
+
+	salt := slices.Concat(senderPublic, receiverPublic)
+
+	key, err := hkdf.Key(sha256.New, shared, salt, "mattressipc", chacha20poly1305.KeySize)
+	if err != nil {
+		return nil, err
+	}
+
+	// WipeBytes securely erases the key once the AEAD has been created.
+	defer memguard.WipeBytes(key)
+
+	return chacha20poly1305.New(key)
+}
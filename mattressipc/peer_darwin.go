@@ -0,0 +1,26 @@
+package mattressipc
+
+import "golang.org/x/sys/unix"
+
+// peerCredentials reads the credentials of the peer of the socket fd with
+// LOCAL_PEERCRED and LOCAL_PEERPID.
+func peerCredentials(fd int) (Peer, error) {
+	cred, err := unix.GetsockoptXucred(fd, unix.SOL_LOCAL, unix.LOCAL_PEERCRED)
+	if err != nil {
+		return Peer{}, err
+	}
+
+	pid, err := unix.GetsockoptInt(fd, unix.SOL_LOCAL, unix.LOCAL_PEERPID)
+	if err != nil {
+		return Peer{}, err
+	}
+
+	peer := Peer{PID: pid, UID: int(cred.Uid)}
+
+	// The first group of an xucred is the effective group ID.
+	if cred.Ngroups > 0 {
+		peer.GID = int(cred.Groups[0])
+	}
+
+	return peer, nil
+}
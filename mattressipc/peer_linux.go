@@ -0,0 +1,13 @@
+package mattressipc
+
+import "golang.org/x/sys/unix"
+
+// peerCredentials reads the credentials of the peer of the socket fd with SO_PEERCRED.
+func peerCredentials(fd int) (Peer, error) {
+	cred, err := unix.GetsockoptUcred(fd, unix.SOL_SOCKET, unix.SO_PEERCRED)
+	if err != nil {
+		return Peer{}, err
+	}
+
+	return Peer{PID: int(cred.Pid), UID: int(cred.Uid), GID: int(cred.Gid)}, nil
+}
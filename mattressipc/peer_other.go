@@ -0,0 +1,10 @@
+//go:build !linux && !darwin
+
+package mattressipc
+
+import "errors"
+
+// peerCredentials is unsupported on this platform.
+func peerCredentials(int) (Peer, error) {
+	return Peer{}, errors.ErrUnsupported
+}
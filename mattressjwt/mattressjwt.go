@@ -0,0 +1,298 @@
+// mattressjwt signs and verifies JSON Web Tokens using keys held in mattress Secrets.
+//
+// HS256 keys are Secrets holding the raw HMAC key, while RS256 and EdDSA keys are
+// mattresscrypto.Signers. In every case the key is only unsealed inside the library
+// call, so the rest of the application only ever handles tokens.
+//
+// Example Usage:
+//
+//	import (
+//	  m "github.com/garrettladley/mattress"
+//	  "github.com/garrettladley/mattress/mattressjwt"
+//	)
+//
+//	func main() {
+//	  key, err := m.NewSecretFromFile("/run/secrets/jwt_key")
+//	  if err != nil {
+//	    // handle error
+//	  }
+//
+//	  token, err := mattressjwt.HS256(key).Sign(map[string]any{"sub": "user"})
+//	  if err != nil {
+//	    // handle error
+//	  }
+//
+//	  var claims map[string]any
+//	  if err := mattressjwt.HS256Verifier(key).Verify(token, &claims); err != nil {
+//	    // handle error
+//	  }
+//	}
+package mattressjwt
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	m "github.com/garrettladley/mattress"
+	"github.com/garrettladley/mattress/mattresscrypto"
+)
+
+// Algorithm identifies a JWS signing algorithm, as carried in the "alg" header.
+type Algorithm string
+
+// The signing algorithms supported by this package.
+const (
+	AlgHS256 Algorithm = "HS256"
+	AlgRS256 Algorithm = "RS256"
+	AlgEdDSA Algorithm = "EdDSA"
+)
+
+var (
+	// ErrInvalidToken is returned when a token is malformed or its signature does not
+	// verify.
+	ErrInvalidToken = errors.New("mattressjwt: invalid token")
+
+	// ErrAlgorithmMismatch is returned when a token is signed with an algorithm other
+	// than the one a Verifier expects.
+	ErrAlgorithmMismatch = errors.New("mattressjwt: unexpected signing algorithm")
+
+	// ErrTokenExpired is returned when the "exp" claim of a token has passed.
+	ErrTokenExpired = errors.New("mattressjwt: token has expired")
+
+	// ErrTokenNotYetValid is returned when the "nbf" claim of a token has not yet been
+	// reached.
+	ErrTokenNotYetValid = errors.New("mattressjwt: token is not yet valid")
+)
+
+// encoding is the base64url encoding, without padding, used by JWS.
+var encoding = base64.RawURLEncoding
+
+// header is the JOSE header of a token.
+type header struct {
+	Algorithm Algorithm `json:"alg"`
+	Type      string    `json:"typ,omitempty"`
+}
+
+// Signer signs tokens with a key held in a Secret.
+type Signer struct {
+	alg  Algorithm                          // alg is the signing algorithm
+	sign func(input []byte) ([]byte, error) // sign signs the JWS signing input
+}
+
+// HS256 returns a Signer which signs tokens with HMAC-SHA256 using the raw key held
+// by key. key remains owned by the caller and must outlive the Signer.
+func HS256(key *m.Secret[[]byte]) *Signer {
+	return &Signer{alg: AlgHS256, sign: func(input []byte) ([]byte, error) {
+		mac := key.HMAC(sha256.New, input)
+		if mac == nil {
+			return nil, m.ErrDestroyed
+		}
+		return mac, nil
+	}}
+}
+
+// RS256 returns a Signer which signs tokens with RSASSA-PKCS1-v1_5 using SHA-256. The
+// key of signer must be an RSA key. signer remains owned by the caller and must
+// outlive the Signer.
+func RS256(signer *mattresscrypto.Signer) (*Signer, error) {
+	if _, ok := signer.Public().(*rsa.PublicKey); !ok {
+		return nil, fmt.Errorf("mattressjwt: %s requires an RSA key", AlgRS256)
+	}
+
+	return &Signer{alg: AlgRS256, sign: func(input []byte) ([]byte, error) {
+		digest := sha256.Sum256(input)
+		return signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	}}, nil
+}
+
+// EdDSA returns a Signer which signs tokens with Ed25519. The key of signer must be an
+// Ed25519 key. signer remains owned by the caller and must outlive the Signer.
+func EdDSA(signer *mattresscrypto.Signer) (*Signer, error) {
+	if _, ok := signer.Public().(ed25519.PublicKey); !ok {
+		return nil, fmt.Errorf("mattressjwt: %s requires an Ed25519 key", AlgEdDSA)
+	}
+
+	return &Signer{alg: AlgEdDSA, sign: func(input []byte) ([]byte, error) {
+		return signer.Sign(rand.Reader, input, crypto.Hash(0))
+	}}, nil
+}
+
+// Sign encodes claims as JSON and returns a signed token in JWS compact serialization.
+func (s *Signer) Sign(claims any) (string, error) {
+	h, err := json.Marshal(header{Algorithm: s.alg, Type: "JWT"})
+	if err != nil {
+		return "", err
+	}
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	input := encoding.EncodeToString(h) + "." + encoding.EncodeToString(payload)
+
+	signature, err := s.sign([]byte(input))
+	if err != nil {
+		return "", err
+	}
+
+	return input + "." + encoding.EncodeToString(signature), nil
+}
+
+// Verifier verifies tokens signed with a single algorithm and key.
+type Verifier struct {
+	alg    Algorithm                            // alg is the expected signing algorithm
+	verify func(input []byte, sig []byte) error // verify checks a signature
+	leeway time.Duration                        // leeway allows for clock skew
+	now    func() time.Time                     // now returns the current time
+}
+
+// VerifierOption configures a Verifier.
+type VerifierOption func(*Verifier)
+
+// WithLeeway allows for clock skew of up to leeway when checking the "exp" and "nbf"
+// claims.
+func WithLeeway(leeway time.Duration) VerifierOption {
+	return func(v *Verifier) {
+		v.leeway = leeway
+	}
+}
+
+// newVerifier returns a Verifier with opts applied.
+func newVerifier(alg Algorithm, verify func(input []byte, sig []byte) error, opts []VerifierOption) *Verifier {
+	v := &Verifier{alg: alg, verify: verify, now: time.Now}
+
+	for _, opt := range opts {
+		opt(v)
+	}
+
+	return v
+}
+
+// HS256Verifier returns a Verifier for tokens signed with HMAC-SHA256 using the raw key
+// held by key. key remains owned by the caller and must outlive the Verifier. Every
+// token is rejected, with mattress.ErrDestroyed, while key cannot be exposed, such as
+// once it has been destroyed or has expired.
+func HS256Verifier(key *m.Secret[[]byte], opts ...VerifierOption) *Verifier {
+	return newVerifier(AlgHS256, func(input []byte, sig []byte) error {
+		if len(sig) != sha256.Size {
+			return ErrInvalidToken
+		}
+
+		mac := key.HMAC(sha256.New, input)
+		if mac == nil {
+			return m.ErrDestroyed
+		}
+
+		if !hmac.Equal(mac, sig) {
+			return ErrInvalidToken
+		}
+
+		return nil
+	}, opts)
+}
+
+// RS256Verifier returns a Verifier for tokens signed with RSASSA-PKCS1-v1_5 using
+// SHA-256 by the private key corresponding to public.
+func RS256Verifier(public *rsa.PublicKey, opts ...VerifierOption) *Verifier {
+	return newVerifier(AlgRS256, func(input []byte, sig []byte) error {
+		digest := sha256.Sum256(input)
+		if rsa.VerifyPKCS1v15(public, crypto.SHA256, digest[:], sig) != nil {
+			return ErrInvalidToken
+		}
+
+		return nil
+	}, opts)
+}
+
+// EdDSAVerifier returns a Verifier for tokens signed with Ed25519 by the private key
+// corresponding to public.
+func EdDSAVerifier(public ed25519.PublicKey, opts ...VerifierOption) *Verifier {
+	return newVerifier(AlgEdDSA, func(input []byte, sig []byte) error {
+		if !ed25519.Verify(public, input, sig) {
+			return ErrInvalidToken
+		}
+
+		return nil
+	}, opts)
+}
+
+// Verify checks the signature of token and its "exp" and "nbf" claims, if present,
+// and then decodes its claims into claims. claims may be nil to skip decoding.
+func (v *Verifier) Verify(token string, claims any) error {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return ErrInvalidToken
+	}
+
+	var h header
+	if err := decodeSegment(parts[0], &h); err != nil {
+		return err
+	}
+
+	if h.Algorithm != v.alg {
+		return fmt.Errorf("%w: %q", ErrAlgorithmMismatch, h.Algorithm)
+	}
+
+	signature, err := encoding.DecodeString(parts[2])
+	if err != nil {
+		return ErrInvalidToken
+	}
+
+	if err := v.verify([]byte(parts[0]+"."+parts[1]), signature); err != nil {
+		return err
+	}
+
+	var registered struct {
+		Expiry    *float64 `json:"exp"`
+		NotBefore *float64 `json:"nbf"`
+	}
+	if err := decodeSegment(parts[1], &registered); err != nil {
+		return err
+	}
+
+	now := v.now()
+
+	if registered.Expiry != nil && now.After(unix(*registered.Expiry).Add(v.leeway)) {
+		return ErrTokenExpired
+	}
+
+	if registered.NotBefore != nil && now.Before(unix(*registered.NotBefore).Add(-v.leeway)) {
+		return ErrTokenNotYetValid
+	}
+
+	if claims == nil {
+		return nil
+	}
+
+	return decodeSegment(parts[1], claims)
+}
+
+// decodeSegment decodes a base64url encoded JSON segment of a token into out.
+func decodeSegment(segment string, out any) error {
+	b, err := encoding.DecodeString(segment)
+	if err != nil {
+		return ErrInvalidToken
+	}
+
+	if err := json.Unmarshal(b, out); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+
+	return nil
+}
+
+// unix converts a JWT NumericDate to a time.Time.
+func unix(seconds float64) time.Time {
+	return time.Unix(0, int64(seconds*float64(time.Second)))
+}
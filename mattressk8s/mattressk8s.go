@@ -0,0 +1,370 @@
+// mattressk8s loads Kubernetes Secrets mounted as volumes into mattress Secrets.
+//
+// Each file in the mount becomes a Secret keyed by its file name. The kubelet rotates
+// a mounted Secret by atomically swapping the "..data" symlink to a new directory; a
+// watched Mount detects the swap, reloads every key, and notifies its subscribers.
+//
+// Example Usage:
+//
+//	import (
+//	  "github.com/garrettladley/mattress/mattressk8s"
+//	)
+//
+//	func main() {
+//	  mount, err := mattressk8s.Watch(ctx, "/var/run/secrets/db", time.Minute)
+//	  if err != nil {
+//	    // handle error
+//	  }
+//	  defer mount.Close()
+//
+//	  rotations, unsubscribe := mount.Subscribe()
+//	  defer unsubscribe()
+//
+//	  for range rotations {
+//	    // reconnect using the rotated credentials
+//	  }
+//	}
+package mattressk8s
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	m "github.com/garrettladley/mattress"
+)
+
+// dataLink is the symlink the kubelet swaps to rotate a mounted Secret.
+const dataLink = "..data"
+
+// Rotation describes a change to the contents of a Mount.
+type Rotation struct {
+	Version string   // Version identifies the new contents of the Mount
+	Keys    []string // Keys lists the keys present after the rotation
+}
+
+// Mount holds the keys of a mounted Kubernetes Secret, each sealed into its own
+// Secret.
+type Mount struct {
+	dir         string                       // dir is the mount directory
+	secrets     map[string]*m.Secret[[]byte] // secrets maps keys to their values
+	version     string                       // version identifies the loaded contents
+	subscribers map[chan Rotation]struct{}   // subscribers are notified of rotations
+	onError     func(error)                  // onError is notified of background failures
+	lock        sync.RWMutex                 // synchronize access to the fields above
+	cancel      context.CancelFunc
+	done        chan struct{}
+}
+
+// Option configures a watched Mount.
+type Option func(*Mount)
+
+// OnError registers fn to be called with any error encountered while reloading the
+// Mount in the background. Failed reloads are retried at the next interval.
+func OnError(fn func(error)) Option {
+	return func(mt *Mount) {
+		mt.onError = fn
+	}
+}
+
+// Load loads every key of the Secret mounted at dir.
+func Load(dir string) (*Mount, error) {
+	secrets, version, err := load(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Mount{
+		dir:         dir,
+		secrets:     secrets,
+		version:     version,
+		subscribers: make(map[chan Rotation]struct{}),
+		onError:     func(error) {},
+	}, nil
+}
+
+// Watch loads every key of the Secret mounted at dir, and checks every interval
+// whether it has been rotated, reloading it if so, until the Mount is closed or ctx is
+// cancelled.
+func Watch(ctx context.Context, dir string, interval time.Duration, opts ...Option) (*Mount, error) {
+	mt, err := Load(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, opt := range opts {
+		opt(mt)
+	}
+
+	ctx, mt.cancel = context.WithCancel(ctx)
+	mt.done = make(chan struct{})
+
+	go mt.run(ctx, interval)
+
+	return mt, nil
+}
+
+// Get returns the Secret holding the value of key. The Secret remains owned by the
+// Mount and is destroyed once the Mount is rotated or closed, so callers should call
+// Get each time the value is needed rather than retaining it.
+func (mt *Mount) Get(key string) (*m.Secret[[]byte], bool) {
+	mt.lock.RLock()         // RLock before reading the secrets
+	defer mt.lock.RUnlock() // Ensure the lock is RUnlocked when the method returns
+
+	secret, ok := mt.secrets[key]
+
+	return secret, ok
+}
+
+// WithExposed exposes the value of key to fn, guaranteeing that it is not rotated
+// while fn runs. It returns an error wrapping mattress.ErrSecretNotFound if the Mount
+// has no such key.
+func (mt *Mount) WithExposed(key string, fn func([]byte) error) error {
+	mt.lock.RLock()         // RLock before reading the secrets
+	defer mt.lock.RUnlock() // Ensure the lock is RUnlocked when the method returns
+
+	secret, ok := mt.secrets[key]
+	if !ok {
+		return fmt.Errorf("mattressk8s: key %q: %w", key, m.ErrSecretNotFound)
+	}
+
+	return secret.WithExposed(fn)
+}
+
+// Keys returns the keys of the Mount in sorted order.
+func (mt *Mount) Keys() []string {
+	mt.lock.RLock()         // RLock before reading the secrets
+	defer mt.lock.RUnlock() // Ensure the lock is RUnlocked when the method returns
+
+	return keys(mt.secrets)
+}
+
+// Version returns an identifier for the currently loaded contents of the Mount.
+func (mt *Mount) Version() string {
+	mt.lock.RLock()         // RLock before reading the version
+	defer mt.lock.RUnlock() // Ensure the lock is RUnlocked when the method returns
+
+	return mt.version
+}
+
+// Subscribe returns a channel on which each Rotation of the Mount is delivered, along
+// with a function that cancels the subscription. Rotations are not queued: a
+// subscriber that has not received the previous Rotation only receives the latest.
+func (mt *Mount) Subscribe() (<-chan Rotation, func()) {
+	ch := make(chan Rotation, 1)
+
+	mt.lock.Lock()
+	mt.subscribers[ch] = struct{}{}
+	mt.lock.Unlock()
+
+	var once sync.Once
+
+	return ch, func() {
+		once.Do(func() {
+			mt.lock.Lock()
+			defer mt.lock.Unlock()
+
+			if _, ok := mt.subscribers[ch]; ok {
+				delete(mt.subscribers, ch)
+				close(ch)
+			}
+		})
+	}
+}
+
+// Close stops watching the Mount, closes every subscription, and destroys every
+// Secret.
+func (mt *Mount) Close() {
+	if mt.cancel != nil {
+		mt.cancel()
+		<-mt.done
+	}
+
+	mt.lock.Lock()         // Lock before destroying the secrets
+	defer mt.lock.Unlock() // Ensure the lock is Unlocked when the method returns
+
+	destroy(mt.secrets)
+	mt.secrets = map[string]*m.Secret[[]byte]{}
+
+	for ch := range mt.subscribers {
+		delete(mt.subscribers, ch)
+		close(ch)
+	}
+}
+
+// run checks for rotations every interval until ctx is cancelled.
+func (mt *Mount) run(ctx context.Context, interval time.Duration) {
+	defer close(mt.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := mt.reload(); err != nil {
+			mt.onError(err)
+		}
+	}
+}
+
+// reload reloads the Mount if it has been rotated, notifying subscribers.
+func (mt *Mount) reload() error {
+	version, err := resolveVersion(mt.dir)
+	if err != nil {
+		return err
+	}
+
+	if version == mt.Version() {
+		return nil
+	}
+
+	secrets, version, err := load(mt.dir)
+	if err != nil {
+		return err
+	}
+
+	mt.lock.Lock()         // Lock before replacing the secrets
+	defer mt.lock.Unlock() // Ensure the lock is Unlocked when the method returns
+
+	destroy(mt.secrets)
+	mt.secrets = secrets
+	mt.version = version
+
+	rotation := Rotation{Version: version, Keys: keys(secrets)}
+
+	for ch := range mt.subscribers {
+		// Drop any undelivered Rotation in favour of the latest.
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- rotation
+	}
+
+	return nil
+}
+
+// load reads every key of the Secret mounted at dir, returning them along with the
+// version they were read from. The version is resolved before and after reading, and
+// the read is retried if the Mount was rotated in between.
+func load(dir string) (map[string]*m.Secret[[]byte], string, error) {
+	for {
+		before, err := resolveVersion(dir)
+		if err != nil {
+			return nil, "", err
+		}
+
+		secrets, err := readAll(dir)
+		if err != nil {
+			return nil, "", err
+		}
+
+		after, err := resolveVersion(dir)
+		if err != nil {
+			destroy(secrets)
+			return nil, "", err
+		}
+
+		if before == after {
+			return secrets, after, nil
+		}
+
+		destroy(secrets)
+	}
+}
+
+// readAll reads every key of the Secret mounted at dir.
+func readAll(dir string) (map[string]*m.Secret[[]byte], error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("mattressk8s: %w", err)
+	}
+
+	secrets := make(map[string]*m.Secret[[]byte], len(entries))
+
+	for _, entry := range entries {
+		// Skip the kubelet's bookkeeping entries, such as "..data".
+		if strings.HasPrefix(entry.Name(), "..") {
+			continue
+		}
+
+		path := filepath.Join(dir, entry.Name())
+
+		// Keys are symlinks into the current data directory, so follow them.
+		info, err := os.Stat(path)
+		if err != nil {
+			destroy(secrets)
+			return nil, fmt.Errorf("mattressk8s: %w", err)
+		}
+
+		if info.IsDir() {
+			continue
+		}
+
+		secret, err := m.NewSecretFromFile(path)
+		if err != nil {
+			destroy(secrets)
+			return nil, fmt.Errorf("mattressk8s: reading key %q: %w", entry.Name(), err)
+		}
+
+		secrets[entry.Name()] = secret
+	}
+
+	return secrets, nil
+}
+
+// resolveVersion returns an identifier for the current contents of the Secret
+// mounted at dir: the target of the "..data" symlink for a kubelet-managed mount, or
+// a summary of each file's size and modification time otherwise.
+func resolveVersion(dir string) (string, error) {
+	if target, err := os.Readlink(filepath.Join(dir, dataLink)); err == nil {
+		return target, nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", fmt.Errorf("mattressk8s: %w", err)
+	}
+
+	var version strings.Builder
+
+	for _, entry := range entries {
+		info, err := os.Stat(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return "", fmt.Errorf("mattressk8s: %w", err)
+		}
+
+		fmt.Fprintf(&version, "%s:%d:%d;", entry.Name(), info.Size(), info.ModTime().UnixNano())
+	}
+
+	return version.String(), nil
+}
+
+// keys returns the keys of secrets in sorted order.
+func keys(secrets map[string]*m.Secret[[]byte]) []string {
+	names := make([]string, 0, len(secrets))
+	for name := range secrets {
+		names = append(names, name)
+	}
+
+	slices.Sort(names)
+
+	return names
+}
+
+// destroy destroys every Secret in secrets.
+func destroy(secrets map[string]*m.Secret[[]byte]) {
+	for _, secret := range secrets {
+		secret.Destroy()
+	}
+}
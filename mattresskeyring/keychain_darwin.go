@@ -0,0 +1,96 @@
+package mattresskeyring
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os/exec"
+
+	"github.com/awnumar/memguard"
+	m "github.com/garrettladley/mattress"
+)
+
+// securityPath is the path of the security command line tool, which manages the
+// Keychain without requiring cgo.
+const securityPath = "/usr/bin/security"
+
+// errItemNotFound is the exit status of security when no matching item exists,
+// errSecItemNotFound.
+const errItemNotFound = 44
+
+// set adds, or updates, the generic password item for service and account in the
+// login Keychain. The command is passed to security on standard input, with the
+// password hex-encoded, so that the plaintext never appears in the arguments of a
+// process, which any user may list.
+func set(service, account string, plaintext []byte) error {
+	command := fmt.Appendf(nil, "add-generic-password -U -s \"%s\" -a \"%s\" -X ", service, account)
+	command = hex.AppendEncode(command, plaintext)
+	command = append(command, '\n')
+
+	// WipeBytes securely erases the command, which holds the encoded plaintext.
+	defer memguard.WipeBytes(command)
+
+	cmd := exec.Command(securityPath, "-i")
+	cmd.Stdin = bytes.NewReader(command)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+
+	// security -i reports failures of its commands on standard error, but exits zero.
+	if err == nil && stderr.Len() > 0 {
+		err = errors.New("add-generic-password failed")
+	}
+
+	if err != nil {
+		return fmt.Errorf("security: %w: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+
+	return nil
+}
+
+// get reads the password of the generic password item for service and account from the
+// login Keychain.
+func get(service, account string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.Command(securityPath, "find-generic-password", "-s", service, "-a", account, "-w")
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+
+	if err := run(cmd, &stderr); err != nil {
+		memguard.WipeBytes(stdout.Bytes())
+		return nil, err
+	}
+
+	// security prints the password followed by a newline.
+	return bytes.TrimSuffix(stdout.Bytes(), []byte("\n")), nil
+}
+
+// remove deletes the generic password item for service and account from the login
+// Keychain.
+func remove(service, account string) error {
+	var stderr bytes.Buffer
+
+	cmd := exec.Command(securityPath, "delete-generic-password", "-s", service, "-a", account)
+	cmd.Stderr = &stderr
+
+	return run(cmd, &stderr)
+}
+
+// run runs cmd, mapping a missing item to ErrSecretNotFound.
+func run(cmd *exec.Cmd, stderr *bytes.Buffer) error {
+	err := cmd.Run()
+
+	var exit *exec.ExitError
+	if errors.As(err, &exit) {
+		if exit.ExitCode() == errItemNotFound {
+			return m.ErrSecretNotFound
+		}
+
+		return fmt.Errorf("security: %w: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+
+	return err
+}
@@ -0,0 +1,79 @@
+package mattresskeyring
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/awnumar/memguard"
+	m "github.com/garrettladley/mattress"
+	"golang.org/x/sys/unix"
+)
+
+// keyType is the type of the keys created in the user keyring.
+const keyType = "user"
+
+// description returns the description of the key holding the secret stored under
+// service and account.
+func description(service, account string) string {
+	return "mattress:" + service + ":" + account
+}
+
+// set adds, or updates, the key for service and account in the user keyring.
+func set(service, account string, plaintext []byte) error {
+	_, err := unix.AddKey(keyType, description(service, account), plaintext, unix.KEY_SPEC_USER_KEYRING)
+
+	return err
+}
+
+// get reads the payload of the key for service and account from the user keyring.
+func get(service, account string) ([]byte, error) {
+	id, err := search(service, account)
+	if err != nil {
+		return nil, err
+	}
+
+	// The payload may grow between the two reads, in which case it is truncated and read
+	// again.
+	for {
+		n, err := unix.KeyctlBuffer(unix.KEYCTL_READ, id, nil, 0)
+		if err != nil {
+			return nil, err
+		}
+
+		plaintext := make([]byte, n)
+
+		read, err := unix.KeyctlBuffer(unix.KEYCTL_READ, id, plaintext, 0)
+		if err != nil {
+			memguard.WipeBytes(plaintext)
+			return nil, err
+		}
+
+		if read <= n {
+			return plaintext[:read], nil
+		}
+
+		memguard.WipeBytes(plaintext)
+	}
+}
+
+// remove unlinks the key for service and account from the user keyring.
+func remove(service, account string) error {
+	id, err := search(service, account)
+	if err != nil {
+		return err
+	}
+
+	_, err = unix.KeyctlInt(unix.KEYCTL_UNLINK, id, unix.KEY_SPEC_USER_KEYRING, 0, 0)
+
+	return err
+}
+
+// search returns the ID of the key for service and account in the user keyring.
+func search(service, account string) (int, error) {
+	id, err := unix.KeyctlSearch(unix.KEY_SPEC_USER_KEYRING, keyType, description(service, account), 0)
+	if errors.Is(err, unix.ENOKEY) {
+		return 0, fmt.Errorf("%w: %w", m.ErrSecretNotFound, err)
+	}
+
+	return id, err
+}
@@ -0,0 +1,20 @@
+//go:build !linux && !darwin
+
+package mattresskeyring
+
+import "errors"
+
+// set is unsupported on platforms other than Linux and macOS.
+func set(string, string, []byte) error {
+	return errors.ErrUnsupported
+}
+
+// get is unsupported on platforms other than Linux and macOS.
+func get(string, string) ([]byte, error) {
+	return nil, errors.ErrUnsupported
+}
+
+// remove is unsupported on platforms other than Linux and macOS.
+func remove(string, string) error {
+	return errors.ErrUnsupported
+}
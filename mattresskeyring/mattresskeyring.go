@@ -0,0 +1,119 @@
+// mattresskeyring persists small secrets, such as the tokens of CLI tools, in the
+// keystore of the operating system between runs, and loads them into mattress Secrets
+// on demand: the login Keychain on macOS, and the user keyring of the kernel, managed
+// with keyctl, on Linux. Other platforms return errors.ErrUnsupported.
+//
+// Secrets stored in the Linux user keyring last until the user's last session ends or
+// the machine reboots, whichever comes first, whereas Keychain items persist until
+// deleted.
+//
+// Example Usage:
+//
+//	import (
+//	  m "github.com/garrettladley/mattress"
+//	  "github.com/garrettladley/mattress/mattresskeyring"
+//	)
+//
+//	func main() {
+//	  keyring := mattresskeyring.New("my-cli")
+//
+//	  token, err := keyring.Get("default")
+//	  if errors.Is(err, m.ErrSecretNotFound) {
+//	    token, err = login()
+//	    if err == nil {
+//	      err = keyring.Set("default", token)
+//	    }
+//	  }
+//	  if err != nil {
+//	    // handle error
+//	  }
+//	  defer token.Destroy()
+//	}
+package mattresskeyring
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unsafe"
+
+	"github.com/awnumar/memguard"
+	m "github.com/garrettladley/mattress"
+)
+
+// Keyring stores secrets in the keystore of the operating system under a service name,
+// identifying the application, and an account name, identifying each secret. Keyring
+// implements mattress.Provider, fetching secrets by account name.
+type Keyring struct {
+	service string // service identifies the application owning the secrets
+}
+
+// New returns a Keyring storing secrets under service.
+func New(service string) *Keyring {
+	return &Keyring{service: service}
+}
+
+// Set stores the plaintext of secret under account, replacing any secret already
+// stored under it.
+func (k *Keyring) Set(account string, secret m.Redactable) error {
+	if err := validate(k.service, account); err != nil {
+		return err
+	}
+
+	return secret.WithPlaintext(func(plaintext []byte) error {
+		if err := set(k.service, account, plaintext); err != nil {
+			return fmt.Errorf("mattresskeyring: storing %s: %w", account, err)
+		}
+
+		return nil
+	})
+}
+
+// Get loads the secret stored under account into a Secret, returning an error wrapping
+// mattress.ErrSecretNotFound if there is none.
+func (k *Keyring) Get(account string, opts ...m.Option) (*m.Secret[string], error) {
+	if err := validate(k.service, account); err != nil {
+		return nil, err
+	}
+
+	plaintext, err := get(k.service, account)
+	if err != nil {
+		return nil, fmt.Errorf("mattresskeyring: loading %s: %w", account, err)
+	}
+
+	// WipeBytes securely erases the plaintext once it has been secured.
+	defer memguard.WipeBytes(plaintext)
+
+	return m.NewSecretString(unsafe.String(unsafe.SliceData(plaintext), len(plaintext)), opts...)
+}
+
+// Delete removes the secret stored under account, returning an error wrapping
+// mattress.ErrSecretNotFound if there is none.
+func (k *Keyring) Delete(account string) error {
+	if err := validate(k.service, account); err != nil {
+		return err
+	}
+
+	if err := remove(k.service, account); err != nil {
+		return fmt.Errorf("mattresskeyring: deleting %s: %w", account, err)
+	}
+
+	return nil
+}
+
+// Fetch loads the secret stored under the account name, as Get does.
+func (k *Keyring) Fetch(_ context.Context, name string) (*m.Secret[string], error) {
+	return k.Get(name)
+}
+
+// validate rejects service and account names which are empty or contain characters
+// which could not be passed to the keystore verbatim.
+func validate(service, account string) error {
+	for _, name := range []string{service, account} {
+		if name == "" || strings.ContainsAny(name, "\"\\\n\r\x00") {
+			return fmt.Errorf("mattresskeyring: invalid name %q", name)
+		}
+	}
+
+	return nil
+}
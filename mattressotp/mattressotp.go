@@ -0,0 +1,228 @@
+// mattressotp generates and verifies HOTP (RFC 4226) and TOTP (RFC 6238) codes from a
+// shared secret held in a mattress Secret, so 2FA seeds for service accounts never sit
+// in ordinary strings.
+//
+// Example Usage:
+//
+//	import (
+//	  m "github.com/garrettladley/mattress"
+//	  "github.com/garrettladley/mattress/mattressotp"
+//	)
+//
+//	func main() {
+//	  seed, err := m.NewSecretFromEnv("TOTP_SEED")
+//	  if err != nil {
+//	    // handle error
+//	  }
+//
+//	  otp, err := mattressotp.NewFromBase32(seed)
+//	  if err != nil {
+//	    // handle error
+//	  }
+//	  defer otp.Destroy()
+//
+//	  code, err := otp.Now()
+//	  if err != nil {
+//	    // handle error
+//	  }
+//	}
+package mattressotp
+
+import (
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"time"
+
+	"github.com/awnumar/memguard"
+	m "github.com/garrettladley/mattress"
+)
+
+// ErrInvalidSeed is returned by NewFromBase32 when the seed is not valid base32.
+var ErrInvalidSeed = errors.New("mattressotp: seed is not valid base32")
+
+// OTP generates and verifies one-time codes from a shared secret held in a Secret.
+type OTP struct {
+	seed   *m.Secret[[]byte] // seed is the shared secret
+	hash   func() hash.Hash  // hash is the HMAC hash function
+	digits int               // digits is the length of generated codes
+	period time.Duration     // period is the TOTP time step
+	skew   uint              // skew is the number of adjacent time steps accepted by Verify
+}
+
+// Option configures an OTP.
+type Option func(*OTP)
+
+// WithDigits sets the number of digits in generated codes, typically 6 or 8. The
+// default is 6.
+func WithDigits(digits int) Option {
+	return func(o *OTP) {
+		o.digits = digits
+	}
+}
+
+// WithPeriod sets the TOTP time step, which is truncated to whole seconds. The default
+// is 30 seconds.
+func WithPeriod(period time.Duration) Option {
+	return func(o *OTP) {
+		o.period = period
+	}
+}
+
+// WithHash sets the hash function used for the HMAC, such as sha256.New or
+// sha512.New. The default is sha1.New, as used by most authenticator apps.
+func WithHash(h func() hash.Hash) Option {
+	return func(o *OTP) {
+		o.hash = h
+	}
+}
+
+// WithSkew sets the number of time steps before and after the current one that Verify
+// accepts, to allow for clock drift. The default is 1.
+func WithSkew(skew uint) Option {
+	return func(o *OTP) {
+		o.skew = skew
+	}
+}
+
+// New returns an OTP for the raw shared secret held by seed. Ownership of seed is
+// transferred to the OTP, and it is destroyed by Destroy.
+func New(seed *m.Secret[[]byte], opts ...Option) (*OTP, error) {
+	o := &OTP{seed: seed, hash: sha1.New, digits: 6, period: 30 * time.Second, skew: 1}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	if o.digits < 1 || o.digits > 10 {
+		return nil, fmt.Errorf("mattressotp: unsupported number of digits %d", o.digits)
+	}
+
+	if o.period < time.Second {
+		return nil, fmt.Errorf("mattressotp: period must be at least one second")
+	}
+
+	return o, nil
+}
+
+// NewFromBase32 returns an OTP for the base32 encoded shared secret held by seed, as
+// found in otpauth:// URIs. Whitespace and padding are ignored and lowercase is
+// accepted. seed is destroyed once it has been decoded.
+func NewFromBase32(seed *m.Secret[string], opts ...Option) (*OTP, error) {
+	defer seed.Destroy()
+
+	var raw []byte
+
+	err := seed.WithPlaintext(func(plaintext []byte) error {
+		normalized := make([]byte, 0, len(plaintext))
+		for _, c := range plaintext {
+			switch {
+			case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '=':
+			case 'a' <= c && c <= 'z':
+				normalized = append(normalized, c-'a'+'A')
+			default:
+				normalized = append(normalized, c)
+			}
+		}
+
+		decoded := make([]byte, base32.StdEncoding.WithPadding(base32.NoPadding).DecodedLen(len(normalized)))
+
+		n, err := base32.StdEncoding.WithPadding(base32.NoPadding).Decode(decoded, normalized)
+
+		// WipeBytes securely erases the normalized copy of the seed.
+		memguard.WipeBytes(normalized)
+
+		if err != nil {
+			memguard.WipeBytes(decoded)
+			return ErrInvalidSeed
+		}
+
+		raw = decoded[:n]
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := m.NewSecretBytes(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	o, err := New(secret, opts...)
+	if err != nil {
+		secret.Destroy()
+		return nil, err
+	}
+
+	return o, nil
+}
+
+// HOTP returns the HOTP code for counter.
+func (o *OTP) HOTP(counter uint64) (string, error) {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], counter)
+
+	mac := o.seed.HMAC(o.hash, msg[:])
+	if mac == nil {
+		return "", m.ErrDestroyed
+	}
+
+	offset := mac[len(mac)-1] & 0x0f
+	value := uint64(binary.BigEndian.Uint32(mac[offset:offset+4]) & 0x7fffffff)
+
+	var modulus uint64 = 1
+	for range o.digits {
+		modulus *= 10
+	}
+
+	return fmt.Sprintf("%0*d", o.digits, value%modulus), nil
+}
+
+// TOTP returns the TOTP code for the time step containing t.
+func (o *OTP) TOTP(t time.Time) (string, error) {
+	return o.HOTP(o.counter(t))
+}
+
+// Now returns the TOTP code for the current time.
+func (o *OTP) Now() (string, error) {
+	return o.TOTP(time.Now())
+}
+
+// Verify reports whether code is the TOTP code for the time step containing t, or for
+// one of the adjacent time steps allowed by WithSkew. Codes are compared in constant
+// time.
+func (o *OTP) Verify(code string, t time.Time) bool {
+	counter := o.counter(t)
+
+	valid := 0
+	for delta := -int64(o.skew); delta <= int64(o.skew); delta++ {
+		if delta < 0 && uint64(-delta) > counter {
+			continue
+		}
+
+		want, err := o.HOTP(counter + uint64(delta))
+		if err != nil {
+			return false
+		}
+
+		valid |= subtle.ConstantTimeCompare([]byte(want), []byte(code))
+	}
+
+	return valid == 1
+}
+
+// Destroy destroys the shared secret. Subsequent calls return m.ErrDestroyed.
+func (o *OTP) Destroy() {
+	o.seed.Destroy()
+}
+
+// counter returns the TOTP time step containing t.
+func (o *OTP) counter(t time.Time) uint64 {
+	return uint64(t.Unix() / int64(o.period/time.Second))
+}
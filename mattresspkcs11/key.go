@@ -0,0 +1,312 @@
+package mattresspkcs11
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+
+	m "github.com/garrettladley/mattress"
+	"github.com/miekg/pkcs11"
+)
+
+// Key is a private key held by an HSM, implementing crypto.Signer and, for RSA keys,
+// crypto.Decrypter. The private key never leaves the token: every operation is
+// performed by the token itself.
+type Key struct {
+	hsm    *HSM                // hsm is the HSM holding the key
+	handle pkcs11.ObjectHandle // handle identifies the private key within the session
+	public crypto.PublicKey    // public is the public key, read from the token
+}
+
+// Key returns the RSA or ECDSA private key labelled label, whose public key must be
+// stored on the token under the same label. An error wrapping
+// mattress.ErrSecretNotFound is returned if either is missing.
+func (h *HSM) Key(label string) (*Key, error) {
+	key := &Key{hsm: h}
+
+	err := h.do(func(ctx *pkcs11.Ctx, session pkcs11.SessionHandle) error {
+		handle, err := find(ctx, session, pkcs11.CKO_PRIVATE_KEY, label)
+		if err != nil {
+			return err
+		}
+
+		public, err := find(ctx, session, pkcs11.CKO_PUBLIC_KEY, label)
+		if err != nil {
+			return err
+		}
+
+		key.handle = handle
+		if key.public, err = publicKey(ctx, session, public); err != nil {
+			return fmt.Errorf("mattresspkcs11: reading public key %q: %w", label, err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// Public returns the public key corresponding to the private key.
+func (k *Key) Public() crypto.PublicKey {
+	return k.public
+}
+
+// Sign signs digest with the private key on the token, as described by crypto.Signer.
+// RSA keys sign with PKCS #1 v1.5, or with PSS if opts is a *rsa.PSSOptions; ECDSA
+// signatures are ASN.1 encoded. rand is ignored, as the token supplies its own
+// randomness.
+func (k *Key) Sign(_ io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	hash := opts.HashFunc()
+	if hash != 0 && len(digest) != hash.Size() {
+		return nil, fmt.Errorf("mattresspkcs11: digest of %d bytes does not match %s", len(digest), hash)
+	}
+
+	var (
+		mechanism *pkcs11.Mechanism
+		data      = digest
+	)
+
+	switch k.public.(type) {
+	case *rsa.PublicKey:
+		if pss, ok := opts.(*rsa.PSSOptions); ok {
+			params, err := hashParams(hash)
+			if err != nil {
+				return nil, err
+			}
+
+			salt := pss.SaltLength
+			if salt <= 0 {
+				salt = hash.Size()
+			}
+
+			mechanism = pkcs11.NewMechanism(pkcs11.CKM_RSA_PKCS_PSS, pkcs11.NewPSSParams(params.mechanism, params.mgf, uint(salt)))
+			break
+		}
+
+		prefix, ok := digestInfoPrefixes[hash]
+		if !ok {
+			return nil, fmt.Errorf("mattresspkcs11: unsupported hash %s", hash)
+		}
+
+		mechanism = pkcs11.NewMechanism(pkcs11.CKM_RSA_PKCS, nil)
+		data = append(append([]byte{}, prefix...), digest...)
+	case *ecdsa.PublicKey:
+		mechanism = pkcs11.NewMechanism(pkcs11.CKM_ECDSA, nil)
+	}
+
+	var signature []byte
+
+	err := k.hsm.do(func(ctx *pkcs11.Ctx, session pkcs11.SessionHandle) error {
+		if err := ctx.SignInit(session, []*pkcs11.Mechanism{mechanism}, k.handle); err != nil {
+			return err
+		}
+
+		var err error
+		signature, err = ctx.Sign(session, data)
+
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("mattresspkcs11: signing: %w", err)
+	}
+
+	if _, ok := k.public.(*ecdsa.PublicKey); ok {
+		// The token returns the concatenation of r and s, each padded to the size of
+		// the curve, rather than their ASN.1 encoding.
+		half := len(signature) / 2
+
+		return asn1.Marshal(struct{ R, S *big.Int }{
+			R: new(big.Int).SetBytes(signature[:half]),
+			S: new(big.Int).SetBytes(signature[half:]),
+		})
+	}
+
+	return signature, nil
+}
+
+// Decrypt decrypts ciphertext with the private RSA key on the token, as described by
+// crypto.Decrypter, using OAEP if opts is a *rsa.OAEPOptions and PKCS #1 v1.5
+// otherwise. The plaintext is returned on the regular heap, as the interface requires;
+// use DecryptSecret to decrypt directly into a Secret.
+func (k *Key) Decrypt(_ io.Reader, ciphertext []byte, opts crypto.DecrypterOpts) ([]byte, error) {
+	if _, ok := k.public.(*rsa.PublicKey); !ok {
+		return nil, errors.New("mattresspkcs11: decryption requires an RSA key")
+	}
+
+	mechanism := pkcs11.NewMechanism(pkcs11.CKM_RSA_PKCS, nil)
+
+	if oaep, ok := opts.(*rsa.OAEPOptions); ok {
+		params, err := hashParams(oaep.Hash)
+		if err != nil {
+			return nil, err
+		}
+
+		mgf := params.mgf
+		if oaep.MGFHash != 0 {
+			mgfParams, err := hashParams(oaep.MGFHash)
+			if err != nil {
+				return nil, err
+			}
+			mgf = mgfParams.mgf
+		}
+
+		mechanism = pkcs11.NewMechanism(pkcs11.CKM_RSA_PKCS_OAEP, pkcs11.NewOAEPParams(params.mechanism, mgf, pkcs11.CKZ_DATA_SPECIFIED, oaep.Label))
+	}
+
+	var plaintext []byte
+
+	err := k.hsm.do(func(ctx *pkcs11.Ctx, session pkcs11.SessionHandle) error {
+		if err := ctx.DecryptInit(session, []*pkcs11.Mechanism{mechanism}, k.handle); err != nil {
+			return err
+		}
+
+		var err error
+		plaintext, err = ctx.Decrypt(session, ciphertext)
+
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("mattresspkcs11: decrypting: %w: %w", m.ErrDecryptionFailed, err)
+	}
+
+	return plaintext, nil
+}
+
+// DecryptSecret decrypts ciphertext, such as a data key wrapped for the public key, as
+// Decrypt does, and returns the plaintext as a Secret, wiping the intermediate copy.
+func (k *Key) DecryptSecret(ciphertext []byte, opts crypto.DecrypterOpts, secretOpts ...m.Option) (*m.Secret[[]byte], error) {
+	plaintext, err := k.Decrypt(nil, ciphertext, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// NewSecretBytes wipes the plaintext once it has been secured.
+	return m.NewSecretBytes(plaintext, secretOpts...)
+}
+
+// publicKey reads the RSA or ECDSA public key object at handle.
+func publicKey(ctx *pkcs11.Ctx, session pkcs11.SessionHandle, handle pkcs11.ObjectHandle) (crypto.PublicKey, error) {
+	attributes, err := ctx.GetAttributeValue(session, handle, []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_KEY_TYPE, nil),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	switch keyType := bytesToUint(attributes[0].Value); keyType {
+	case pkcs11.CKK_RSA:
+		attributes, err := ctx.GetAttributeValue(session, handle, []*pkcs11.Attribute{
+			pkcs11.NewAttribute(pkcs11.CKA_MODULUS, nil),
+			pkcs11.NewAttribute(pkcs11.CKA_PUBLIC_EXPONENT, nil),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(attributes[0].Value),
+			E: int(new(big.Int).SetBytes(attributes[1].Value).Int64()),
+		}, nil
+	case pkcs11.CKK_EC:
+		attributes, err := ctx.GetAttributeValue(session, handle, []*pkcs11.Attribute{
+			pkcs11.NewAttribute(pkcs11.CKA_EC_PARAMS, nil),
+			pkcs11.NewAttribute(pkcs11.CKA_EC_POINT, nil),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return ecdsaPublicKey(attributes[0].Value, attributes[1].Value)
+	default:
+		return nil, fmt.Errorf("unsupported key type %#x", keyType)
+	}
+}
+
+// oidPublicKeyECDSA identifies elliptic curve public keys in a SubjectPublicKeyInfo.
+var oidPublicKeyECDSA = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+
+// ecdsaPublicKey parses an ECDSA public key from the DER encoded curve parameters and
+// point of a PKCS#11 public key object, by assembling them into a SubjectPublicKeyInfo.
+func ecdsaPublicKey(params, point []byte) (crypto.PublicKey, error) {
+	// The point should be wrapped in a DER OCTET STRING, but some tokens return it
+	// unwrapped.
+	var unwrapped []byte
+	if rest, err := asn1.Unmarshal(point, &unwrapped); err == nil && len(rest) == 0 {
+		point = unwrapped
+	}
+
+	spki, err := asn1.Marshal(struct {
+		Algorithm pkix.AlgorithmIdentifier
+		PublicKey asn1.BitString
+	}{
+		Algorithm: pkix.AlgorithmIdentifier{
+			Algorithm:  oidPublicKeyECDSA,
+			Parameters: asn1.RawValue{FullBytes: params},
+		},
+		PublicKey: asn1.BitString{Bytes: point, BitLength: 8 * len(point)},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return x509.ParsePKIXPublicKey(spki)
+}
+
+// bytesToUint decodes a CK_ULONG attribute value, which is in native byte order and
+// four bytes long on Windows but eight elsewhere.
+func bytesToUint(b []byte) uint {
+	switch len(b) {
+	case 4:
+		return uint(binary.NativeEndian.Uint32(b))
+	case 8:
+		return uint(binary.NativeEndian.Uint64(b))
+	default:
+		return 0
+	}
+}
+
+// hashMechanism holds the PKCS#11 identifiers of a hash function.
+type hashMechanism struct {
+	mechanism uint // mechanism is the CKM_ identifier of the hash
+	mgf       uint // mgf is the CKG_ identifier of MGF1 with the hash
+}
+
+// hashParams returns the PKCS#11 identifiers of hash.
+func hashParams(hash crypto.Hash) (hashMechanism, error) {
+	switch hash {
+	case crypto.SHA1:
+		return hashMechanism{pkcs11.CKM_SHA_1, pkcs11.CKG_MGF1_SHA1}, nil
+	case crypto.SHA224:
+		return hashMechanism{pkcs11.CKM_SHA224, pkcs11.CKG_MGF1_SHA224}, nil
+	case crypto.SHA256:
+		return hashMechanism{pkcs11.CKM_SHA256, pkcs11.CKG_MGF1_SHA256}, nil
+	case crypto.SHA384:
+		return hashMechanism{pkcs11.CKM_SHA384, pkcs11.CKG_MGF1_SHA384}, nil
+	case crypto.SHA512:
+		return hashMechanism{pkcs11.CKM_SHA512, pkcs11.CKG_MGF1_SHA512}, nil
+	default:
+		return hashMechanism{}, fmt.Errorf("mattresspkcs11: unsupported hash %s", hash)
+	}
+}
+
+// digestInfoPrefixes are the DER encoded DigestInfo headers which precede digests
+// signed with PKCS #1 v1.5, as CKM_RSA_PKCS signs its input verbatim.
+var digestInfoPrefixes = map[crypto.Hash][]byte{
+	crypto.SHA1:   {0x30, 0x21, 0x30, 0x09, 0x06, 0x05, 0x2b, 0x0e, 0x03, 0x02, 0x1a, 0x05, 0x00, 0x04, 0x14},
+	crypto.SHA224: {0x30, 0x2d, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x04, 0x05, 0x00, 0x04, 0x1c},
+	crypto.SHA256: {0x30, 0x31, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x01, 0x05, 0x00, 0x04, 0x20},
+	crypto.SHA384: {0x30, 0x41, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x02, 0x05, 0x00, 0x04, 0x30},
+	crypto.SHA512: {0x30, 0x51, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x03, 0x05, 0x00, 0x04, 0x40},
+	0:             {}, // 0 signs a pre-encoded DigestInfo, as rsa.SignPKCS1v15 does
+}
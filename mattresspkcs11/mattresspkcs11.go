@@ -0,0 +1,189 @@
+// mattresspkcs11 uses keys held in a hardware security module (HSM), or any other
+// PKCS#11 token, for regulated environments in which private keys must never enter the
+// memory of the process at all.
+//
+// Private keys on the token are exposed as a crypto.Signer and crypto.Decrypter which
+// proxy every operation to the token, so they can be used wherever the standard library
+// accepts one, such as in a tls.Certificate. Data keys wrapped by the token are
+// unwrapped directly into mattress Secrets, either by decrypting them with an RSA key
+// or through a Wrapper backed by an AES key, for envelope encryption with Export and
+// ImportSecret.
+//
+// The package uses cgo to load the PKCS#11 module of the token.
+//
+// Example Usage:
+//
+//	import (
+//	  m "github.com/garrettladley/mattress"
+//	  "github.com/garrettladley/mattress/mattresspkcs11"
+//	)
+//
+//	func main() {
+//	  pin, err := m.NewSecretFromEnv("HSM_PIN")
+//	  if err != nil {
+//	    // handle error
+//	  }
+//
+//	  hsm, err := mattresspkcs11.Open("/usr/lib/softhsm/libsofthsm2.so", "payments", pin)
+//	  if err != nil {
+//	    // handle error
+//	  }
+//	  defer hsm.Close()
+//
+//	  key, err := hsm.Key("tls")
+//	  if err != nil {
+//	    // handle error
+//	  }
+//
+//	  certificate := tls.Certificate{Certificate: chain, PrivateKey: key}
+//	}
+package mattresspkcs11
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"unsafe"
+
+	m "github.com/garrettladley/mattress"
+	"github.com/miekg/pkcs11"
+)
+
+// HSM is a logged in session with a PKCS#11 token. The operations of an HSM, and of
+// the Keys and Wrappers obtained from it, are safe for concurrent use, but are
+// serialized, as a PKCS#11 session can only perform one operation at a time.
+type HSM struct {
+	ctx     *pkcs11.Ctx          // ctx is the loaded PKCS#11 module
+	session pkcs11.SessionHandle // session is the logged in session with the token
+	closed  bool                 // closed is whether Close has been called
+	lock    sync.Mutex           // synchronize access to the fields above and the session
+}
+
+// Open loads the PKCS#11 module at the path module, opens a session with the token
+// labelled token, and logs in to it as the normal user with pin. pin remains owned by
+// the caller and may be destroyed once Open returns. An error wrapping
+// mattress.ErrSecretNotFound is returned if no token is labelled token.
+func Open(module, token string, pin *m.Secret[string]) (*HSM, error) {
+	ctx := pkcs11.New(module)
+	if ctx == nil {
+		return nil, fmt.Errorf("mattresspkcs11: loading module %s", module)
+	}
+
+	if err := ctx.Initialize(); err != nil && !errors.Is(err, pkcs11.Error(pkcs11.CKR_CRYPTOKI_ALREADY_INITIALIZED)) {
+		ctx.Destroy()
+		return nil, fmt.Errorf("mattresspkcs11: initializing module: %w", err)
+	}
+
+	session, err := login(ctx, token, pin)
+	if err != nil {
+		ctx.Finalize()
+		ctx.Destroy()
+		return nil, err
+	}
+
+	return &HSM{ctx: ctx, session: session}, nil
+}
+
+// login opens a session with the token labelled token and logs in to it with pin.
+func login(ctx *pkcs11.Ctx, token string, pin *m.Secret[string]) (pkcs11.SessionHandle, error) {
+	slots, err := ctx.GetSlotList(true)
+	if err != nil {
+		return 0, fmt.Errorf("mattresspkcs11: listing slots: %w", err)
+	}
+
+	for _, slot := range slots {
+		info, err := ctx.GetTokenInfo(slot)
+		if err != nil || info.Label != token {
+			continue
+		}
+
+		session, err := ctx.OpenSession(slot, pkcs11.CKF_SERIAL_SESSION)
+		if err != nil {
+			return 0, fmt.Errorf("mattresspkcs11: opening session: %w", err)
+		}
+
+		err = pin.WithPlaintext(func(plaintext []byte) error {
+			return ctx.Login(session, pkcs11.CKU_USER, unsafe.String(unsafe.SliceData(plaintext), len(plaintext)))
+		})
+		if err != nil && !errors.Is(err, pkcs11.Error(pkcs11.CKR_USER_ALREADY_LOGGED_IN)) {
+			ctx.CloseSession(session)
+			return 0, fmt.Errorf("mattresspkcs11: logging in: %w", err)
+		}
+
+		return session, nil
+	}
+
+	return 0, fmt.Errorf("mattresspkcs11: token %q: %w", token, m.ErrSecretNotFound)
+}
+
+// Close logs out of the token, closes the session, and unloads the module. Keys and
+// Wrappers obtained from the HSM fail with mattress.ErrDestroyed once it is closed.
+func (h *HSM) Close() error {
+	h.lock.Lock()         // Lock before closing the session
+	defer h.lock.Unlock() // Ensure the lock is Unlocked when the method returns
+
+	if h.closed {
+		return nil
+	}
+
+	h.closed = true
+
+	// Logging out fails if the token was removed, in which case the session is closed
+	// regardless.
+	_ = h.ctx.Logout(h.session)
+
+	err := h.ctx.CloseSession(h.session)
+
+	if finalizeErr := h.ctx.Finalize(); err == nil {
+		err = finalizeErr
+	}
+
+	h.ctx.Destroy()
+
+	if err != nil {
+		return fmt.Errorf("mattresspkcs11: closing: %w", err)
+	}
+
+	return nil
+}
+
+// do calls fn with the session, serialized with every other operation of the HSM.
+func (h *HSM) do(fn func(ctx *pkcs11.Ctx, session pkcs11.SessionHandle) error) error {
+	h.lock.Lock()         // Lock before using the session
+	defer h.lock.Unlock() // Ensure the lock is Unlocked when the method returns
+
+	if h.closed {
+		return m.ErrDestroyed
+	}
+
+	return fn(h.ctx, h.session)
+}
+
+// find returns the object of class labelled label, or an error wrapping
+// mattress.ErrSecretNotFound if there is none.
+func find(ctx *pkcs11.Ctx, session pkcs11.SessionHandle, class uint, label string) (pkcs11.ObjectHandle, error) {
+	template := []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, class),
+		pkcs11.NewAttribute(pkcs11.CKA_LABEL, label),
+	}
+
+	if err := ctx.FindObjectsInit(session, template); err != nil {
+		return 0, fmt.Errorf("mattresspkcs11: finding key %q: %w", label, err)
+	}
+
+	objects, _, err := ctx.FindObjects(session, 1)
+
+	if finalErr := ctx.FindObjectsFinal(session); err == nil {
+		err = finalErr
+	}
+
+	if err != nil {
+		return 0, fmt.Errorf("mattresspkcs11: finding key %q: %w", label, err)
+	}
+
+	if len(objects) == 0 {
+		return 0, fmt.Errorf("mattresspkcs11: key %q: %w", label, m.ErrSecretNotFound)
+	}
+
+	return objects[0], nil
+}
@@ -0,0 +1,108 @@
+package mattresspkcs11
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+
+	m "github.com/garrettladley/mattress"
+	"github.com/miekg/pkcs11"
+)
+
+// gcmNonceSize and gcmTagBits are the sizes of the nonce and tag of the AES-GCM
+// encryption used by Wrapper.
+const (
+	gcmNonceSize = 12
+	gcmTagBits   = 128
+)
+
+// Wrapper is a mattress.Wrapper which wraps data keys with AES-GCM under an AES key
+// held by an HSM, so that the key encryption key never leaves the token.
+type Wrapper struct {
+	hsm    *HSM                // hsm is the HSM holding the key encryption key
+	handle pkcs11.ObjectHandle // handle identifies the key encryption key within the session
+}
+
+// Wrapper returns a Wrapper for the AES key labelled label, returning an error wrapping
+// mattress.ErrSecretNotFound if there is none.
+func (h *HSM) Wrapper(label string) (*Wrapper, error) {
+	w := &Wrapper{hsm: h}
+
+	err := h.do(func(ctx *pkcs11.Ctx, session pkcs11.SessionHandle) error {
+		handle, err := find(ctx, session, pkcs11.CKO_SECRET_KEY, label)
+		w.handle = handle
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// WrapKey implements mattress.Wrapper, encrypting key on the token. The wrapped key is
+// the nonce followed by the ciphertext and tag.
+func (w *Wrapper) WrapKey(_ context.Context, key *m.Secret[[]byte]) ([]byte, error) {
+	nonce := make([]byte, gcmNonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	var wrapped []byte
+
+	err := key.WithExposed(func(plaintext []byte) error {
+		return w.hsm.do(func(ctx *pkcs11.Ctx, session pkcs11.SessionHandle) error {
+			params := pkcs11.NewGCMParams(nonce, nil, gcmTagBits)
+			defer params.Free()
+
+			if err := ctx.EncryptInit(session, []*pkcs11.Mechanism{pkcs11.NewMechanism(pkcs11.CKM_AES_GCM, params)}, w.handle); err != nil {
+				return err
+			}
+
+			ciphertext, err := ctx.Encrypt(session, plaintext)
+			if err != nil {
+				return err
+			}
+
+			// Some tokens ignore the nonce they are given and generate their own.
+			wrapped = append(params.IV(), ciphertext...)
+
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("mattresspkcs11: wrapping key: %w", err)
+	}
+
+	return wrapped, nil
+}
+
+// UnwrapKey implements mattress.Wrapper, decrypting a key wrapped by WrapKey on the
+// token and securing it as soon as it is returned.
+func (w *Wrapper) UnwrapKey(_ context.Context, wrapped []byte) (*m.Secret[[]byte], error) {
+	if len(wrapped) < gcmNonceSize+gcmTagBits/8 {
+		return nil, m.ErrDecryptionFailed
+	}
+
+	var plaintext []byte
+
+	err := w.hsm.do(func(ctx *pkcs11.Ctx, session pkcs11.SessionHandle) error {
+		params := pkcs11.NewGCMParams(wrapped[:gcmNonceSize], nil, gcmTagBits)
+		defer params.Free()
+
+		if err := ctx.DecryptInit(session, []*pkcs11.Mechanism{pkcs11.NewMechanism(pkcs11.CKM_AES_GCM, params)}, w.handle); err != nil {
+			return err
+		}
+
+		var err error
+		plaintext, err = ctx.Decrypt(session, wrapped[gcmNonceSize:])
+
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("mattresspkcs11: unwrapping key: %w: %w", m.ErrDecryptionFailed, err)
+	}
+
+	// NewSecretBytes wipes the plaintext once it has been secured.
+	return m.NewSecretBytes(plaintext)
+}
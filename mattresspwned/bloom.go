@@ -0,0 +1,197 @@
+package mattresspwned
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/awnumar/memguard"
+	m "github.com/garrettladley/mattress"
+)
+
+// bloomMagic prefixes BloomFilters written by WriteTo, identifying the format and its
+// version.
+var bloomMagic = [5]byte{'M', 'T', 'R', 'B', 1}
+
+// BloomFilter is a probabilistic set of known bad passwords, held by their SHA-1
+// hashes, answering whether a password is in the set offline. It never misses a
+// password which was added, but reports a small fraction of others as present, at a
+// rate chosen when it is created. A BloomFilter is not safe for concurrent use while
+// passwords are being added.
+type BloomFilter struct {
+	bits   []uint64 // bits holds the bits of the filter
+	hashes uint32   // hashes is the number of bits set for each password
+}
+
+// NewBloomFilter returns an empty BloomFilter sized to hold n passwords while falsely
+// reporting others as present at the rate falsePositiveRate, such as 0.001.
+func NewBloomFilter(n int, falsePositiveRate float64) (*BloomFilter, error) {
+	if n < 1 || falsePositiveRate <= 0 || falsePositiveRate >= 1 {
+		return nil, fmt.Errorf("mattresspwned: invalid bloom filter parameters n=%d, rate=%g", n, falsePositiveRate)
+	}
+
+	bits := math.Ceil(-float64(n) * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2))
+	hashes := max(1, math.Round(bits/float64(n)*math.Ln2))
+
+	return &BloomFilter{
+		bits:   make([]uint64, (uint64(bits)+63)/64),
+		hashes: uint32(hashes),
+	}, nil
+}
+
+// Add adds password to the filter.
+func (f *BloomFilter) Add(password []byte) {
+	sum := sha1.Sum(password)
+	defer memguard.WipeBytes(sum[:])
+
+	f.AddHash(sum)
+}
+
+// AddHash adds the password whose SHA-1 hash is sum to the filter, so that a filter
+// can be built from hashes, such as those published by Have I Been Pwned, without the
+// passwords themselves.
+func (f *BloomFilter) AddHash(sum [sha1.Size]byte) {
+	for i := range f.hashes {
+		index := f.index(sum, i)
+		f.bits[index/64] |= 1 << (index % 64)
+	}
+}
+
+// AddHashes adds every SHA-1 hash read from r, one per line in hexadecimal, to the
+// filter. Anything following a colon on a line, such as the counts in the files
+// published by Have I Been Pwned, is ignored.
+func (f *BloomFilter) AddHashes(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+
+	for line := 1; scanner.Scan(); line++ {
+		text, _, _ := bytes.Cut(bytes.TrimSpace(scanner.Bytes()), []byte(":"))
+		if len(text) == 0 {
+			continue
+		}
+
+		var sum [sha1.Size]byte
+		if n, err := hex.Decode(sum[:], text); err != nil || n != sha1.Size {
+			return fmt.Errorf("mattresspwned: line %d: malformed SHA-1 hash", line)
+		}
+
+		f.AddHash(sum)
+	}
+
+	return scanner.Err()
+}
+
+// Contains reports whether the textual form of the plaintext of secret, as passed to
+// WithPlaintext, may be in the filter. Nothing leaves the process.
+func (f *BloomFilter) Contains(secret m.Redactable) (bool, error) {
+	var found bool
+
+	err := secret.WithPlaintext(func(plaintext []byte) error {
+		found = f.contains(plaintext)
+		return nil
+	})
+
+	return found, err
+}
+
+// Validator returns a mattress.Validator, for use with Secret.Validate, which rejects
+// passwords which may be in the filter.
+func (f *BloomFilter) Validator() m.Validator {
+	return func(plaintext []byte) error {
+		if f.contains(plaintext) {
+			return &m.ValidationError{Rule: "known-password", Reason: "is a known compromised or weak password"}
+		}
+		return nil
+	}
+}
+
+// contains reports whether password may be in the filter.
+func (f *BloomFilter) contains(password []byte) bool {
+	sum := sha1.Sum(password)
+	defer memguard.WipeBytes(sum[:])
+
+	for i := range f.hashes {
+		index := f.index(sum, i)
+		if f.bits[index/64]&(1<<(index%64)) == 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// index returns the bit set for the ith hash of the password whose SHA-1 hash is sum,
+// deriving every hash from two halves of sum by double hashing.
+func (f *BloomFilter) index(sum [sha1.Size]byte, i uint32) uint64 {
+	h1 := binary.BigEndian.Uint64(sum[0:8])
+	h2 := binary.BigEndian.Uint64(sum[8:16]) | 1
+
+	return (h1 + uint64(i)*h2) % (uint64(len(f.bits)) * 64)
+}
+
+// WriteTo writes the filter to w, in a form read by ReadBloomFilter.
+func (f *BloomFilter) WriteTo(w io.Writer) (int64, error) {
+	header := make([]byte, 0, len(bloomMagic)+4+8)
+	header = append(header, bloomMagic[:]...)
+	header = binary.BigEndian.AppendUint32(header, f.hashes)
+	header = binary.BigEndian.AppendUint64(header, uint64(len(f.bits)))
+
+	n, err := w.Write(header)
+	written := int64(n)
+	if err != nil {
+		return written, err
+	}
+
+	bw := bufio.NewWriter(w)
+
+	var word [8]byte
+	for _, bits := range f.bits {
+		binary.BigEndian.PutUint64(word[:], bits)
+
+		n, err := bw.Write(word[:])
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+	}
+
+	return written, bw.Flush()
+}
+
+// ReadBloomFilter reads a filter written by WriteTo from r.
+func ReadBloomFilter(r io.Reader) (*BloomFilter, error) {
+	header := make([]byte, len(bloomMagic)+4+8)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("mattresspwned: reading bloom filter: %w", err)
+	}
+
+	if !bytes.HasPrefix(header, bloomMagic[:]) {
+		return nil, errors.New("mattresspwned: not a bloom filter")
+	}
+
+	hashes := binary.BigEndian.Uint32(header[len(bloomMagic):])
+	words := binary.BigEndian.Uint64(header[len(bloomMagic)+4:])
+
+	if hashes == 0 || words == 0 {
+		return nil, errors.New("mattresspwned: malformed bloom filter")
+	}
+
+	f := &BloomFilter{hashes: hashes}
+
+	br := bufio.NewReader(r)
+
+	var word [8]byte
+	for range words {
+		if _, err := io.ReadFull(br, word[:]); err != nil {
+			return nil, fmt.Errorf("mattresspwned: reading bloom filter: %w", err)
+		}
+		f.bits = append(f.bits, binary.BigEndian.Uint64(word[:]))
+	}
+
+	return f, nil
+}
@@ -0,0 +1,201 @@
+// mattresspwned reports whether credentials held in mattress Secrets are known to have
+// been leaked, without exposing them beyond the process.
+//
+// A Client queries the Have I Been Pwned Pwned Passwords range API using
+// k-anonymity: only the first five hexadecimal characters of the SHA-1 hash of the
+// password leave the process, and the match against the returned suffixes is made
+// locally. Requests are padded so that the size of the response reveals nothing
+// either. Where network access is unavailable or undesirable, a BloomFilter built from
+// a list of known bad passwords, or from the hashes published by Have I Been Pwned,
+// answers the same question offline.
+//
+// Example Usage:
+//
+//	import (
+//	  m "github.com/garrettladley/mattress"
+//	  "github.com/garrettladley/mattress/mattresspwned"
+//	)
+//
+//	func main() {
+//	  password, err := m.NewSecretString(input)
+//	  if err != nil {
+//	    // handle error
+//	  }
+//	  defer password.Destroy()
+//
+//	  client := mattresspwned.NewClient()
+//
+//	  err = password.Validate(m.MinLength(12), client.Validator(ctx))
+//	  if errors.Is(err, m.ErrValidationFailed) {
+//	    // reject the password
+//	  }
+//	}
+package mattresspwned
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/awnumar/memguard"
+	m "github.com/garrettladley/mattress"
+)
+
+// DefaultAddr is the address of the Pwned Passwords API.
+const DefaultAddr = "https://api.pwnedpasswords.com"
+
+// prefixLen is the number of hexadecimal characters of the hash sent to the API.
+const prefixLen = 5
+
+// Client checks passwords against the Pwned Passwords range API.
+type Client struct {
+	addr string       // addr is the address of the Pwned Passwords API
+	http *http.Client // http performs requests to the API
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithAddr sets the address of the Pwned Passwords API, such as that of a self-hosted
+// mirror. It defaults to DefaultAddr.
+func WithAddr(addr string) Option {
+	return func(c *Client) {
+		c.addr = strings.TrimRight(addr, "/")
+	}
+}
+
+// WithHTTPClient sets the http.Client used to make requests to the API. It defaults to
+// http.DefaultClient.
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *Client) {
+		c.http = client
+	}
+}
+
+// NewClient returns a Client for the Pwned Passwords API.
+func NewClient(opts ...Option) *Client {
+	c := &Client{
+		addr: DefaultAddr,
+		http: http.DefaultClient,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// Count returns the number of times the textual form of the plaintext of secret, as
+// passed to WithPlaintext, appears in the breaches known to Have I Been Pwned, or zero
+// if it does not. Only the first five characters of its SHA-1 hash are sent.
+func (c *Client) Count(ctx context.Context, secret m.Redactable) (int, error) {
+	var hash [sha1.Size * 2]byte
+
+	// WipeBytes securely erases the hash, from which weak passwords can be recovered,
+	// once it has been checked.
+	defer memguard.WipeBytes(hash[:])
+
+	if err := secret.WithPlaintext(func(plaintext []byte) error {
+		sum := sha1.Sum(plaintext)
+		defer memguard.WipeBytes(sum[:])
+
+		hex.Encode(hash[:], sum[:])
+
+		// The API returns hashes in upper case.
+		for i, c := range hash {
+			if c >= 'a' {
+				hash[i] = c - 'a' + 'A'
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+
+	prefix, suffix := hash[:prefixLen], hash[prefixLen:]
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.addr+"/range/"+string(prefix), nil)
+	if err != nil {
+		return 0, err
+	}
+
+	// Padding hides the number of suffixes sharing the prefix from observers.
+	req.Header.Set("Add-Padding", "true")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("mattresspwned: unexpected status %d", resp.StatusCode)
+	}
+
+	count := 0
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		candidate, occurrences, ok := bytes.Cut(bytes.TrimSpace(scanner.Bytes()), []byte(":"))
+		if !ok {
+			continue
+		}
+
+		if subtle.ConstantTimeCompare(candidate, suffix) == 1 {
+			// Padding entries have a count of zero, reporting the password as not found.
+			count, err = strconv.Atoi(string(occurrences))
+			if err != nil {
+				return 0, fmt.Errorf("mattresspwned: malformed response: %w", err)
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// Pwned reports whether the plaintext of secret appears in any breach known to Have I
+// Been Pwned, as described by Count.
+func (c *Client) Pwned(ctx context.Context, secret m.Redactable) (bool, error) {
+	count, err := c.Count(ctx, secret)
+	return count > 0, err
+}
+
+// Validator returns a mattress.Validator, for use with Secret.Validate, which rejects
+// passwords appearing in any breach known to Have I Been Pwned. Requests are made with
+// ctx while the Secret is exposed, and a failed request fails validation with its own
+// error.
+func (c *Client) Validator(ctx context.Context) m.Validator {
+	return func(plaintext []byte) error {
+		count, err := c.Count(ctx, plaintextOf(plaintext))
+		if err != nil {
+			return err
+		}
+
+		if count > 0 {
+			return &m.ValidationError{Rule: "pwned", Reason: fmt.Sprintf("appears in %d known breaches", count)}
+		}
+
+		return nil
+	}
+}
+
+// plaintextOf adapts plaintext already exposed to a Validator to a mattress.Redactable.
+type plaintextOf []byte
+
+// WithPlaintext implements mattress.Redactable.
+func (p plaintextOf) WithPlaintext(fn func(plaintext []byte) error) error {
+	return fn(p)
+}
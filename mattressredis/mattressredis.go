@@ -0,0 +1,62 @@
+// mattressredis supplies passwords held in mattress Secrets to go-redis clients.
+//
+// The password is read afresh whenever the client opens a connection, so a password
+// held by a Rotator, or any other source refreshed in the background, is picked up by
+// reconnects without recreating the client.
+//
+// Example Usage:
+//
+//	import (
+//	  m "github.com/garrettladley/mattress"
+//	  "github.com/garrettladley/mattress/mattressredis"
+//	)
+//
+//	func main() {
+//	  password, err := m.NewSecretFromEnv("REDIS_PASSWORD")
+//	  if err != nil {
+//	    // handle error
+//	  }
+//
+//	  client := redis.NewClient(&redis.Options{
+//	    Addr:                       "cache:6379",
+//	    CredentialsProviderContext: mattressredis.CredentialsProvider("app", password),
+//	  })
+//	}
+package mattressredis
+
+import (
+	"context"
+	"strings"
+)
+
+// Password is a source of the Redis password. It is implemented by *m.Secret[string],
+// *m.Rotator[string], and *mattressvault.LeasedSecret, among others.
+type Password interface {
+	// WithExposed calls fn with the current password, which must not be retained.
+	WithExposed(fn func(password string) error) error
+}
+
+// CredentialsProvider returns a function suitable for the CredentialsProviderContext
+// field of redis.Options, redis.ClusterOptions, redis.FailoverOptions, and
+// redis.UniversalOptions, which authenticates each new connection as username with the
+// current password. An empty username authenticates as the default user.
+//
+// go-redis holds the password it is given in an ordinary string only for the duration
+// of the AUTH handshake.
+func CredentialsProvider(username string, password Password) func(ctx context.Context) (string, string, error) {
+	return func(context.Context) (string, string, error) {
+		var p string
+
+		err := password.WithExposed(func(exposed string) error {
+			// The exposed string is wiped when fn returns, so go-redis is given its own
+			// copy.
+			p = strings.Clone(exposed)
+			return nil
+		})
+		if err != nil {
+			return "", "", err
+		}
+
+		return username, p, nil
+	}
+}
@@ -0,0 +1,160 @@
+// mattresssql provides a database/sql driver.Connector which keeps the database
+// password in a mattress Secret.
+//
+// The DSN is assembled only within Connect, for the duration of the driver handshake,
+// and wiped as soon as the connection has been established. The password is read
+// afresh for every new connection, so a password held by a Rotator, or any other
+// source which is refreshed in the background, is picked up by the pool without
+// reopening the sql.DB.
+//
+// For pgx and the MySQL driver, which accept their configuration as a struct rather
+// than a DSN, BeforeConnect and NewMySQLConnector set the password of each new
+// connection through the drivers' own hooks.
+//
+// Example Usage:
+//
+//	import (
+//	  m "github.com/garrettladley/mattress"
+//	  "github.com/garrettladley/mattress/mattresssql"
+//	)
+//
+//	func main() {
+//	  password, err := m.NewSecretFromEnv("DB_PASSWORD")
+//	  if err != nil {
+//	    // handle error
+//	  }
+//
+//	  dsn := mattresssql.URL(&url.URL{Scheme: "postgres", User: url.User("app"), Host: "db:5432", Path: "/app"})
+//
+//	  db := sql.OpenDB(mattresssql.NewConnector(&pq.Driver{}, dsn, password))
+//	  defer db.Close()
+//	}
+package mattresssql
+
+import (
+	"context"
+	"database/sql/driver"
+	"net/url"
+	"strings"
+	"unsafe"
+
+	"github.com/awnumar/memguard"
+)
+
+// Password is a source of the database password. It is implemented by
+// *m.Secret[string], *m.Rotator[string], and *mattressvault.LeasedSecret, among others.
+type Password interface {
+	// WithExposed calls fn with the current password, which must not be retained.
+	WithExposed(fn func(password string) error) error
+}
+
+// DSNFunc appends the DSN for a connection authenticated with password to dst and
+// returns the extended buffer. It must not retain password or the buffer.
+type DSNFunc func(dst []byte, password string) []byte
+
+// URL returns a DSNFunc for drivers which accept a URL as their DSN, such as lib/pq and
+// pgx. The password is inserted into the userinfo of u, escaped as required; any
+// password u already holds is ignored.
+func URL(u *url.URL) DSNFunc {
+	rest := *u
+	rest.Scheme = ""
+	rest.User = nil
+
+	// Everything following the userinfo is fixed, so it is only rendered once.
+	host := strings.TrimPrefix(rest.String(), "//")
+
+	var user string
+	if u.User != nil {
+		user = url.User(u.User.Username()).String()
+	}
+
+	return func(dst []byte, password string) []byte {
+		dst = append(dst, u.Scheme...)
+		dst = append(dst, "://"...)
+		dst = append(dst, user...)
+		dst = append(dst, ':')
+		dst = appendEscaped(dst, password)
+		dst = append(dst, '@')
+		dst = append(dst, host...)
+
+		return dst
+	}
+}
+
+// appendEscaped appends s to dst with every byte other than the unreserved characters
+// of RFC 3986 percent-encoded, as is safe within the userinfo of a URL.
+func appendEscaped(dst []byte, s string) []byte {
+	const hex = "0123456789ABCDEF"
+
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9',
+			c == '-', c == '.', c == '_', c == '~':
+			dst = append(dst, c)
+		default:
+			dst = append(dst, '%', hex[c>>4], hex[c&0xf])
+		}
+	}
+
+	return dst
+}
+
+// Connector is a driver.Connector which assembles its DSN, including the password,
+// only for the duration of each connection attempt.
+type Connector struct {
+	driver   driver.Driver // driver opens the connections
+	dsn      DSNFunc       // dsn assembles the DSN
+	password Password      // password is read afresh for every connection
+}
+
+// NewConnector returns a Connector which opens connections with drv using the DSN built
+// by dsn from the current password. Pass the Connector to sql.OpenDB.
+//
+// The DSN is wiped once the driver has returned from its handshake, so drv must not
+// retain the DSN, or strings sliced from it, beyond the connection attempt. Most
+// drivers parse the DSN into their own configuration; check that yours copies the
+// password before relying on this.
+func NewConnector(drv driver.Driver, dsn DSNFunc, password Password) *Connector {
+	return &Connector{driver: drv, dsn: dsn, password: password}
+}
+
+// Connect exposes the current password, assembles the DSN, and opens a connection
+// with it, wiping the DSN once the driver returns.
+func (c *Connector) Connect(ctx context.Context) (driver.Conn, error) {
+	var conn driver.Conn
+
+	err := c.password.WithExposed(func(password string) error {
+		dsn := c.dsn(nil, password)
+
+		// WipeBytes securely erases the DSN once the handshake is complete.
+		defer memguard.WipeBytes(dsn)
+
+		// The DSN is passed to the driver without copying it into an ordinary string,
+		// so that the only copy is the one wiped above.
+		name := unsafe.String(unsafe.SliceData(dsn), len(dsn))
+
+		var err error
+
+		if dc, ok := c.driver.(driver.DriverContext); ok {
+			var connector driver.Connector
+
+			connector, err = dc.OpenConnector(name)
+			if err != nil {
+				return err
+			}
+
+			conn, err = connector.Connect(ctx)
+		} else {
+			conn, err = c.driver.Open(name)
+		}
+
+		return err
+	})
+
+	return conn, err
+}
+
+// Driver returns the driver the Connector opens connections with.
+func (c *Connector) Driver() driver.Driver {
+	return c.driver
+}
@@ -0,0 +1,40 @@
+package mattresssql
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// MySQLBeforeConnect returns a mysql.Option which sets the password of each new
+// connection from password as it is made, for use with mysql.Config.Apply. Pairing it
+// with a Rotator, or a source of short-lived IAM credentials, keeps the pool
+// authenticating with the current password without reopening the sql.DB.
+//
+// The driver holds the password of each connection's configuration in an ordinary
+// string, so it remains in memory for the lifetime of the connection.
+func MySQLBeforeConnect(password Password) mysql.Option {
+	return mysql.BeforeConnect(func(_ context.Context, cfg *mysql.Config) error {
+		return password.WithExposed(func(p string) error {
+			// The exposed string is wiped when fn returns, so the driver is given its own
+			// copy.
+			cfg.Passwd = strings.Clone(p)
+			return nil
+		})
+	})
+}
+
+// NewMySQLConnector returns a driver.Connector which opens connections as described by
+// cfg, with the password of each taken from password as described by
+// MySQLBeforeConnect. cfg is not modified. Pass the Connector to sql.OpenDB.
+func NewMySQLConnector(cfg *mysql.Config, password Password) (driver.Connector, error) {
+	cfg = cfg.Clone()
+
+	if err := cfg.Apply(MySQLBeforeConnect(password)); err != nil {
+		return nil, err
+	}
+
+	return mysql.NewConnector(cfg)
+}
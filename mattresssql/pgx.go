@@ -0,0 +1,26 @@
+package mattresssql
+
+import (
+	"context"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// BeforeConnect returns a hook for pgxpool.Config.BeforeConnect, or for
+// stdlib.OptionBeforeConnect, which sets the password of each new pgx connection from
+// password as it is made. Pairing it with a Rotator, or a source of short-lived IAM
+// credentials, keeps the pool authenticating with the current password without
+// recreating it.
+//
+// pgx holds the password of each connection's configuration in an ordinary string, so
+// it remains in memory for the lifetime of the connection.
+func BeforeConnect(password Password) func(ctx context.Context, cfg *pgx.ConnConfig) error {
+	return func(_ context.Context, cfg *pgx.ConnConfig) error {
+		return password.WithExposed(func(p string) error {
+			// The exposed string is wiped when fn returns, so pgx is given its own copy.
+			cfg.Password = strings.Clone(p)
+			return nil
+		})
+	}
+}
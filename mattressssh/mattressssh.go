@@ -0,0 +1,121 @@
+// mattressssh provides ssh.Signer implementations whose private keys live in mattress
+// Secrets, for use by SSH clients and agents built on golang.org/x/crypto/ssh.
+//
+// Private keys in OpenSSH or PEM form are parsed once, re-sealed as a
+// mattresscrypto.Signer, and thereafter only unwrapped for the duration of each
+// signature.
+//
+// Example Usage:
+//
+//	import (
+//	  m "github.com/garrettladley/mattress"
+//	  "github.com/garrettladley/mattress/mattressssh"
+//	)
+//
+//	func main() {
+//	  key, err := m.NewSecretFromFile("/home/deploy/.ssh/id_ed25519", m.WithStrictPermissions())
+//	  if err != nil {
+//	    // handle error
+//	  }
+//
+//	  signer, err := mattressssh.NewSigner(key)
+//	  if err != nil {
+//	    // handle error
+//	  }
+//	  defer signer.Destroy()
+//
+//	  config := &ssh.ClientConfig{
+//	    Auth: []ssh.AuthMethod{ssh.PublicKeys(signer)},
+//	  }
+//	}
+package mattressssh
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"errors"
+
+	m "github.com/garrettladley/mattress"
+	"github.com/garrettladley/mattress/mattresscrypto"
+	"golang.org/x/crypto/ssh"
+)
+
+// Signer is an ssh.Signer whose private key is held in a mattress Secret. It also
+// implements ssh.MultiAlgorithmSigner, so RSA keys can sign with SHA-2 algorithms.
+type Signer struct {
+	ssh.MultiAlgorithmSigner
+	key *mattresscrypto.Signer // key holds the sealed private key
+}
+
+// NewSigner parses the OpenSSH or PEM encoded private key held by key and returns a
+// Signer for it. key is destroyed once the private key has been re-sealed.
+func NewSigner(key *m.Secret[[]byte]) (*Signer, error) {
+	return newSigner(key, func(b []byte) (any, error) {
+		return ssh.ParseRawPrivateKey(b)
+	})
+}
+
+// NewSignerWithPassphrase parses the passphrase protected OpenSSH or PEM encoded
+// private key held by key and returns a Signer for it. key is destroyed once the
+// private key has been re-sealed; passphrase remains owned by the caller.
+func NewSignerWithPassphrase(key *m.Secret[[]byte], passphrase *m.Secret[[]byte]) (*Signer, error) {
+	return newSigner(key, func(b []byte) (any, error) {
+		var parsed any
+
+		err := passphrase.WithExposed(func(p []byte) error {
+			var err error
+			parsed, err = ssh.ParseRawPrivateKeyWithPassphrase(b, p)
+			return err
+		})
+
+		return parsed, err
+	})
+}
+
+// newSigner exposes key to parse, re-seals the parsed private key, and wraps it in a
+// Signer.
+func newSigner(key *m.Secret[[]byte], parse func([]byte) (any, error)) (*Signer, error) {
+	defer key.Destroy()
+
+	var signer *mattresscrypto.Signer
+
+	err := key.WithExposed(func(b []byte) error {
+		parsed, err := parse(b)
+		if err != nil {
+			return err
+		}
+
+		// ssh returns Ed25519 keys by pointer, whereas crypto/x509 expects a value.
+		if k, ok := parsed.(*ed25519.PrivateKey); ok {
+			parsed = *k
+		}
+
+		// NewSigner wipes the parsed key once it has been re-sealed.
+		signer, err = mattresscrypto.NewSigner(crypto.PrivateKey(parsed))
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	wrapped, err := ssh.NewSignerFromSigner(signer)
+	if err != nil {
+		signer.Destroy()
+		return nil, err
+	}
+
+	multi, ok := wrapped.(ssh.MultiAlgorithmSigner)
+	if !ok {
+		signer.Destroy()
+		return nil, errors.New("mattressssh: signer does not support algorithm selection")
+	}
+
+	return &Signer{MultiAlgorithmSigner: multi, key: signer}, nil
+}
+
+// Destroy destroys the Secret holding the private key. The Signer cannot be used
+// afterwards.
+func (s *Signer) Destroy() {
+	s.key.Destroy()
+}
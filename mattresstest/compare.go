@@ -0,0 +1,83 @@
+package mattresstest
+
+import (
+	"crypto/rand"
+	"fmt"
+	"reflect"
+
+	m "github.com/garrettladley/mattress"
+	"github.com/google/go-cmp/cmp"
+)
+
+// fingerprinter is implemented by every *m.Secret.
+type fingerprinter interface {
+	KeyedFingerprint(key []byte) (m.Fingerprint, error)
+}
+
+// key keys the fingerprints reported by Comparer, so that they cannot be used to
+// confirm guesses of the plaintext.
+var key = func() []byte {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(err)
+	}
+	return key
+}()
+
+// Comparer returns a cmp.Option which compares Secrets of any type by the fingerprint
+// of their plaintext, rather than by their unexported fields, so that cmp.Equal and
+// cmp.Diff can be used on values containing Secrets. Each Secret is transformed into
+// its redacted form followed by an HMAC of its plaintext under a key random to the
+// process, which is all cmp.Diff ever reports; cmp never reflects into the Secret
+// itself, which would otherwise fault on its guard pages. Two nil Secrets are equal,
+// as are two Secrets which cannot be exposed for the same reason, such as both having
+// been destroyed.
+func Comparer() cmp.Option {
+	return cmp.Transformer("mattresstest.Redact", func(s fingerprinter) string {
+		if isNil(s) {
+			return "<nil>"
+		}
+
+		f, err := s.KeyedFingerprint(key)
+		if err != nil {
+			return fmt.Sprintf("%v (%v)", s, err)
+		}
+
+		return fmt.Sprintf("%v (hmac %s)", s, f)
+	})
+}
+
+// isNil reports whether f holds a nil pointer.
+func isNil(f fingerprinter) bool {
+	if f == nil {
+		return true
+	}
+
+	v := reflect.ValueOf(f)
+
+	return v.Kind() == reflect.Pointer && v.IsNil()
+}
+
+// Equal reports whether expected and actual are deeply equal, comparing any Secrets
+// they contain as described by Comparer and comparing unexported fields. Its signature
+// matches testify's assert.ObjectsAreEqual, so it can stand in for it:
+//
+//	assert.Truef(t, mattresstest.Equal(want, got), "mismatch (-want +got):\n%s", mattresstest.Diff(want, got))
+func Equal(expected, actual any) bool {
+	return cmp.Equal(expected, actual, options()...)
+}
+
+// Diff returns a human-readable report of the differences between expected and actual,
+// as cmp.Diff does, comparing any Secrets they contain as described by Comparer. It
+// returns the empty string if they are equal.
+func Diff(expected, actual any) string {
+	return cmp.Diff(expected, actual, options()...)
+}
+
+// options returns the cmp.Options used by Equal and Diff.
+func options() []cmp.Option {
+	return []cmp.Option{
+		Comparer(),
+		cmp.Exporter(func(reflect.Type) bool { return true }),
+	}
+}
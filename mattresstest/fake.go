@@ -0,0 +1,78 @@
+package mattresstest
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"sync"
+
+	m "github.com/garrettladley/mattress"
+)
+
+// Fake is an in-memory implementation of mattress.SecretValue for injecting into code
+// under test. It holds its data on the regular heap, offering none of the protections
+// of a Secret, but allocates no locked pages, so it may be used freely in environments
+// with a low memlock limit. It records whether it has been destroyed, for assertions
+// that code under test destroys the Secrets it owns.
+type Fake[T any] struct {
+	data      T          // data is the value held by the Fake
+	destroyed bool       // destroyed reports whether Destroy has been called
+	lock      sync.Mutex // synchronize access to the fields above
+}
+
+// NewFake returns a Fake holding value.
+func NewFake[T any](value T) *Fake[T] {
+	return &Fake[T]{data: value}
+}
+
+// Expose returns the value held by the Fake, or the zero value of T once destroyed.
+func (f *Fake[T]) Expose() T {
+	f.lock.Lock()         // Lock before reading the data
+	defer f.lock.Unlock() // Ensure the lock is Unlocked when the method returns
+
+	return f.data
+}
+
+// Destroy resets the value held by the Fake to the zero value of T and marks it
+// destroyed.
+func (f *Fake[T]) Destroy() {
+	f.lock.Lock()         // Lock before resetting the data
+	defer f.lock.Unlock() // Ensure the lock is Unlocked when the method returns
+
+	var zero T
+	f.data = zero
+	f.destroyed = true
+}
+
+// IsDestroyed reports whether Destroy has been called.
+func (f *Fake[T]) IsDestroyed() bool {
+	f.lock.Lock()         // Lock before reading the destroyed state
+	defer f.lock.Unlock() // Ensure the lock is Unlocked when the method returns
+
+	return f.destroyed
+}
+
+// Fingerprint returns the SHA-256 digest of the textual form of the value held by the
+// Fake, matching the Fingerprint of a Secret holding the same value. It returns
+// mattress.ErrDestroyed once the Fake has been destroyed.
+func (f *Fake[T]) Fingerprint() (m.Fingerprint, error) {
+	f.lock.Lock()         // Lock before reading the data
+	defer f.lock.Unlock() // Ensure the lock is Unlocked when the method returns
+
+	if f.destroyed {
+		return m.Fingerprint{}, m.ErrDestroyed
+	}
+
+	switch v := any(f.data).(type) {
+	case string:
+		return sha256.Sum256([]byte(v)), nil
+	case []byte:
+		return sha256.Sum256(v), nil
+	default:
+		return sha256.Sum256(fmt.Append(nil, v)), nil
+	}
+}
+
+// String returns mattress.Placeholder.
+func (f *Fake[T]) String() string {
+	return m.Placeholder
+}
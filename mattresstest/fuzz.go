@@ -0,0 +1,96 @@
+package mattresstest
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+
+	m "github.com/garrettladley/mattress"
+)
+
+// Corpus returns seed plaintexts which commonly trip up code handling secrets, for use
+// as the seed corpus of fuzz tests: empty and single-byte values, whitespace, NUL and
+// control bytes, invalid UTF-8, multi-byte characters, values resembling the redaction
+// placeholder, format verbs, regular expression metacharacters, and a long value.
+func Corpus() [][]byte {
+	return [][]byte{
+		{},
+		[]byte("a"),
+		[]byte(" "),
+		[]byte("\x00secret\x00"),
+		[]byte("line\r\nbreak"),
+		{0xff, 0xfe, 0xfd},
+		[]byte("pässwörd-秘密-🔑"),
+		[]byte(m.Placeholder),
+		[]byte("[SECRET:label]"),
+		[]byte("%s%v%x%!"),
+		[]byte(`.*+?()[]{}|^$\`),
+		[]byte(`"quoted"='single'`),
+		bytes.Repeat([]byte("0123456789abcdef"), 256),
+	}
+}
+
+// AddCorpus adds every seed plaintext returned by Corpus to f, for fuzz targets taking
+// a single []byte argument.
+func AddCorpus(f *testing.F) {
+	f.Helper()
+
+	for _, seed := range Corpus() {
+		f.Add(seed)
+	}
+}
+
+// NewFromFuzz returns a Secret holding a copy of data, created with opts, which is
+// destroyed once the test completes. Unlike New, it never wipes or retains data, which
+// is owned by the fuzzing engine.
+func NewFromFuzz(tb testing.TB, data []byte, opts ...m.Option) *m.SecretBytes {
+	tb.Helper()
+
+	secret, err := m.NewSecretFromBytesUnsafe(data, opts...)
+	if err != nil {
+		tb.Fatalf("mattresstest: creating secret: %v", err)
+	}
+
+	tb.Cleanup(secret.Destroy)
+
+	return secret
+}
+
+// CheckNoLeak is a fuzz helper which fails the test if render, given a Secret holding
+// data, produces output containing data. render stands for the code path under test,
+// such as formatting a struct, logging a request, or writing through a redactor:
+//
+//	func FuzzLogin(f *testing.F) {
+//	  mattresstest.AddCorpus(f)
+//	  f.Fuzz(func(t *testing.T, data []byte) {
+//	    mattresstest.CheckNoLeak(t, data, func(s *m.SecretBytes) []byte {
+//	      var logs bytes.Buffer
+//	      login(slog.New(slog.NewTextHandler(&logs, nil)), s)
+//	      return logs.Bytes()
+//	    })
+//	  })
+//	}
+//
+// Short plaintexts appear in almost any output by coincidence, so render is first
+// called with a random Secret of the same length, and a plaintext which already
+// appears in that output is not reported. Empty plaintexts are skipped.
+func CheckNoLeak(tb testing.TB, data []byte, render func(s *m.SecretBytes) []byte) {
+	tb.Helper()
+
+	if len(data) == 0 {
+		return
+	}
+
+	decoy := make([]byte, len(data))
+	if _, err := rand.Read(decoy); err != nil {
+		tb.Fatalf("mattresstest: generating decoy: %v", err)
+	}
+
+	if bytes.Contains(render(NewFromFuzz(tb, decoy)), data) {
+		return
+	}
+
+	secret := NewFromFuzz(tb, data)
+
+	AssertNoPlaintext(tb, render(secret), secret)
+}
@@ -0,0 +1,96 @@
+// mattresstest provides helpers for tests of code which handles mattress Secrets.
+//
+// New creates Secrets which are destroyed automatically once the test completes, while
+// AssertDestroyed and AssertNoPlaintext check that code under test destroys the Secrets
+// it owns and never leaks their plaintext into logs, responses, or any other output.
+// NewFake returns an in-memory implementation of mattress.SecretValue for injecting
+// into code under test without allocating locked pages. Comparer, Equal, and Diff
+// compare values containing Secrets with go-cmp or testify without reflecting into the
+// Secrets. Corpus, AddCorpus, NewFromFuzz, and CheckNoLeak support fuzz tests of
+// redaction and codec round-trips. Failures never include the plaintext itself.
+//
+// Example Usage:
+//
+//	import (
+//	  "github.com/garrettladley/mattress/mattresstest"
+//	)
+//
+//	func TestLogin(t *testing.T) {
+//	  password := mattresstest.New(t, "hunter2")
+//
+//	  var logs bytes.Buffer
+//	  login(slog.New(slog.NewTextHandler(&logs, nil)), password)
+//
+//	  mattresstest.AssertNoPlaintext(t, logs.Bytes(), password)
+//	}
+package mattresstest
+
+import (
+	"bytes"
+	"testing"
+
+	m "github.com/garrettladley/mattress"
+)
+
+// New returns a Secret holding value, created with opts, which is destroyed once the
+// test and all its subtests have completed. It fails the test immediately if the
+// Secret cannot be created.
+func New[T any](tb testing.TB, value T, opts ...m.Option) *m.Secret[T] {
+	tb.Helper()
+
+	secret, err := m.NewSecret(value, opts...)
+	if err != nil {
+		tb.Fatalf("mattresstest: creating secret: %v", err)
+	}
+
+	tb.Cleanup(secret.Destroy)
+
+	return secret
+}
+
+// AssertDestroyed reports whether s has been destroyed, failing the test if it has
+// not.
+func AssertDestroyed[T any](tb testing.TB, s *m.Secret[T]) bool {
+	tb.Helper()
+
+	if !s.IsDestroyed() {
+		tb.Errorf("mattresstest: secret %v was not destroyed", s)
+		return false
+	}
+
+	return true
+}
+
+// AssertNoPlaintext reports whether buf is free of the plaintext of every one of
+// secrets, in the textual form passed to WithPlaintext, failing the test for each
+// secret whose plaintext it contains. buf may hold any output, such as captured logs or
+// a recorded HTTP response body. Secrets which cannot be exposed, such as those already
+// destroyed, fail the test, as nothing can be said about their plaintext.
+func AssertNoPlaintext(tb testing.TB, buf []byte, secrets ...m.Redactable) bool {
+	tb.Helper()
+
+	clean := true
+
+	for i, secret := range secrets {
+		err := secret.WithPlaintext(func(plaintext []byte) error {
+			tb.Helper()
+
+			if len(plaintext) == 0 {
+				return nil
+			}
+
+			if offset := bytes.Index(buf, plaintext); offset >= 0 {
+				tb.Errorf("mattresstest: output contains the plaintext of secret %d (%v) at offset %d", i, secret, offset)
+				clean = false
+			}
+
+			return nil
+		})
+		if err != nil {
+			tb.Errorf("mattresstest: exposing secret %d: %v", i, err)
+			clean = false
+		}
+	}
+
+	return clean
+}
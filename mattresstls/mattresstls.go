@@ -0,0 +1,108 @@
+// mattresstls loads TLS certificates whose private keys remain sealed in mattress
+// Secrets.
+//
+// The private key of each certificate is a mattresscrypto.Signer, so it is only
+// unwrapped for the duration of each handshake signature. A Reloader serves the
+// current certificate to a tls.Config and hot-reloads it when the files on disk are
+// rotated.
+//
+// Example Usage:
+//
+//	import (
+//	  "github.com/garrettladley/mattress/mattresstls"
+//	)
+//
+//	func main() {
+//	  reloader, err := mattresstls.NewReloader("/etc/tls/tls.crt", "/etc/tls/tls.key")
+//	  if err != nil {
+//	    // handle error
+//	  }
+//	  defer reloader.Close()
+//
+//	  go reloader.Watch(ctx, time.Minute)
+//
+//	  server := &http.Server{
+//	    TLSConfig: &tls.Config{GetCertificate: reloader.GetCertificate},
+//	  }
+//	}
+package mattresstls
+
+import (
+	"crypto"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+
+	m "github.com/garrettladley/mattress"
+	"github.com/garrettladley/mattress/mattresscrypto"
+)
+
+// LoadX509KeyPair reads a PEM encoded certificate chain from certPath and the PEM
+// encoded private key from keyPath, returning a tls.Certificate whose PrivateKey is a
+// *mattresscrypto.Signer. The private key is read directly into guarded memory and is
+// never held as a parsed key outside of signing operations.
+func LoadX509KeyPair(certPath string, keyPath string) (tls.Certificate, error) {
+	certPEM, err := os.ReadFile(certPath)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	keyPEM, err := m.NewSecretFromFile(keyPath)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	signer, err := mattresscrypto.NewSignerFromPEM(keyPEM)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("mattresstls: loading private key: %w", err)
+	}
+
+	cert, err := X509KeyPair(certPEM, signer)
+	if err != nil {
+		signer.Destroy()
+		return tls.Certificate{}, err
+	}
+
+	return cert, nil
+}
+
+// X509KeyPair parses a PEM encoded certificate chain and pairs it with signer,
+// returning an error if the leaf certificate does not match the signer's public key.
+func X509KeyPair(certPEM []byte, signer *mattresscrypto.Signer) (tls.Certificate, error) {
+	var cert tls.Certificate
+
+	for {
+		var block *pem.Block
+
+		block, certPEM = pem.Decode(certPEM)
+		if block == nil {
+			break
+		}
+
+		if block.Type == "CERTIFICATE" {
+			cert.Certificate = append(cert.Certificate, block.Bytes)
+		}
+	}
+
+	if len(cert.Certificate) == 0 {
+		return tls.Certificate{}, errors.New("mattresstls: no certificates found")
+	}
+
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("mattresstls: parsing certificate: %w", err)
+	}
+
+	public, ok := leaf.PublicKey.(interface{ Equal(crypto.PublicKey) bool })
+	if !ok || !public.Equal(signer.Public()) {
+		return tls.Certificate{}, errors.New("mattresstls: private key does not match certificate")
+	}
+
+	cert.Leaf = leaf
+	cert.PrivateKey = signer
+
+	return cert, nil
+}
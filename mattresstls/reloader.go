@@ -0,0 +1,157 @@
+package mattresstls
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/garrettladley/mattress/mattresscrypto"
+)
+
+// gracePeriod is how long the private key of a replaced certificate is kept alive, so
+// that handshakes already in progress can complete.
+const gracePeriod = time.Minute
+
+// Reloader serves a certificate loaded with LoadX509KeyPair to a tls.Config, reloading
+// it when the certificate or key file changes.
+type Reloader struct {
+	certPath string           // certPath is the path of the certificate chain
+	keyPath  string           // keyPath is the path of the private key
+	cert     *tls.Certificate // cert is the most recently loaded certificate
+	stamp    string           // stamp summarizes the files cert was loaded from
+	lock     sync.RWMutex     // synchronize access to cert and stamp
+}
+
+// NewReloader loads the certificate at certPath and private key at keyPath, as
+// described by LoadX509KeyPair.
+func NewReloader(certPath string, keyPath string) (*Reloader, error) {
+	r := &Reloader{certPath: certPath, keyPath: keyPath}
+
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// GetCertificate returns the current certificate. It is suitable for use as
+// tls.Config.GetCertificate.
+func (r *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.lock.RLock()         // RLock before reading the certificate
+	defer r.lock.RUnlock() // Ensure the lock is RUnlocked when the method returns
+
+	return r.cert, nil
+}
+
+// GetClientCertificate returns the current certificate. It is suitable for use as
+// tls.Config.GetClientCertificate.
+func (r *Reloader) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	return r.GetCertificate(nil)
+}
+
+// Reload loads the certificate and private key from disk, replacing the current
+// certificate. The private key of the replaced certificate is destroyed after a grace
+// period, allowing in-progress handshakes to complete.
+func (r *Reloader) Reload() error {
+	stamp, err := r.stat()
+	if err != nil {
+		return err
+	}
+
+	cert, err := LoadX509KeyPair(r.certPath, r.keyPath)
+	if err != nil {
+		return err
+	}
+
+	r.lock.Lock()         // Lock before replacing the certificate
+	defer r.lock.Unlock() // Ensure the lock is Unlocked when the method returns
+
+	if r.cert != nil {
+		retire(r.cert)
+	}
+
+	r.cert = &cert
+	r.stamp = stamp
+
+	return nil
+}
+
+// Watch checks every interval whether the certificate or key file has changed,
+// reloading them if so, until ctx is cancelled. Errors are passed to onError, if it is
+// not nil, and the reload is retried at the next interval.
+func (r *Reloader) Watch(ctx context.Context, interval time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := r.reloadIfChanged(); err != nil && onError != nil {
+			onError(err)
+		}
+	}
+}
+
+// Close destroys the private key of the current certificate. The Reloader cannot be
+// used afterwards.
+func (r *Reloader) Close() {
+	r.lock.Lock()         // Lock before destroying the certificate
+	defer r.lock.Unlock() // Ensure the lock is Unlocked when the method returns
+
+	if signer, ok := r.cert.PrivateKey.(*mattresscrypto.Signer); ok {
+		signer.Destroy()
+	}
+}
+
+// reloadIfChanged reloads the certificate if either file has changed since it was
+// last loaded.
+func (r *Reloader) reloadIfChanged() error {
+	stamp, err := r.stat()
+	if err != nil {
+		return err
+	}
+
+	r.lock.RLock()
+	unchanged := stamp == r.stamp
+	r.lock.RUnlock()
+
+	if unchanged {
+		return nil
+	}
+
+	return r.Reload()
+}
+
+// stat summarizes the size and modification time of the certificate and key files.
+func (r *Reloader) stat() (string, error) {
+	var stamp strings.Builder
+
+	for _, path := range []string{r.certPath, r.keyPath} {
+		info, err := os.Stat(path)
+		if err != nil {
+			return "", err
+		}
+
+		fmt.Fprintf(&stamp, "%s:%d:%d;", path, info.Size(), info.ModTime().UnixNano())
+	}
+
+	return stamp.String(), nil
+}
+
+// retire destroys the private key of cert once the grace period has elapsed.
+func retire(cert *tls.Certificate) {
+	signer, ok := cert.PrivateKey.(*mattresscrypto.Signer)
+	if !ok {
+		return
+	}
+
+	time.AfterFunc(gracePeriod, signer.Destroy)
+}
@@ -0,0 +1,335 @@
+// mattresstpm seals secrets to the TPM 2.0 of a machine and the state of its platform
+// configuration registers (PCRs), and unseals them into mattress Secrets at startup, so
+// that credentials are bound to a specific machine and boot configuration.
+//
+// Sealed blobs may be stored anywhere, such as alongside the application: they can
+// only be unsealed by the TPM which sealed them, and only while the selected PCRs hold
+// the values they held at sealing. The TPM can seal at most 128 bytes, so larger
+// Secrets are exported with a Wrapper, which seals the data key instead.
+//
+// Example Usage:
+//
+//	import (
+//	  m "github.com/garrettladley/mattress"
+//	  "github.com/garrettladley/mattress/mattresstpm"
+//	  "github.com/google/go-tpm/tpm2/transport/linuxtpm"
+//	)
+//
+//	func main() {
+//	  tpm, err := linuxtpm.Open("/dev/tpmrm0")
+//	  if err != nil {
+//	    // handle error
+//	  }
+//	  defer tpm.Close()
+//
+//	  // Bind the credential to the firmware, bootloader, and Secure Boot policy.
+//	  wrapper := mattresstpm.NewWrapper(tpm, 0, 2, 4, 7)
+//
+//	  credential, err := m.ImportSecretWithCodec(ctx, wrapper, blob, m.StringCodec{})
+//	  if err != nil {
+//	    // handle error
+//	  }
+//	  defer credential.Destroy()
+//	}
+package mattresstpm
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	m "github.com/garrettladley/mattress"
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpm2/transport"
+)
+
+// MaxSealedSize is the largest plaintext, in bytes, that a TPM can seal.
+const MaxSealedSize = 128
+
+// blobMagic prefixes blobs produced by Seal, identifying the format and its version.
+var blobMagic = [4]byte{'M', 'T', 'R', 'T'}
+
+// Wrapper is a mattress.Wrapper which seals data keys to a TPM and the state of a
+// selection of its PCRs, so that Secrets exported with it can only be imported on the
+// same machine in the same boot configuration.
+type Wrapper struct {
+	tpm  transport.TPM // tpm is the TPM keys are sealed to
+	pcrs []uint        // pcrs are the indices of the PCRs keys are bound to
+}
+
+// NewWrapper returns a Wrapper sealing data keys to tpm and the current values of the
+// SHA-256 PCRs at the given indices. With no PCRs, keys are bound to the TPM alone.
+func NewWrapper(tpm transport.TPM, pcrs ...uint) *Wrapper {
+	return &Wrapper{tpm: tpm, pcrs: pcrs}
+}
+
+// WrapKey implements mattress.Wrapper, sealing key to the TPM and PCRs of the Wrapper.
+func (w *Wrapper) WrapKey(_ context.Context, key *m.Secret[[]byte]) ([]byte, error) {
+	return Seal(w.tpm, key, w.pcrs...)
+}
+
+// UnwrapKey implements mattress.Wrapper, unsealing a key sealed by WrapKey.
+func (w *Wrapper) UnwrapKey(_ context.Context, wrapped []byte) (*m.Secret[[]byte], error) {
+	return Unseal(w.tpm, wrapped)
+}
+
+// tpmLock serializes the commands sent by this package, as each operation creates and
+// flushes transient objects and sessions which occupy the limited slots of the TPM.
+var tpmLock sync.Mutex
+
+// Seal seals the plaintext of secret, which must be at most MaxSealedSize bytes, to tpm
+// and the current values of the SHA-256 PCRs at the given indices, and returns a blob
+// which can be restored with Unseal. The plaintext is encrypted in transit to the TPM,
+// and the blob is protected by the storage root key of the TPM's owner hierarchy.
+func Seal(tpm transport.TPM, secret m.Redactable, pcrs ...uint) ([]byte, error) {
+	pcrs = slices.Clone(pcrs)
+	slices.Sort(pcrs)
+	pcrs = slices.Compact(pcrs)
+
+	if len(pcrs) > 0 && pcrs[len(pcrs)-1] > 255 {
+		return nil, fmt.Errorf("mattresstpm: invalid PCR %d", pcrs[len(pcrs)-1])
+	}
+
+	tpmLock.Lock()         // Lock before creating the SRK
+	defer tpmLock.Unlock() // Ensure the lock is Unlocked when the function returns
+
+	srk, err := createSRK(tpm)
+	if err != nil {
+		return nil, err
+	}
+	defer flush(tpm, srk.ObjectHandle)
+
+	policy, err := policyDigest(tpm, pcrs)
+	if err != nil {
+		return nil, err
+	}
+
+	var created *tpm2.CreateResponse
+
+	err = secret.WithPlaintext(func(plaintext []byte) error {
+		if len(plaintext) > MaxSealedSize {
+			return fmt.Errorf("mattresstpm: secret of %d bytes exceeds the maximum of %d", len(plaintext), MaxSealedSize)
+		}
+
+		create := tpm2.Create{
+			ParentHandle: tpm2.AuthHandle{
+				Handle: srk.ObjectHandle,
+				Name:   srk.Name,
+				Auth:   tpm2.HMAC(tpm2.TPMAlgSHA256, 16, saltedBy(srk), tpm2.AESEncryption(128, tpm2.EncryptIn)),
+			},
+			InSensitive: tpm2.TPM2BSensitiveCreate{
+				Sensitive: &tpm2.TPMSSensitiveCreate{
+					Data: tpm2.NewTPMUSensitiveCreate(&tpm2.TPM2BSensitiveData{Buffer: plaintext}),
+				},
+			},
+			InPublic: tpm2.New2B(tpm2.TPMTPublic{
+				Type:    tpm2.TPMAlgKeyedHash,
+				NameAlg: tpm2.TPMAlgSHA256,
+				ObjectAttributes: tpm2.TPMAObject{
+					FixedTPM:    true,
+					FixedParent: true,
+				},
+				AuthPolicy: tpm2.TPM2BDigest{Buffer: policy},
+			}),
+		}
+
+		if created, err = create.Execute(tpm); err != nil {
+			return fmt.Errorf("mattresstpm: sealing: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	blob := append(blobMagic[:], byte(len(pcrs)))
+	for _, pcr := range pcrs {
+		blob = append(blob, byte(pcr))
+	}
+
+	blob = append(blob, tpm2.Marshal(created.OutPublic)...)
+	blob = append(blob, tpm2.Marshal(created.OutPrivate)...)
+
+	return blob, nil
+}
+
+// Unseal restores a Secret from a blob produced by Seal. The plaintext is encrypted in
+// transit from the TPM and secured as soon as it is received. An error wrapping
+// mattress.ErrDecryptionFailed is returned if the values of the PCRs the blob is bound
+// to have changed since it was sealed, or if it was sealed by a different TPM.
+func Unseal(tpm transport.TPM, blob []byte, opts ...m.Option) (*m.Secret[[]byte], error) {
+	pcrs, public, private, err := parseBlob(blob)
+	if err != nil {
+		return nil, err
+	}
+
+	tpmLock.Lock()         // Lock before creating the SRK
+	defer tpmLock.Unlock() // Ensure the lock is Unlocked when the function returns
+
+	srk, err := createSRK(tpm)
+	if err != nil {
+		return nil, err
+	}
+	defer flush(tpm, srk.ObjectHandle)
+
+	load := tpm2.Load{
+		ParentHandle: tpm2.NamedHandle{
+			Handle: srk.ObjectHandle,
+			Name:   srk.Name,
+		},
+		InPublic:  *public,
+		InPrivate: *private,
+	}
+
+	loaded, err := load.Execute(tpm)
+	if err != nil {
+		return nil, fmt.Errorf("mattresstpm: loading sealed object: %w: %w", m.ErrDecryptionFailed, err)
+	}
+	defer flush(tpm, loaded.ObjectHandle)
+
+	unseal := tpm2.Unseal{
+		ItemHandle: tpm2.AuthHandle{
+			Handle: loaded.ObjectHandle,
+			Name:   loaded.Name,
+			Auth: tpm2.Policy(tpm2.TPMAlgSHA256, 16, func(tpm transport.TPM, handle tpm2.TPMISHPolicy, _ tpm2.TPM2BNonce) error {
+				_, err := tpm2.PolicyPCR{PolicySession: handle, Pcrs: selection(pcrs)}.Execute(tpm)
+				return err
+			}, saltedBy(srk), tpm2.AESEncryption(128, tpm2.EncryptOut)),
+		},
+	}
+
+	unsealed, err := unseal.Execute(tpm)
+	if errors.Is(err, tpm2.TPMRCPolicyFail) {
+		return nil, fmt.Errorf("mattresstpm: PCR values have changed: %w", m.ErrDecryptionFailed)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("mattresstpm: unsealing: %w", err)
+	}
+
+	// NewSecretBytes wipes the unsealed plaintext once it has been secured.
+	return m.NewSecretBytes(unsealed.OutData.Buffer, opts...)
+}
+
+// createSRK creates the storage root key of the owner hierarchy from the standard
+// template. The key is derived from the hierarchy's seed, so the same key is recreated
+// every time without being persisted in the TPM.
+func createSRK(tpm transport.TPM) (*tpm2.CreatePrimaryResponse, error) {
+	create := tpm2.CreatePrimary{
+		PrimaryHandle: tpm2.TPMRHOwner,
+		InPublic:      tpm2.New2B(tpm2.ECCSRKTemplate),
+	}
+
+	srk, err := create.Execute(tpm)
+	if err != nil {
+		return nil, fmt.Errorf("mattresstpm: creating storage root key: %w", err)
+	}
+
+	return srk, nil
+}
+
+// saltedBy salts sessions with srk, so that the session key, and the parameters it
+// encrypts, cannot be recovered by observing the bus to the TPM.
+func saltedBy(srk *tpm2.CreatePrimaryResponse) tpm2.AuthOption {
+	public, err := srk.OutPublic.Contents()
+	if err != nil {
+		// OutPublic was just unmarshalled from the response of the TPM.
+		panic(err)
+	}
+
+	return tpm2.Salted(srk.ObjectHandle, *public)
+}
+
+// policyDigest computes, with a trial session, the digest of a policy satisfied while
+// the PCRs at the given indices hold their current values.
+func policyDigest(tpm transport.TPM, pcrs []uint) ([]byte, error) {
+	session, closeSession, err := tpm2.PolicySession(tpm, tpm2.TPMAlgSHA256, 16, tpm2.Trial())
+	if err != nil {
+		return nil, fmt.Errorf("mattresstpm: starting trial session: %w", err)
+	}
+	defer closeSession()
+
+	if _, err := (tpm2.PolicyPCR{PolicySession: session.Handle(), Pcrs: selection(pcrs)}).Execute(tpm); err != nil {
+		return nil, fmt.Errorf("mattresstpm: binding to PCRs: %w", err)
+	}
+
+	digest, err := tpm2.PolicyGetDigest{PolicySession: session.Handle()}.Execute(tpm)
+	if err != nil {
+		return nil, fmt.Errorf("mattresstpm: computing policy: %w", err)
+	}
+
+	return digest.PolicyDigest.Buffer, nil
+}
+
+// selection returns the selection of the SHA-256 PCRs at the given indices.
+func selection(pcrs []uint) tpm2.TPMLPCRSelection {
+	return tpm2.TPMLPCRSelection{
+		PCRSelections: []tpm2.TPMSPCRSelection{
+			{
+				Hash:      tpm2.TPMAlgSHA256,
+				PCRSelect: tpm2.PCClientCompatible.PCRs(pcrs...),
+			},
+		},
+	}
+}
+
+// flush evicts the transient object or session at handle from the TPM.
+func flush(tpm transport.TPM, handle tpm2.TPMHandle) {
+	// Flushing only fails if the handle was already evicted.
+	_, _ = tpm2.FlushContext{FlushHandle: handle}.Execute(tpm)
+}
+
+// parseBlob splits a blob produced by Seal into the PCRs it is bound to and the public
+// and private areas of its sealed object.
+func parseBlob(blob []byte) ([]uint, *tpm2.TPM2BPublic, *tpm2.TPM2BPrivate, error) {
+	malformed := fmt.Errorf("mattresstpm: malformed blob: %w", m.ErrDecryptionFailed)
+
+	if len(blob) < len(blobMagic)+1 || !bytes.HasPrefix(blob, blobMagic[:]) {
+		return nil, nil, nil, malformed
+	}
+
+	rest := blob[len(blobMagic):]
+
+	count := int(rest[0])
+	if len(rest) < 1+count {
+		return nil, nil, nil, malformed
+	}
+
+	pcrs := make([]uint, count)
+	for i, pcr := range rest[1 : 1+count] {
+		pcrs[i] = uint(pcr)
+	}
+
+	rest = rest[1+count:]
+
+	// The public and private areas are each prefixed by their size in two bytes.
+	var areas [2][]byte
+	for i := range areas {
+		if len(rest) < 2 || len(rest) < 2+int(binary.BigEndian.Uint16(rest)) {
+			return nil, nil, nil, malformed
+		}
+
+		n := 2 + int(binary.BigEndian.Uint16(rest))
+		areas[i], rest = rest[:n], rest[n:]
+	}
+
+	if len(rest) != 0 {
+		return nil, nil, nil, malformed
+	}
+
+	public, err := tpm2.Unmarshal[tpm2.TPM2BPublic](areas[0])
+	if err != nil {
+		return nil, nil, nil, malformed
+	}
+
+	private, err := tpm2.Unmarshal[tpm2.TPM2BPrivate](areas[1])
+	if err != nil {
+		return nil, nil, nil, malformed
+	}
+
+	return pcrs, public, private, nil
+}
@@ -0,0 +1,80 @@
+// mattressvalidator allows structs holding mattress Secrets to be validated with
+// go-playground/validator.
+//
+// A Secret is opaque to the validator, so the usual string rules cannot be applied to
+// it directly. Register adds a "secret" tag whose parameter lists the rules to apply
+// to the plaintext of the Secret, separated by semicolons rather than commas. The
+// Secret is exposed only for the duration of the check and the plaintext is wiped
+// afterwards; the validator's own error never reveals it. The built-in required rule
+// continues to work on *Secret fields as it does on any pointer.
+//
+// Example Usage:
+//
+//	import (
+//	  m "github.com/garrettladley/mattress"
+//	  "github.com/garrettladley/mattress/mattressvalidator"
+//	)
+//
+//	type Config struct {
+//	  Password *m.Secret[string] `validate:"required,secret=min=12;max=128;containsany=!@#$%^&*"`
+//	}
+//
+//	func main() {
+//	  validate := validator.New()
+//	  if err := mattressvalidator.Register(validate); err != nil {
+//	    // handle error
+//	  }
+//
+//	  err := validate.Struct(cfg)
+//	}
+package mattressvalidator
+
+import (
+	"strings"
+	"unsafe"
+
+	m "github.com/garrettladley/mattress"
+	"github.com/go-playground/validator/v10"
+)
+
+// Tag is the validation tag registered by Register.
+const Tag = "secret"
+
+// Register registers the "secret" tag with v. Its parameter holds validator rules,
+// separated by semicolons, which are applied to the textual form of the plaintext of
+// the Secret, as passed to WithPlaintext. Rules are combined with AND; the | operator
+// is not available within the parameter. Without a parameter, the tag only requires
+// that the Secret can be exposed, failing for Secrets which have been destroyed or
+// have expired.
+func Register(v *validator.Validate) error {
+	return v.RegisterValidation(Tag, func(fl validator.FieldLevel) bool {
+		field := fl.Field()
+		if !field.CanAddr() {
+			return false
+		}
+
+		secret, ok := field.Addr().Interface().(m.Redactable)
+		if !ok {
+			return false
+		}
+
+		rules := strings.ReplaceAll(fl.Param(), ";", ",")
+
+		var valid bool
+
+		err := secret.WithPlaintext(func(plaintext []byte) error {
+			if rules == "" {
+				valid = true
+				return nil
+			}
+
+			// The plaintext is validated without copying it into an ordinary string, so
+			// the only copy is the one wiped once fn returns.
+			valid = v.Var(unsafe.String(unsafe.SliceData(plaintext), len(plaintext)), rules) == nil
+
+			return nil
+		})
+
+		return err == nil && valid
+	})
+}
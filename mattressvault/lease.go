@@ -0,0 +1,185 @@
+package mattressvault
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	m "github.com/garrettladley/mattress"
+)
+
+// LeasedSecret keeps a secret fetched from Vault fresh in the background, renewing its
+// lease before it expires and re-fetching the secret once the lease can no longer be
+// renewed.
+type LeasedSecret struct {
+	client   *Client           // client fetches the secret and renews its lease
+	name     string            // name identifies the secret, as accepted by Fetch
+	current  *m.Secret[string] // current is the most recently fetched secret
+	lease    Lease             // lease is the lease attached to current
+	interval time.Duration     // interval forces a periodic re-fetch of unleased secrets
+	onError  func(error)       // onError is notified of background failures
+	lock     sync.RWMutex      // synchronize access to current and lease
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+// LeaseOption configures a LeasedSecret.
+type LeaseOption func(*LeasedSecret)
+
+// WithRefreshInterval re-fetches the secret every interval when Vault does not attach
+// a lease to it, as is typical of the KV version 2 engine.
+func WithRefreshInterval(interval time.Duration) LeaseOption {
+	return func(l *LeasedSecret) {
+		l.interval = interval
+	}
+}
+
+// OnError registers fn to be called with any error encountered while renewing or
+// re-fetching the secret in the background. Failed attempts are retried.
+func OnError(fn func(error)) LeaseOption {
+	return func(l *LeasedSecret) {
+		l.onError = fn
+	}
+}
+
+// retryInterval is how long a LeasedSecret waits before retrying a failed refresh.
+const retryInterval = 5 * time.Second
+
+// Lease fetches the secret called name, as described by Fetch, and keeps it fresh in
+// the background until Close is called or ctx is cancelled.
+func (c *Client) Lease(ctx context.Context, name string, opts ...LeaseOption) (*LeasedSecret, error) {
+	secret, lease, err := c.fetch(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+
+	l := &LeasedSecret{
+		client:  c,
+		name:    name,
+		current: secret,
+		lease:   lease,
+		onError: func(error) {},
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	go l.run(ctx)
+
+	return l, nil
+}
+
+// Current returns the most recently fetched secret. The Secret remains owned by the
+// LeasedSecret and is destroyed once it is replaced, so callers should call Current
+// each time the secret is needed rather than retaining it.
+func (l *LeasedSecret) Current() *m.Secret[string] {
+	l.lock.RLock()         // RLock before reading the current secret
+	defer l.lock.RUnlock() // Ensure the lock is RUnlocked when the method returns
+
+	return l.current
+}
+
+// WithExposed exposes the most recently fetched secret to fn, guaranteeing that it is
+// not replaced while fn runs.
+func (l *LeasedSecret) WithExposed(fn func(string) error) error {
+	l.lock.RLock()         // RLock before reading the current secret
+	defer l.lock.RUnlock() // Ensure the lock is RUnlocked when the method returns
+
+	return l.current.WithExposed(fn)
+}
+
+// Close stops refreshing the secret and destroys it.
+func (l *LeasedSecret) Close() {
+	l.cancel()
+	<-l.done
+
+	l.lock.Lock()         // Lock before destroying the current secret
+	defer l.lock.Unlock() // Ensure the lock is Unlocked when the method returns
+
+	l.current.Destroy()
+}
+
+// run refreshes the secret until ctx is cancelled.
+func (l *LeasedSecret) run(ctx context.Context) {
+	defer close(l.done)
+
+	wait := l.nextRefresh()
+
+	for wait > 0 {
+		timer := time.NewTimer(wait)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		if err := l.refresh(ctx); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+
+			l.onError(err)
+			wait = retryInterval
+
+			continue
+		}
+
+		wait = l.nextRefresh()
+	}
+}
+
+// nextRefresh returns how long to wait before refreshing the secret, or zero if it
+// never needs refreshing. Leases are refreshed once two thirds of their duration has
+// elapsed, leaving time to retry before they expire.
+func (l *LeasedSecret) nextRefresh() time.Duration {
+	l.lock.RLock()         // RLock before reading the lease
+	defer l.lock.RUnlock() // Ensure the lock is RUnlocked when the method returns
+
+	if l.lease.Duration > 0 {
+		return l.lease.Duration * 2 / 3
+	}
+
+	return l.interval
+}
+
+// refresh renews the lease on the secret if possible, and otherwise re-fetches it.
+func (l *LeasedSecret) refresh(ctx context.Context) error {
+	l.lock.RLock()
+	lease := l.lease
+	l.lock.RUnlock()
+
+	if lease.Renewable && lease.ID != "" {
+		renewed, err := l.client.Renew(ctx, lease.ID, lease.Duration)
+
+		// A lease renewed for less than requested is approaching its maximum TTL, so
+		// the secret is re-fetched instead.
+		if err == nil && renewed.Duration >= lease.Duration {
+			l.lock.Lock()
+			l.lease = renewed
+			l.lock.Unlock()
+
+			return nil
+		}
+	}
+
+	secret, lease, err := l.client.fetch(ctx, l.name)
+	if err != nil {
+		return err
+	}
+
+	l.lock.Lock()         // Lock before replacing the current secret
+	defer l.lock.Unlock() // Ensure the lock is Unlocked when the method returns
+
+	l.current.Destroy()
+	l.current = secret
+	l.lease = lease
+
+	return nil
+}
@@ -0,0 +1,317 @@
+// mattressvault sources mattress Secrets from the KV version 2 secrets engine of
+// HashiCorp Vault.
+//
+// Responses are read into memory that is wiped once decoded, and each value is sealed
+// into a Secret as soon as it has been decoded, so plaintext never outlives the decode
+// step in ordinary Go memory.
+//
+// Example Usage:
+//
+//	import (
+//	  m "github.com/garrettladley/mattress"
+//	  "github.com/garrettladley/mattress/mattressvault"
+//	)
+//
+//	func main() {
+//	  token, err := m.NewSecretString(os.Getenv("VAULT_TOKEN"))
+//	  if err != nil {
+//	    // handle error
+//	  }
+//
+//	  client := mattressvault.NewClient("https://vault.example.com:8200", token)
+//	  m.Register("vault", client)
+//
+//	  password, err := m.Fetch(ctx, "vault", "myapp/database#password")
+//	  if err != nil {
+//	    // handle error
+//	  }
+//	  defer password.Destroy()
+//	}
+package mattressvault
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/awnumar/memguard"
+	m "github.com/garrettladley/mattress"
+)
+
+// Client fetches secrets from the KV version 2 secrets engine of a Vault server. It
+// implements mattress.Provider.
+type Client struct {
+	addr      string            // addr is the address of the Vault server
+	token     *m.Secret[string] // token authenticates requests to Vault
+	mount     string            // mount is the path the KV engine is mounted at
+	namespace string            // namespace is the Vault Enterprise namespace, if any
+	http      *http.Client      // http performs requests to Vault
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithMount sets the path the KV version 2 engine is mounted at. It defaults to
+// "secret".
+func WithMount(mount string) Option {
+	return func(c *Client) {
+		c.mount = strings.Trim(mount, "/")
+	}
+}
+
+// WithNamespace sets the Vault Enterprise namespace requests are made in.
+func WithNamespace(namespace string) Option {
+	return func(c *Client) {
+		c.namespace = namespace
+	}
+}
+
+// WithHTTPClient sets the http.Client used to make requests to Vault. It defaults to
+// http.DefaultClient.
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *Client) {
+		c.http = client
+	}
+}
+
+// NewClient returns a Client for the Vault server at addr, authenticating with token.
+// The token remains owned by the caller and must outlive the Client.
+func NewClient(addr string, token *m.Secret[string], opts ...Option) *Client {
+	c := &Client{
+		addr:  strings.TrimRight(addr, "/"),
+		token: token,
+		mount: "secret",
+		http:  http.DefaultClient,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// Fetch implements mattress.Provider. name takes the form "path#key", naming the key
+// within the KV secret at path; if "#key" is omitted the key defaults to "value".
+func (c *Client) Fetch(ctx context.Context, name string) (*m.Secret[string], error) {
+	secret, _, err := c.fetch(ctx, name)
+
+	return secret, err
+}
+
+// fetch fetches the secret called name, as described by Fetch, along with its Lease.
+func (c *Client) fetch(ctx context.Context, name string) (*m.Secret[string], Lease, error) {
+	path, key := splitName(name)
+
+	secrets, lease, err := c.Read(ctx, path)
+	if err != nil {
+		return nil, Lease{}, err
+	}
+
+	secret, ok := secrets[key]
+
+	// Destroy every other key read alongside the one requested.
+	for k, s := range secrets {
+		if k != key {
+			s.Destroy()
+		}
+	}
+
+	if !ok {
+		return nil, Lease{}, fmt.Errorf("mattressvault: key %q in %q: %w", key, path, m.ErrSecretNotFound)
+	}
+
+	return secret, lease, nil
+}
+
+// Lease describes the lease Vault attached to a response.
+type Lease struct {
+	ID        string        // ID identifies the lease, if it can be renewed by ID
+	Duration  time.Duration // Duration is how long the response is valid for
+	Renewable bool          // Renewable reports whether the lease may be renewed
+}
+
+// Read reads every key of the KV secret at path, sealing each value into its own
+// Secret. The returned Secrets are owned by the caller.
+func (c *Client) Read(ctx context.Context, path string) (map[string]*m.Secret[string], Lease, error) {
+	var response struct {
+		LeaseID       string `json:"lease_id"`
+		LeaseDuration int64  `json:"lease_duration"`
+		Renewable     bool   `json:"renewable"`
+		Data          struct {
+			Data map[string]json.RawMessage `json:"data"`
+		} `json:"data"`
+	}
+
+	endpoint := fmt.Sprintf("%s/data/%s", c.mount, strings.Trim(path, "/"))
+
+	if err := c.do(ctx, http.MethodGet, endpoint, nil, &response); err != nil {
+		return nil, Lease{}, err
+	}
+
+	lease := Lease{
+		ID:        response.LeaseID,
+		Duration:  time.Duration(response.LeaseDuration) * time.Second,
+		Renewable: response.Renewable,
+	}
+
+	secrets := make(map[string]*m.Secret[string], len(response.Data.Data))
+
+	for key, raw := range response.Data.Data {
+		secret, err := seal(raw)
+
+		// WipeBytes securely erases the raw value once it has been sealed.
+		memguard.WipeBytes(raw)
+
+		if err != nil {
+			for _, s := range secrets {
+				s.Destroy()
+			}
+			return nil, Lease{}, fmt.Errorf("mattressvault: decoding key %q in %q: %w", key, path, err)
+		}
+
+		secrets[key] = secret
+	}
+
+	return secrets, lease, nil
+}
+
+// Renew asks Vault to extend the lease identified by leaseID by increment, returning
+// the renewed Lease.
+func (c *Client) Renew(ctx context.Context, leaseID string, increment time.Duration) (Lease, error) {
+	var response struct {
+		LeaseID       string `json:"lease_id"`
+		LeaseDuration int64  `json:"lease_duration"`
+		Renewable     bool   `json:"renewable"`
+	}
+
+	body, err := json.Marshal(map[string]any{
+		"lease_id":  leaseID,
+		"increment": int64(increment / time.Second),
+	})
+	if err != nil {
+		return Lease{}, err
+	}
+
+	if err := c.do(ctx, http.MethodPut, "sys/leases/renew", body, &response); err != nil {
+		return Lease{}, err
+	}
+
+	return Lease{
+		ID:        response.LeaseID,
+		Duration:  time.Duration(response.LeaseDuration) * time.Second,
+		Renewable: response.Renewable,
+	}, nil
+}
+
+// do performs a request against the Vault HTTP API and decodes the JSON response into
+// out. The response body is wiped once decoded.
+func (c *Client) do(ctx context.Context, method string, endpoint string, body []byte, out any) error {
+	u, err := url.JoinPath(c.addr, "v1", endpoint)
+	if err != nil {
+		return err
+	}
+
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, reader)
+	if err != nil {
+		return err
+	}
+
+	if c.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", c.namespace)
+	}
+
+	if err := c.token.WithExposed(func(token string) error {
+		req.Header.Set("X-Vault-Token", token)
+		return nil
+	}); err != nil {
+		return fmt.Errorf("mattressvault: exposing token: %w", err)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(resp.Body)
+
+	// WipeBytes securely erases the response body once it has been decoded.
+	defer memguard.WipeBytes(raw)
+
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return fmt.Errorf("mattressvault: %s: %w", endpoint, m.ErrSecretNotFound)
+	case resp.StatusCode >= http.StatusBadRequest:
+		return &ResponseError{StatusCode: resp.StatusCode, Errors: decodeErrors(raw)}
+	}
+
+	return json.Unmarshal(raw, out)
+}
+
+// ResponseError is returned when Vault responds with an error status.
+type ResponseError struct {
+	StatusCode int      // StatusCode is the HTTP status code of the response
+	Errors     []string // Errors holds the error messages reported by Vault
+}
+
+func (e *ResponseError) Error() string {
+	if len(e.Errors) == 0 {
+		return fmt.Sprintf("mattressvault: unexpected status %d", e.StatusCode)
+	}
+
+	return fmt.Sprintf("mattressvault: unexpected status %d: %s", e.StatusCode, strings.Join(e.Errors, "; "))
+}
+
+// decodeErrors extracts the error messages from the body of a Vault error response.
+func decodeErrors(raw []byte) []string {
+	var response struct {
+		Errors []string `json:"errors"`
+	}
+
+	if err := json.Unmarshal(raw, &response); err != nil {
+		return nil
+	}
+
+	return response.Errors
+}
+
+// seal seals a raw JSON value into a Secret. Strings are unquoted; any other JSON
+// value is sealed verbatim.
+func seal(raw json.RawMessage) (*m.Secret[string], error) {
+	if len(raw) == 0 || raw[0] != '"' {
+		return m.NewSecretString(string(raw))
+	}
+
+	var value string
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return nil, err
+	}
+
+	return m.NewSecretString(value)
+}
+
+// splitName splits a name of the form "path#key" into its path and key.
+func splitName(name string) (string, string) {
+	path, key, ok := strings.Cut(name, "#")
+	if !ok || key == "" {
+		return path, "value"
+	}
+
+	return path, key
+}
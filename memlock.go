@@ -0,0 +1,113 @@
+package mattress
+
+import (
+	"fmt"
+	"os"
+	"sync/atomic"
+)
+
+// Environment describes the locked memory available to the process, which bounds the
+// guarded memory, and therefore the number of Secrets, it can hold at once.
+type Environment struct {
+	MemlockLimit uint64 // MemlockLimit is the number of bytes the process may lock, RLIMIT_MEMLOCK
+	MemlockUsed  uint64 // MemlockUsed is the number of bytes currently locked by the process
+	Unlimited    bool   // Unlimited reports whether locked memory is not limited, such as with CAP_IPC_LOCK
+}
+
+// Available returns the number of bytes which may still be locked, or the largest
+// uint64 if locked memory is unlimited.
+func (e Environment) Available() uint64 {
+	switch {
+	case e.Unlimited:
+		return ^uint64(0)
+	case e.MemlockUsed >= e.MemlockLimit:
+		return 0
+	default:
+		return e.MemlockLimit - e.MemlockUsed
+	}
+}
+
+// CheckEnvironment reports the locked-memory budget of the process and how much of it
+// is in use, so that applications can check at startup that they can hold the Secrets
+// they expect to, rather than failing once the limit is reached. The limit of most
+// containers is far lower than that of a host, often 64KiB, and every Secret locks at
+// least one page. CheckEnvironment is only supported on Linux, and returns
+// errors.ErrUnsupported elsewhere.
+//
+// Constructors which would exceed the budget fail with ErrMemlockExhausted rather than
+// memguard panicking.
+func CheckEnvironment() (Environment, error) {
+	return memlockStatus()
+}
+
+// memlockBudget holds the locked-memory budget last read by reserveLocked, against
+// which reserveView checks views without reading it again, or nil before it is first
+// read.
+var memlockBudget atomic.Pointer[memlockSnapshot]
+
+// memlockSnapshot is a locked-memory budget as read at a point in time. ok is false if
+// the budget could not be determined, in which case it is assumed to suffice.
+type memlockSnapshot struct {
+	env Environment
+	ok  bool
+}
+
+// fits returns ErrMemlockExhausted if size more bytes of guarded memory would exceed
+// the budget.
+func (m *memlockSnapshot) fits(size int) error {
+	if !m.ok || m.env.Unlimited {
+		return nil
+	}
+
+	// memguard locks whole pages, rounding up the size of every buffer.
+	page := uint64(os.Getpagesize())
+	needed := (uint64(size) + page - 1) / page * page
+
+	if available := m.env.Available(); needed > available {
+		return fmt.Errorf("%w: %d bytes needed, %d of %d available", ErrMemlockExhausted, needed, available, m.env.MemlockLimit)
+	}
+
+	return nil
+}
+
+// readMemlockBudget reads the locked-memory budget of the process, recording it for
+// reserveView.
+func readMemlockBudget() *memlockSnapshot {
+	env, err := memlockStatus()
+	snapshot := &memlockSnapshot{env: env, ok: err == nil}
+
+	memlockBudget.Store(snapshot)
+
+	return snapshot
+}
+
+// reserveLocked returns ErrMemlockExhausted if size more bytes of long-lived guarded
+// memory, such as that holding a Secret, would exceed the locked-memory budget of the
+// process. The budget is read afresh, which costs a system call and a read of
+// /proc/self/status, so reserveLocked is only called as a Secret is constructed.
+// Budgets which cannot be determined are assumed to suffice.
+func reserveLocked(size int) error {
+	if size <= 0 || Degraded() {
+		return nil
+	}
+
+	return readMemlockBudget().fits(size)
+}
+
+// reserveView is reserveLocked for short-lived guarded memory, such as that a Secret is
+// decrypted into while exposed. It checks size against the budget last read rather
+// than reading it again, so that exposing a Secret stays free of system calls; the
+// budget is only stale by whatever has been locked or unlocked since the last Secret
+// was constructed.
+func reserveView(size int) error {
+	if size <= 0 || Degraded() {
+		return nil
+	}
+
+	snapshot := memlockBudget.Load()
+	if snapshot == nil {
+		snapshot = readMemlockBudget()
+	}
+
+	return snapshot.fits(size)
+}
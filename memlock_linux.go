@@ -0,0 +1,56 @@
+package mattress
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+
+	"golang.org/x/sys/unix"
+)
+
+// capIPCLock is the bit of CAP_IPC_LOCK within the capability sets of /proc/self/status.
+const capIPCLock = 14
+
+// memlockStatus reads RLIMIT_MEMLOCK, along with the locked memory and effective
+// capabilities reported by /proc/self/status.
+func memlockStatus() (Environment, error) {
+	var limit unix.Rlimit
+	if err := unix.Getrlimit(unix.RLIMIT_MEMLOCK, &limit); err != nil {
+		return Environment{}, fmt.Errorf("mattress: reading RLIMIT_MEMLOCK: %w", err)
+	}
+
+	status, err := os.ReadFile("/proc/self/status")
+	if err != nil {
+		return Environment{}, err
+	}
+
+	env := Environment{MemlockLimit: limit.Cur, Unlimited: limit.Cur == unix.RLIM_INFINITY}
+
+	scanner := bufio.NewScanner(bytes.NewReader(status))
+	for scanner.Scan() {
+		key, value, _ := bytes.Cut(scanner.Bytes(), []byte(":"))
+		value = bytes.TrimSpace(value)
+
+		switch string(key) {
+		case "VmLck":
+			kb, err := strconv.ParseUint(string(bytes.TrimSuffix(value, []byte(" kB"))), 10, 64)
+			if err != nil {
+				return Environment{}, fmt.Errorf("mattress: malformed VmLck in /proc/self/status: %w", err)
+			}
+			env.MemlockUsed = kb * 1024
+		case "CapEff":
+			caps, err := strconv.ParseUint(string(value), 16, 64)
+			if err != nil {
+				return Environment{}, fmt.Errorf("mattress: malformed CapEff in /proc/self/status: %w", err)
+			}
+			// CAP_IPC_LOCK exempts the process from RLIMIT_MEMLOCK entirely.
+			if caps&(1<<capIPCLock) != 0 {
+				env.Unlimited = true
+			}
+		}
+	}
+
+	return env, nil
+}
@@ -0,0 +1,81 @@
+package mattress_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/sys/unix"
+
+	m "github.com/garrettladley/mattress"
+)
+
+func TestConstructorsMemlockExhausted(t *testing.T) {
+	// Creating a Secret initializes memguard, which locks memory of its own, before the
+	// limit is lowered.
+	secret, err := m.NewSecret("warm-up")
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret.Destroy()
+
+	env, err := m.CheckEnvironment()
+	if err != nil {
+		t.Skipf("locked-memory budget cannot be determined: %v", err)
+	}
+	if env.Unlimited {
+		t.Skip("locked memory is not limited, such as with CAP_IPC_LOCK")
+	}
+
+	var limit unix.Rlimit
+	if err := unix.Getrlimit(unix.RLIMIT_MEMLOCK, &limit); err != nil {
+		t.Fatal(err)
+	}
+
+	// Leave no room for another page to be locked.
+	lowered := limit
+	lowered.Cur = env.MemlockUsed
+	if err := unix.Setrlimit(unix.RLIMIT_MEMLOCK, &lowered); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := unix.Setrlimit(unix.RLIMIT_MEMLOCK, &limit); err != nil {
+			t.Error(err)
+		}
+	})
+
+	for _, tt := range []struct {
+		name string
+		new  func() (interface{ Destroy() }, error)
+	}{
+		{
+			name: "NewSecret",
+			new: func() (interface{ Destroy() }, error) {
+				return m.NewSecret("hunter2")
+			},
+		},
+		{
+			name: "NewSecretString",
+			new: func() (interface{ Destroy() }, error) {
+				return m.NewSecretString("hunter2")
+			},
+		},
+		{
+			name: "NewSecretFromReader",
+			new: func() (interface{ Destroy() }, error) {
+				return m.NewSecretFromReader(strings.NewReader("hunter2"), 0)
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			secret, err := tt.new()
+			if err == nil {
+				secret.Destroy()
+			}
+
+			if !errors.Is(err, m.ErrMemlockExhausted) {
+				t.Fatalf("got %v, want ErrMemlockExhausted", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package mattress
+
+import "errors"
+
+// memlockStatus is unsupported on platforms other than Linux.
+func memlockStatus() (Environment, error) {
+	return Environment{}, errors.ErrUnsupported
+}
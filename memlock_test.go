@@ -0,0 +1,52 @@
+package mattress
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestMemlockSnapshotFits(t *testing.T) {
+	page := uint64(os.Getpagesize())
+
+	for _, tt := range []struct {
+		name     string
+		snapshot memlockSnapshot
+		size     int
+		want     error
+	}{
+		{
+			name:     "available",
+			snapshot: memlockSnapshot{env: Environment{MemlockLimit: 4 * page, MemlockUsed: 2 * page}, ok: true},
+			size:     int(page),
+		},
+		{
+			name:     "rounded up to whole pages",
+			snapshot: memlockSnapshot{env: Environment{MemlockLimit: 4 * page, MemlockUsed: 3 * page}, ok: true},
+			size:     int(page) + 1,
+			want:     ErrMemlockExhausted,
+		},
+		{
+			name:     "exhausted",
+			snapshot: memlockSnapshot{env: Environment{MemlockLimit: 4 * page, MemlockUsed: 4 * page}, ok: true},
+			size:     1,
+			want:     ErrMemlockExhausted,
+		},
+		{
+			name:     "unlimited",
+			snapshot: memlockSnapshot{env: Environment{MemlockLimit: page, MemlockUsed: page, Unlimited: true}, ok: true},
+			size:     int(page),
+		},
+		{
+			name:     "undetermined",
+			snapshot: memlockSnapshot{},
+			size:     int(page),
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.snapshot.fits(tt.size); !errors.Is(err, tt.want) {
+				t.Errorf("fits(%d) = %v, want %v", tt.size, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,13 @@
+package mattress
+
+// noCopy may be added to structs which must not be copied after first use, so that the
+// copylocks check of go vet reports any copies.
+//
+// See https://golang.org/issues/8005#issuecomment-190753527 for details.
+type noCopy struct{}
+
+// Lock is a no-op used by the copylocks check of go vet.
+func (*noCopy) Lock() {}
+
+// Unlock is a no-op used by the copylocks check of go vet.
+func (*noCopy) Unlock() {}
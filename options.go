@@ -0,0 +1,171 @@
+package mattress
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// Option configures how a Secret is constructed.
+type Option func(*config)
+
+// config holds the settings accumulated from the Options passed to a constructor.
+type config struct {
+	sealed         bool          // sealed keeps the data in an Enclave between exposures
+	overwriteEnv   bool          // overwriteEnv wipes environment variables from the initial environment block
+	sizeLimit      int64         // sizeLimit caps the number of bytes read from a file or reader
+	strictPerms    bool          // strictPerms refuses to read world-readable files
+	maxExposures   int           // maxExposures destroys the Secret once it has been exposed this many times
+	ttl            time.Duration // ttl destroys the Secret once it has elapsed
+	cachedExposure bool          // cachedExposure keeps a decoded copy in guarded memory
+	label          string        // label names the Secret in diagnostics
+	sensitive      []string      // sensitive are the key patterns LoadDotenv seals into Secrets
+	pool           *Pool         // pool bounds the number of Secrets held open at once
+	chunkSize      int           // chunkSize splits larger data across Enclaves of at most this many bytes
+	dpapi          bool          // dpapi encrypts the data with CryptProtectMemory on Windows
+	codec          any           // codec is the Codec set by WithCodec, if any
+	noCleanup      bool          // noCleanup skips destroying the guarded memory once the Secret is garbage collected
+	compress       bool          // compress compresses the data before securing it
+	rateLimit      int           // rateLimit is the number of exposures admitted per ratePer
+	ratePer        time.Duration // ratePer is the period over which rateLimit exposures are admitted
+	err            error         // err reports an invalid option, failing the constructor it was passed to
+	ownKey         bool          // ownKey seals the data under a key of its own, once Rekey has been called
+}
+
+// newConfig applies opts on top of the default configuration.
+func newConfig(opts []Option) config {
+	var cfg config
+
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return cfg
+}
+
+// WithSealedStorage keeps the data encrypted within a memguard.Enclave for the
+// lifetime of the Secret, decrypting it into guarded memory only for the duration of
+// each exposure. This shrinks the window during which plaintext is resident in memory
+// at the cost of a decryption on every exposure.
+func WithSealedStorage() Option {
+	return func(c *config) {
+		c.sealed = true
+	}
+}
+
+// WithEnvOverwrite causes NewSecretFromEnv to additionally overwrite the variable's
+// value within the environment block the process was started with, which remains
+// visible through /proc/self/environ even after the variable is unset. It is only
+// supported on Linux and is ignored elsewhere.
+func WithEnvOverwrite() Option {
+	return func(c *config) {
+		c.overwriteEnv = true
+	}
+}
+
+// WithSizeLimit causes NewSecretFromFile to fail with ErrSecretTooLarge rather than
+// read more than limit bytes, preventing unbounded consumption of locked memory.
+func WithSizeLimit(limit int64) Option {
+	return func(c *config) {
+		c.sizeLimit = limit
+	}
+}
+
+// WithStrictPermissions causes NewSecretFromFile to fail with ErrInsecurePermissions
+// rather than read a file which is readable by any user. It is ignored on Windows,
+// where file permissions are not expressed as Unix mode bits.
+func WithStrictPermissions() Option {
+	return func(c *config) {
+		c.strictPerms = true
+	}
+}
+
+// WithTTL causes the Secret to destroy itself once ttl has elapsed since its data was
+// set, after which exposures fail with ErrSecretExpired. The data is wiped when the
+// deadline passes, not merely on the next exposure.
+func WithTTL(ttl time.Duration) Option {
+	return func(c *config) {
+		c.ttl = ttl
+	}
+}
+
+// WithMaxExposures causes the Secret to destroy itself as soon as its data has been
+// exposed n times, after which exposures fail with ErrSecretExpired. Each call to
+// Expose, ExposeErr, WithExposed, or WithPlaintext counts as one exposure; operations
+// which never hand out the plaintext, such as Equal, Fingerprint, HMAC, Seal, and
+// redaction, do not.
+func WithMaxExposures(n int) Option {
+	return func(c *config) {
+		c.maxExposures = n
+	}
+}
+
+// WithCachedExposure keeps a decoded copy of the data in a second LockedBuffer after
+// the first exposure, so that later exposures copy it out rather than decoding it
+// again. It applies to strings and byte slices, whose contents are cached and copied
+// out on each exposure, and to types whose values contain no pointers, such as
+// integers, fixed-size arrays, and structs of them, since only those can be held in
+// guarded memory in decoded form. It has no effect on other types, such as maps or
+// structs holding strings. Flush drops the cached copy.
+func WithCachedExposure() Option {
+	return func(c *config) {
+		c.cachedExposure = true
+	}
+}
+
+// WithLabel attaches a human-readable name to the Secret, such as "db-password", so
+// that diagnostics can identify it without revealing its content. The label is
+// rendered by String and the formatting verbs as [SECRET:label], and is included in
+// the errors returned by exposures, in ExposeEvents, and in the registry enabled by
+// TrackSecrets. Labels are not secret and must not be derived from the data.
+func WithLabel(label string) Option {
+	return func(c *config) {
+		c.label = label
+	}
+}
+
+// WithSensitivePatterns causes LoadDotenv to seal the values of keys matching any of
+// patterns, in the syntax of path.Match, rather than DefaultSensitivePatterns.
+func WithSensitivePatterns(patterns ...string) Option {
+	return func(c *config) {
+		c.sensitive = patterns
+	}
+}
+
+// WithoutFinalizer skips attaching a cleanup, with runtime.AddCleanup, which destroys
+// the guarded memory holding the data once the Secret is garbage collected. It suits
+// applications which destroy every Secret explicitly, such as with defer, and want
+// garbage collection to do no work on their behalf. memguard keeps guarded memory
+// reachable until it is destroyed, so a Secret created with WithoutFinalizer that is
+// never destroyed holds its memory, and its data, until Purge or SafeExit.
+func WithoutFinalizer() Option {
+	return func(c *config) {
+		c.noCleanup = true
+	}
+}
+
+// WithCodec serializes the data with codec rather than encoding/gob. It applies to the
+// constructors which otherwise default to GobCodec, such as NewSecret, LoadSecret, and
+// ImportSecret, which fail if codec serializes a type other than that of the Secret;
+// their WithCodec counterparts, such as NewSecretWithCodec, use the Codec they are
+// given instead.
+func WithCodec[T any](codec Codec[T]) Option {
+	return func(c *config) {
+		c.codec = codec
+	}
+}
+
+// codecFor returns the Codec set by WithCodec, or GobCodec if none was set. It returns
+// an error if the Codec set by WithCodec does not serialize T.
+func codecFor[T any](cfg config) (Codec[T], error) {
+	if cfg.codec == nil {
+		return GobCodec[T]{}, nil
+	}
+
+	codec, ok := cfg.codec.(Codec[T])
+	if !ok {
+		return nil, fmt.Errorf("mattress: WithCodec was given a %T, which does not serialize %s", cfg.codec, reflect.TypeFor[T]())
+	}
+
+	return codec, nil
+}
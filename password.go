@@ -0,0 +1,128 @@
+package mattress
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// passwordSaltLen is the length in bytes of the random salt generated by HashPassword.
+const passwordSaltLen = 16
+
+// HashPassword hashes password with Argon2id using DefaultArgon2idParams and a random
+// salt, returning the hash in the PHC string format understood by VerifyPassword and
+// other Argon2 implementations. The password is only exposed for the duration of the
+// derivation. HashPassword fails if DefaultArgon2idParams have been raised beyond what
+// VerifyPassword accepts.
+func HashPassword(password *Secret[string]) (string, error) {
+	if !argon2idAccepted(DefaultArgon2idParams) {
+		return "", errors.New("mattress: DefaultArgon2idParams exceed the maximum VerifyPassword accepts")
+	}
+
+	salt := make([]byte, passwordSaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	params := DefaultArgon2idParams
+
+	key, err := DeriveKey(password, salt, params)
+	if err != nil {
+		return "", err
+	}
+	defer key.Destroy()
+
+	var hash string
+
+	err = key.WithPlaintext(func(plaintext []byte) error {
+		hash = fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+			argon2.Version, params.Memory, params.Time, params.Threads,
+			base64.RawStdEncoding.EncodeToString(salt),
+			base64.RawStdEncoding.EncodeToString(plaintext),
+		)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return hash, nil
+}
+
+// VerifyPassword reports whether candidate matches hash, which may be an Argon2id hash
+// in PHC string format, as produced by HashPassword, or a bcrypt hash. The comparison
+// is performed in constant time and the candidate is only exposed for the duration of
+// the check. ErrInvalidHash is returned if hash cannot be parsed, or if its Argon2id
+// parameters exceed 1 GiB of memory, 16 passes, or 64 threads.
+func VerifyPassword(hash string, candidate *Secret[string]) (bool, error) {
+	if strings.HasPrefix(hash, "$2") {
+		return verifyBcrypt(hash, candidate)
+	}
+
+	return verifyArgon2id(hash, candidate)
+}
+
+// verifyArgon2id verifies candidate against an Argon2id hash in PHC string format.
+func verifyArgon2id(hash string, candidate *Secret[string]) (bool, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[0] != "" || parts[1] != "argon2id" {
+		return false, ErrInvalidHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false, ErrInvalidHash
+	}
+
+	params := KDFParams{KDF: Argon2id}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Time, &params.Threads); err != nil || !argon2idAccepted(params) {
+		return false, ErrInvalidHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false, ErrInvalidHash
+	}
+
+	want, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(want) == 0 {
+		return false, ErrInvalidHash
+	}
+
+	params.KeyLen = uint32(len(want))
+
+	key, err := DeriveKey(candidate, salt, params)
+	if err != nil {
+		return false, err
+	}
+	defer key.Destroy()
+
+	return key.EqualBytes(want), nil
+}
+
+// verifyBcrypt verifies candidate against a bcrypt hash.
+func verifyBcrypt(hash string, candidate *Secret[string]) (bool, error) {
+	var ok bool
+
+	err := candidate.WithPlaintext(func(plaintext []byte) error {
+		switch err := bcrypt.CompareHashAndPassword([]byte(hash), plaintext); err {
+		case nil:
+			ok = true
+		case bcrypt.ErrMismatchedHashAndPassword:
+		default:
+			return fmt.Errorf("%w: %w", ErrInvalidHash, err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return ok, nil
+}
@@ -0,0 +1,43 @@
+package mattress_test
+
+import (
+	"errors"
+	"testing"
+
+	m "github.com/garrettladley/mattress"
+)
+
+func TestVerifyPasswordExcessiveParams(t *testing.T) {
+	candidate, err := m.NewSecret("hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer candidate.Destroy()
+
+	for _, hash := range []string{
+		"$argon2id$v=19$m=4294967295,t=1,p=1$c2FsdHNhbHRzYWx0c2FsdA$aGFzaGhhc2hoYXNoaGFzaA",
+		"$argon2id$v=19$m=65536,t=4294967295,p=1$c2FsdHNhbHRzYWx0c2FsdA$aGFzaGhhc2hoYXNoaGFzaA",
+		"$argon2id$v=19$m=65536,t=1,p=255$c2FsdHNhbHRzYWx0c2FsdA$aGFzaGhhc2hoYXNoaGFzaA",
+	} {
+		if ok, err := m.VerifyPassword(hash, candidate); ok || !errors.Is(err, m.ErrInvalidHash) {
+			t.Errorf("VerifyPassword(%q) = %t, %v, want ErrInvalidHash", hash, ok, err)
+		}
+	}
+}
+
+func TestHashPasswordRoundTrip(t *testing.T) {
+	password, err := m.NewSecret("hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer password.Destroy()
+
+	hash, err := m.HashPassword(password)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ok, err := m.VerifyPassword(hash, password); !ok || err != nil {
+		t.Errorf("VerifyPassword(%q) = %t, %v, want true", hash, ok, err)
+	}
+}
@@ -0,0 +1,196 @@
+package mattress
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// persistMagic prefixes files written by Save, identifying the format and its version.
+var persistMagic = [4]byte{'M', 'T', 'R', 'P'}
+
+// persistHeaderLen is the length of the header of files written by Save: the magic,
+// the Argon2id memory, time, and threads parameters, the salt, and the nonce.
+const persistHeaderLen = len(persistMagic) + 4 + 4 + 1 + passwordSaltLen + chacha20poly1305.NonceSizeX
+
+// Save encrypts the data held by the Secret with XChaCha20-Poly1305 under a key derived
+// from passphrase with Argon2id, and atomically writes it to the file at path with
+// permissions 0600. The file can be restored with LoadSecret, and the data is never
+// written to disk in plaintext. Save fails if DefaultArgon2idParams have been raised
+// beyond what LoadSecret accepts: 1 GiB of memory, 16 passes, and 64 threads.
+func (s *Secret[T]) Save(path string, passphrase *Secret[string]) error {
+	if !argon2idAccepted(DefaultArgon2idParams) {
+		return errors.New("mattress: DefaultArgon2idParams exceed the maximum LoadSecret accepts")
+	}
+
+	header := make([]byte, 0, persistHeaderLen)
+	header = append(header, persistMagic[:]...)
+	header = binary.BigEndian.AppendUint32(header, DefaultArgon2idParams.Memory)
+	header = binary.BigEndian.AppendUint32(header, DefaultArgon2idParams.Time)
+	header = append(header, DefaultArgon2idParams.Threads)
+
+	salt := make([]byte, passwordSaltLen+chacha20poly1305.NonceSizeX)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+
+	header = append(header, salt...)
+
+	var ciphertext []byte
+
+	err := withPersistAEAD(passphrase, header, func(aead cipher.AEAD, nonce []byte) error {
+		return s.withBytes(func(b []byte) error {
+			ciphertext = aead.Seal(header, nonce, b, header)
+			return nil
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	return writeAtomic(path, ciphertext)
+}
+
+// writeAtomic writes data to the file at path with permissions 0600, via a temporary
+// file which is renamed over path so that a partially written file is never seen.
+func writeAtomic(path string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if err := f.Chmod(0o600); err != nil {
+		f.Close()
+		return err
+	}
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), path)
+}
+
+// LoadSecret restores a Secret from the file at path, written by Save with the same
+// passphrase. The data is decrypted directly into guarded memory and decoded with
+// GobCodec, or the Codec set by WithCodec, so Secrets created with a different Codec,
+// such as by NewSecretString, must be loaded with that Codec. ErrDecryptionFailed is
+// returned if the passphrase is wrong or the file has been tampered with, including if
+// its Argon2id parameters exceed the maximum Save accepts, and an error wrapping
+// ErrSecretNotFound if the file does not exist.
+func LoadSecret[T any](path string, passphrase *Secret[string], opts ...Option) (*Secret[T], error) {
+	codec, err := codecFor[T](newConfig(opts))
+	if err != nil {
+		return nil, err
+	}
+
+	return LoadSecretWithCodec(path, passphrase, codec, opts...)
+}
+
+// LoadSecretWithCodec restores a Secret from the file at path as described by
+// LoadSecret, using codec to decode the data.
+func LoadSecretWithCodec[T any](path string, passphrase *Secret[string], codec Codec[T], opts ...Option) (*Secret[T], error) {
+	cfg := newConfig(opts)
+
+	raw, err := readPersisted(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(raw) < persistHeaderLen+chacha20poly1305.Overhead || !bytes.HasPrefix(raw, persistMagic[:]) {
+		return nil, ErrDecryptionFailed
+	}
+
+	header, ciphertext := raw[:persistHeaderLen], raw[persistHeaderLen:]
+
+	buffer, err := newGuardedBuffer(len(ciphertext) - chacha20poly1305.Overhead)
+	if err != nil {
+		return nil, err
+	}
+
+	err = withPersistAEAD(passphrase, header, func(aead cipher.AEAD, nonce []byte) error {
+		// Open decrypts directly into the guarded buffer.
+		if _, err := aead.Open(buffer.Bytes()[:0], nonce, ciphertext, header); err != nil {
+			return ErrDecryptionFailed
+		}
+		return nil
+	})
+	if err != nil {
+		buffer.Destroy()
+		return nil, err
+	}
+
+	store, err := newStorageFromBuffer(buffer, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return newSecret(store, codec, cfg), nil
+}
+
+// withPersistAEAD derives the key described by header from passphrase and calls fn
+// with an XChaCha20-Poly1305 AEAD keyed by it and the nonce from header.
+func withPersistAEAD(passphrase *Secret[string], header []byte, fn func(aead cipher.AEAD, nonce []byte) error) error {
+	offset := len(persistMagic)
+
+	params := DefaultArgon2idParams
+	params.Memory = binary.BigEndian.Uint32(header[offset:])
+	params.Time = binary.BigEndian.Uint32(header[offset+4:])
+	params.Threads = header[offset+8]
+	params.KeyLen = chacha20poly1305.KeySize
+
+	if !argon2idAccepted(params) {
+		return fmt.Errorf("%w: Argon2id parameters exceed the accepted maximum", ErrDecryptionFailed)
+	}
+
+	salt := header[offset+9 : offset+9+passwordSaltLen]
+	nonce := header[offset+9+passwordSaltLen:]
+
+	key, err := DeriveKey(passphrase, salt, params)
+	if err != nil {
+		return err
+	}
+	defer key.Destroy()
+
+	return key.WithExposed(func(k []byte) error {
+		aead, err := chacha20poly1305.NewX(k)
+		if err != nil {
+			return err
+		}
+
+		return fn(aead, nonce)
+	})
+}
+
+// readPersisted reads the file at path, returning an error wrapping ErrSecretNotFound if
+// it does not exist.
+func readPersisted(path string) ([]byte, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("%w: %w", ErrSecretNotFound, err)
+		}
+		return nil, err
+	}
+
+	return raw, nil
+}
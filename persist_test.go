@@ -0,0 +1,72 @@
+package mattress_test
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	m "github.com/garrettladley/mattress"
+)
+
+// FuzzLoadSecret checks that LoadSecret rejects rather than panics on files with
+// malformed headers, and that any file it accepts holds what Save wrote.
+func FuzzLoadSecret(f *testing.F) {
+	// Cheap Argon2id parameters keep each derivation fast enough to fuzz.
+	defaults := m.DefaultArgon2idParams
+	m.DefaultArgon2idParams.Memory, m.DefaultArgon2idParams.Time, m.DefaultArgon2idParams.Threads = 64, 1, 1
+	f.Cleanup(func() { m.DefaultArgon2idParams = defaults })
+
+	passphrase, err := m.NewSecret("correct horse battery staple")
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Cleanup(passphrase.Destroy)
+
+	secret, err := m.NewSecret("hunter2")
+	if err != nil {
+		f.Fatal(err)
+	}
+	defer secret.Destroy()
+
+	path := filepath.Join(f.TempDir(), "secret")
+	if err := secret.Save(path, passphrase); err != nil {
+		f.Fatal(err)
+	}
+
+	saved, err := os.ReadFile(path)
+	if err != nil {
+		f.Fatal(err)
+	}
+
+	f.Add(saved)
+	f.Add(saved[:len(saved)-1])
+	f.Add(saved[:4])
+	f.Add([]byte{})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// Parameters within the accepted maximum but beyond the seed's only slow the
+		// fuzzer down, since the passphrase cannot match them.
+		if len(data) >= 13 {
+			memory, time := binary.BigEndian.Uint32(data[4:]), binary.BigEndian.Uint32(data[8:])
+			if memory > 256 && memory <= 1<<20 || time > 2 && time <= 16 {
+				t.Skip()
+			}
+		}
+
+		path := filepath.Join(t.TempDir(), "secret")
+		if err := os.WriteFile(path, data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		loaded, err := m.LoadSecret[string](path, passphrase)
+		if err != nil {
+			return
+		}
+		defer loaded.Destroy()
+
+		if got, err := loaded.ExposeErr(); err != nil || got != "hunter2" {
+			t.Fatalf("loading %x: got %q, %v", data, got, err)
+		}
+	})
+}
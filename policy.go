@@ -0,0 +1,36 @@
+package mattress
+
+import "sync/atomic"
+
+// SerializationPolicy determines how a Secret responds when a serialization library,
+// such as encoding/json, asks it to marshal itself.
+type SerializationPolicy int32
+
+const (
+	// RedactOnSerialize serializes a Secret as Placeholder. This is the default.
+	RedactOnSerialize SerializationPolicy = iota
+
+	// RefuseOnSerialize causes serialization of a Secret to fail with
+	// ErrSerializationRefused.
+	RefuseOnSerialize
+)
+
+// serializationPolicy holds the package-wide SerializationPolicy.
+var serializationPolicy atomic.Int32
+
+// SetSerializationPolicy sets the package-wide SerializationPolicy, applying to every
+// Secret. It is safe to call concurrently, but is intended to be called once during
+// program initialization.
+func SetSerializationPolicy(policy SerializationPolicy) {
+	serializationPolicy.Store(int32(policy))
+}
+
+// redactOrRefuse returns the redacted form of a Secret, as produced by redacted, unless
+// the current SerializationPolicy is RefuseOnSerialize.
+func redactOrRefuse(redacted func() ([]byte, error)) ([]byte, error) {
+	if SerializationPolicy(serializationPolicy.Load()) == RefuseOnSerialize {
+		return nil, ErrSerializationRefused
+	}
+
+	return redacted()
+}
@@ -0,0 +1,158 @@
+package mattress
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/awnumar/memguard"
+)
+
+// Pool bounds the number of Secrets whose data is held open in guarded memory at once,
+// for services holding many more Secrets, such as per-tenant credentials, than their
+// locked-memory budget allows. Secrets created with WithPool keep their data sealed
+// within a memguard.Enclave, as WithSealedStorage does, and decrypt it into guarded
+// memory on exposure. Rather than being wiped again at once, the decrypted data is kept
+// open, so that later exposures are as cheap as for an unsealed Secret, until more than
+// the limit of the Pool are open, when the least recently exposed is resealed.
+//
+// Resealing is transparent: the next exposure of a resealed Secret decrypts it again.
+// Secrets being exposed are never resealed, so the limit may be exceeded while more
+// than that many are exposed at once. A Pool is safe for concurrent use.
+type Pool struct {
+	limit int        // limit is the number of Secrets which may be open at once
+	open  list.List  // open holds the open storages, most recently exposed first
+	lock  sync.Mutex // synchronize access to the fields above and to pooled storages
+}
+
+// NewPool returns a Pool keeping at most limit Secrets open at once. A limit below one
+// is treated as one.
+func NewPool(limit int) *Pool {
+	return &Pool{limit: max(limit, 1)}
+}
+
+// WithPool adds the Secret to pool, as described by Pool. It takes precedence over
+// WithSealedStorage, which it implies.
+func WithPool(pool *Pool) Option {
+	return func(c *config) {
+		c.pool = pool
+	}
+}
+
+// Open returns the number of Secrets in the Pool whose data is currently open.
+func (p *Pool) Open() int {
+	p.lock.Lock()         // Lock before reading the open list
+	defer p.lock.Unlock() // Ensure the lock is Unlocked when the method returns
+
+	return p.open.Len()
+}
+
+// Reseal reseals every open Secret in the Pool which is not being exposed.
+func (p *Pool) Reseal() {
+	p.lock.Lock()         // Lock before resealing the open storages
+	defer p.lock.Unlock() // Ensure the lock is Unlocked when the method returns
+
+	p.evict(0)
+}
+
+// evict reseals the least recently exposed open storages which are not being exposed
+// until at most limit remain open. The caller must hold the lock.
+func (p *Pool) evict(limit int) {
+	for elem := p.open.Back(); elem != nil && p.open.Len() > limit; {
+		prev := elem.Prev()
+
+		if s := elem.Value.(*pooledStorage); s.views == 0 {
+			s.close()
+		}
+
+		elem = prev
+	}
+}
+
+// pooledStorage keeps the data within a memguard.Enclave, decrypting it into a
+// LockedBuffer which the Pool keeps open until the storage is evicted.
+type pooledStorage struct {
+	pool    *Pool                  // pool bounds the open storages
+	enclave *memguard.Enclave      // enclave holds the sealed data
+	buffer  *memguard.LockedBuffer // buffer holds the open data, if open
+	elem    *list.Element          // elem is the entry in the open list, if open
+	views   int                    // views counts the outstanding views of buffer
+}
+
+// newPooledStorage seals b into an Enclave held by pool, wiping b in the process.
+func newPooledStorage(pool *Pool, b []byte) *pooledStorage {
+	return &pooledStorage{pool: pool, enclave: memguard.NewEnclave(b)}
+}
+
+func (s *pooledStorage) view() ([]byte, func(), error) {
+	s.pool.lock.Lock()         // Lock before opening the storage
+	defer s.pool.lock.Unlock() // Ensure the lock is Unlocked when the method returns
+
+	if s.enclave == nil {
+		return nil, nil, ErrDestroyed
+	}
+
+	// The buffer is only destroyed behind the Pool's back by Purge.
+	if s.buffer != nil && !s.buffer.IsAlive() {
+		s.close()
+	}
+
+	if s.buffer == nil {
+		buffer, err := openEnclave(s.enclave)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		buffer.Freeze()
+
+		s.buffer = buffer
+		s.elem = s.pool.open.PushFront(s)
+	} else {
+		s.pool.open.MoveToFront(s.elem)
+	}
+
+	s.views++
+	s.pool.evict(s.pool.limit)
+
+	return s.buffer.Bytes(), s.release, nil
+}
+
+// release ends a view, resealing the least recently exposed storages if the Pool is
+// over its limit.
+func (s *pooledStorage) release() {
+	s.pool.lock.Lock()         // Lock before ending the view
+	defer s.pool.lock.Unlock() // Ensure the lock is Unlocked when the method returns
+
+	s.views--
+	s.pool.evict(s.pool.limit)
+}
+
+// close wipes the open data and removes the storage from the open list, leaving the
+// Enclave intact. The caller must hold the lock of the Pool.
+func (s *pooledStorage) close() {
+	if s.buffer == nil {
+		return
+	}
+
+	s.buffer.Destroy()
+	s.pool.open.Remove(s.elem)
+	s.buffer, s.elem = nil, nil
+}
+
+func (s *pooledStorage) destroy() {
+	s.pool.lock.Lock()         // Lock before closing the storage
+	defer s.pool.lock.Unlock() // Ensure the lock is Unlocked when the method returns
+
+	s.close()
+	s.enclave = nil
+}
+
+func (s *pooledStorage) size() int {
+	s.pool.lock.Lock()         // Lock before reading the Enclave
+	defer s.pool.lock.Unlock() // Ensure the lock is Unlocked when the method returns
+
+	if s.enclave == nil {
+		return 0
+	}
+
+	return s.enclave.Size()
+}
@@ -0,0 +1,192 @@
+package mattress
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// tagKey is the struct tag key read by Process.
+const tagKey = "mattress"
+
+// Process populates the *Secret fields of the struct v points to from DefaultRegistry,
+// as described by Registry.Process.
+func Process(v any) error {
+	return DefaultRegistry.Process(context.Background(), v)
+}
+
+// Process walks the struct v points to, including any nested structs and non-nil
+// pointers to structs, and populates every *Secret field tagged with the provider and
+// name to fetch it from, giving a one-call secure configuration loader:
+//
+//	type Config struct {
+//	  DBPassword *mattress.Secret[string] `mattress:"env=DB_PASSWORD"`
+//	  TLSKey     *mattress.Secret[[]byte] `mattress:"file=/etc/app/tls.key,sealed"`
+//	  APIToken   *mattress.Secret[string] `mattress:"vault=secret/data/app#token,optional"`
+//	}
+//
+// The tag names a registered Provider, followed by "=" and the name of the secret
+// within it, optionally followed by comma-separated flags:
+//
+//   - optional: a secret which does not exist leaves the field untouched rather than
+//     failing with ErrSecretNotFound
+//   - sealed: the Secret is created with WithSealedStorage
+//
+// Fields may hold a Secret of string, []byte, or any type whose pointer implements
+// encoding.TextUnmarshaler, which is given the fetched text. A field which already
+// holds a Secret has its data replaced in place, keeping the options it was created
+// with, as UnmarshalJSON does. Tags which are malformed, or attached to fields of any
+// other type, fail with ErrInvalidTag. If any field cannot be populated, the Secrets
+// created by this call are destroyed and their fields reset.
+func (r *Registry) Process(ctx context.Context, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("mattress: Process requires a non-nil pointer to a struct, not %T", v)
+	}
+
+	var created []reflect.Value
+
+	if err := r.process(ctx, rv.Elem(), &created); err != nil {
+		for _, field := range created {
+			field.Interface().(fetchable).Destroy()
+			field.SetZero()
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// process populates the tagged fields of the struct rv, recording the fields it sets
+// to newly created Secrets in created.
+func (r *Registry) process(ctx context.Context, rv reflect.Value, created *[]reflect.Value) error {
+	for i := 0; i < rv.NumField(); i++ {
+		field := rv.Field(i)
+		sf := rv.Type().Field(i)
+
+		if !sf.IsExported() {
+			continue
+		}
+
+		tag, tagged := sf.Tag.Lookup(tagKey)
+		if !tagged {
+			if err := r.processNested(ctx, field, created); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if !sf.Type.Implements(fetchableType) {
+			return fmt.Errorf("%w: field %s is a %s, not a *Secret", ErrInvalidTag, sf.Name, sf.Type)
+		}
+
+		provider, name, flags, err := parseTag(tag)
+		if err != nil {
+			return fmt.Errorf("%w: field %s: %w", ErrInvalidTag, sf.Name, err)
+		}
+
+		fetched, err := r.Fetch(ctx, provider, name)
+		if err != nil {
+			if flags.optional && errors.Is(err, ErrSecretNotFound) {
+				continue
+			}
+
+			return fmt.Errorf("mattress: field %s: %w", sf.Name, err)
+		}
+
+		if !field.IsNil() {
+			if err := field.Interface().(fetchable).setFetched(fetched, nil); err != nil {
+				return fmt.Errorf("mattress: field %s: %w", sf.Name, err)
+			}
+
+			continue
+		}
+
+		secret := reflect.New(sf.Type.Elem())
+
+		if err := secret.Interface().(fetchable).setFetched(fetched, &flags.cfg); err != nil {
+			return fmt.Errorf("mattress: field %s: %w", sf.Name, err)
+		}
+
+		field.Set(secret)
+		*created = append(*created, field)
+	}
+
+	return nil
+}
+
+// processNested descends into field if it is a struct or a non-nil pointer to one.
+func (r *Registry) processNested(ctx context.Context, field reflect.Value, created *[]reflect.Value) error {
+	switch {
+	case field.Kind() == reflect.Struct:
+		return r.process(ctx, field, created)
+	case field.Kind() == reflect.Pointer && !field.IsNil() && field.Elem().Kind() == reflect.Struct &&
+		!field.Type().Implements(fetchableType):
+		return r.process(ctx, field.Elem(), created)
+	default:
+		return nil
+	}
+}
+
+// tagFlags holds the flags parsed from a mattress struct tag.
+type tagFlags struct {
+	optional bool   // optional tolerates a secret which does not exist
+	cfg      config // cfg configures newly created Secrets
+}
+
+// parseTag parses a mattress struct tag of the form "provider=name[,flag...]".
+func parseTag(tag string) (string, string, tagFlags, error) {
+	var flags tagFlags
+
+	spec, rest, _ := strings.Cut(tag, ",")
+
+	provider, name, ok := strings.Cut(spec, "=")
+	if !ok || provider == "" || name == "" {
+		return "", "", flags, fmt.Errorf("%q is not of the form provider=name", spec)
+	}
+
+	if rest != "" {
+		for _, flag := range strings.Split(rest, ",") {
+			switch flag {
+			case "optional":
+				flags.optional = true
+			case "sealed":
+				flags.cfg.sealed = true
+			default:
+				return "", "", flags, fmt.Errorf("unknown flag %q", flag)
+			}
+		}
+	}
+
+	return provider, name, flags, nil
+}
+
+// fetchable is implemented by every *Secret, allowing Process to populate Secrets of
+// any type through reflection.
+type fetchable interface {
+	setFetched(fetched *Secret[string], cfg *config) error
+	Destroy()
+}
+
+// fetchableType is the reflect.Type of fetchable.
+var fetchableType = reflect.TypeFor[fetchable]()
+
+// setFetched replaces the data held by the Secret with the text held by fetched, as
+// described by UnmarshalText, and destroys fetched. A new Secret is configured by cfg,
+// whereas the Secret keeps its existing Codec and options if cfg is nil.
+func (s *Secret[T]) setFetched(fetched *Secret[string], cfg *config) error {
+	defer fetched.Destroy()
+
+	codec, settings := s.textSettings()
+	if cfg != nil {
+		settings = *cfg
+	}
+
+	return fetched.WithPlaintext(func(text []byte) error {
+		return s.setText(text, codec, settings)
+	})
+}
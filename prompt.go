@@ -0,0 +1,159 @@
+package mattress
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"golang.org/x/term"
+)
+
+// Control characters handled while reading from a terminal in raw mode.
+const (
+	keyInterrupt = 0x03 // keyInterrupt is Ctrl-C
+	keyEOF       = 0x04 // keyEOF is Ctrl-D
+	keyBackspace = 0x08 // keyBackspace is Ctrl-H
+	keyDelete    = 0x7f // keyDelete is the backspace key on most terminals
+)
+
+// PromptSecret writes prompt to standard error and reads a line from standard input
+// into a new Secret, for CLI tools that ask for passphrases. When standard input is a
+// terminal, echo is disabled while reading. The input is read a byte at a time
+// directly into guarded memory, so it never forms a regular Go string or slice. It
+// returns ErrPromptInterrupted if the user presses Ctrl-C.
+func PromptSecret(prompt string, opts ...Option) (*Secret[string], error) {
+	return promptSecret(os.Stdin, os.Stderr, prompt, opts)
+}
+
+// promptSecret implements PromptSecret, reading from in and writing to out.
+func promptSecret(in *os.File, out io.Writer, prompt string, opts []Option) (*Secret[string], error) {
+	if _, err := fmt.Fprint(out, prompt); err != nil {
+		return nil, err
+	}
+
+	buffer, err := readPrompt(in, out)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := newConfig(opts)
+
+	store, err := newStorageFromBuffer(buffer, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return newSecret[string](store, StringCodec{}, cfg), nil
+}
+
+// readPrompt reads a line from in, disabling echo if in is a terminal.
+func readPrompt(in *os.File, out io.Writer) (guardedBuffer, error) {
+	fd := int(in.Fd())
+
+	if !term.IsTerminal(fd) {
+		return readLine(in, false)
+	}
+
+	// Raw mode disables echo, and delivers input byte by byte rather than a line at a
+	// time, so that editing keys must be handled by readLine.
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return nil, err
+	}
+
+	buffer, err := readLine(in, true)
+
+	if restoreErr := term.Restore(fd, state); restoreErr != nil && err == nil {
+		buffer.Destroy()
+		err = restoreErr
+	}
+
+	// The user's newline was not echoed, so move the cursor past the prompt.
+	fmt.Fprint(out, "\r\n")
+
+	return buffer, err
+}
+
+// readLine reads from r a byte at a time, directly into a LockedBuffer, until a line
+// ending or EOF. When raw is true, r is a terminal in raw mode and the backspace,
+// Ctrl-C and Ctrl-D keys are interpreted.
+func readLine(r io.Reader, raw bool) (guardedBuffer, error) {
+	buffer, err := newGuardedBuffer(os.Getpagesize())
+	if err != nil {
+		return nil, err
+	}
+
+	n := 0
+
+	for {
+		if n == buffer.Size() {
+			grown, err := grow(buffer)
+			if err != nil {
+				buffer.Destroy()
+				return nil, err
+			}
+			buffer = grown
+		}
+
+		// Read directly into the next free byte of the buffer.
+		next := buffer.Bytes()[n : n+1]
+
+		if _, err := io.ReadFull(r, next); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			buffer.Destroy()
+			return nil, err
+		}
+
+		c := next[0]
+
+		if c == '\n' || c == '\r' {
+			next[0] = 0
+			break
+		}
+
+		if raw {
+			switch c {
+			case keyInterrupt:
+				buffer.Destroy()
+				return nil, ErrPromptInterrupted
+			case keyEOF:
+				next[0] = 0
+				if n == 0 {
+					buffer.Destroy()
+					return nil, io.EOF
+				}
+				continue
+			case keyBackspace, keyDelete:
+				next[0] = 0
+				if n > 0 {
+					n--
+					buffer.Bytes()[n] = 0
+				}
+				continue
+			}
+		}
+
+		n++
+	}
+
+	defer buffer.Destroy()
+
+	return guardedCopy(buffer.Bytes()[:n])
+}
+
+// grow returns a LockedBuffer twice the size of buffer holding a copy of its
+// contents, destroying buffer. buffer is left intact if it cannot be grown.
+func grow(buffer guardedBuffer) (guardedBuffer, error) {
+	grown, err := newGuardedBuffer(buffer.Size() * 2)
+	if err != nil {
+		return nil, err
+	}
+
+	copy(grown.Bytes(), buffer.Bytes())
+	buffer.Destroy()
+
+	return grown, nil
+}
@@ -0,0 +1,42 @@
+package mattress
+
+import (
+	"errors"
+	"io"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestPromptSecretOptions(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	if _, err := io.WriteString(w, "hunter2\n"); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+
+	secret, err := promptSecret(r, io.Discard, "Password: ", []Option{WithTTL(10 * time.Millisecond), WithLabel("db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer secret.Destroy()
+
+	if got := secret.String(); got != "[SECRET:db]" {
+		t.Errorf("String() = %q, want %q", got, "[SECRET:db]")
+	}
+
+	if got, err := secret.ExposeErr(); err != nil || got != "hunter2" {
+		t.Fatalf("ExposeErr() = %q, %v, want %q", got, err, "hunter2")
+	}
+
+	time.Sleep(50 * time.Millisecond)
+
+	if _, err := secret.ExposeErr(); !errors.Is(err, ErrSecretExpired) {
+		t.Errorf("ExposeErr() after the TTL = %v, want ErrSecretExpired", err)
+	}
+}
@@ -0,0 +1,119 @@
+package mattress
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Provider sources secrets by name from an external system, such as environment
+// variables, files, or a secret manager.
+//
+// The returned Secret is owned by the caller, who is responsible for destroying it.
+// Providers should return an error wrapping ErrSecretNotFound if no secret called name
+// exists.
+type Provider interface {
+	Fetch(ctx context.Context, name string) (*Secret[string], error)
+}
+
+// ProviderFunc is an adapter allowing an ordinary function to be used as a Provider.
+type ProviderFunc func(ctx context.Context, name string) (*Secret[string], error)
+
+// Fetch calls f(ctx, name).
+func (f ProviderFunc) Fetch(ctx context.Context, name string) (*Secret[string], error) {
+	return f(ctx, name)
+}
+
+// Registry maps provider names, such as "env" or "vault", to Providers, allowing
+// secrets to be fetched from any registered source through a single API.
+type Registry struct {
+	providers map[string]Provider // providers maps names to registered Providers
+	lock      sync.RWMutex        // synchronize access to providers
+}
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{providers: make(map[string]Provider)}
+}
+
+// Register registers p under name, replacing any Provider previously registered under
+// the same name.
+func (r *Registry) Register(name string, p Provider) {
+	r.lock.Lock()         // Lock before writing the providers
+	defer r.lock.Unlock() // Ensure the lock is Unlocked when the method returns
+
+	r.providers[name] = p
+}
+
+// Unregister removes the Provider registered under name, if any.
+func (r *Registry) Unregister(name string) {
+	r.lock.Lock()         // Lock before writing the providers
+	defer r.lock.Unlock() // Ensure the lock is Unlocked when the method returns
+
+	delete(r.providers, name)
+}
+
+// Provider returns the Provider registered under name, if any.
+func (r *Registry) Provider(name string) (Provider, bool) {
+	r.lock.RLock()         // RLock before reading the providers
+	defer r.lock.RUnlock() // Ensure the lock is RUnlocked when the method returns
+
+	p, ok := r.providers[name]
+
+	return p, ok
+}
+
+// Providers returns the names of all registered Providers in sorted order.
+func (r *Registry) Providers() []string {
+	r.lock.RLock()         // RLock before reading the providers
+	defer r.lock.RUnlock() // Ensure the lock is RUnlocked when the method returns
+
+	names := make([]string, 0, len(r.providers))
+	for name := range r.providers {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// Fetch fetches the secret called name from the Provider registered under provider.
+// It returns an error wrapping ErrProviderNotFound if no such Provider is registered.
+func (r *Registry) Fetch(ctx context.Context, provider string, name string) (*Secret[string], error) {
+	p, ok := r.Provider(provider)
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrProviderNotFound, provider)
+	}
+
+	return p.Fetch(ctx, name)
+}
+
+// DefaultRegistry is the Registry used by the package-level Register and Fetch
+// functions. It comes with the following Providers registered:
+//
+//   - "env": EnvProvider
+//   - "file": FileProvider
+var DefaultRegistry = newDefaultRegistry()
+
+// newDefaultRegistry returns a Registry with the built-in Providers registered.
+func newDefaultRegistry() *Registry {
+	r := NewRegistry()
+
+	r.Register("env", EnvProvider{})
+	r.Register("file", FileProvider{})
+
+	return r
+}
+
+// Register registers p under name in DefaultRegistry.
+func Register(name string, p Provider) {
+	DefaultRegistry.Register(name, p)
+}
+
+// Fetch fetches the secret called name from the Provider registered under provider in
+// DefaultRegistry.
+func Fetch(ctx context.Context, provider string, name string) (*Secret[string], error) {
+	return DefaultRegistry.Fetch(ctx, provider, name)
+}
@@ -0,0 +1,32 @@
+package mattress
+
+import (
+	"os"
+
+	"github.com/awnumar/memguard"
+)
+
+// Purge wipes the data held by every live Secret and replaces the session key that
+// protects sealed Secrets, rather than relying on each Secret being destroyed or
+// garbage collected. Exposing a Secret after Purge fails with ErrDestroyed. Purge is
+// intended for fatal error paths and also wipes any memguard containers allocated
+// outside this package.
+func Purge() {
+	purgeMasked()
+	memguard.Purge()
+}
+
+// SafeExit purges every live Secret, as Purge does, and then exits the process with
+// code. It should be used in place of os.Exit, which skips deferred calls to Destroy
+// and never runs cleanups.
+func SafeExit(code int) {
+	purgeMasked()
+
+	// memguard.SafeExit allocates guarded memory, which is unavailable if Degraded.
+	if Degraded() {
+		memguard.Purge()
+		os.Exit(code)
+	}
+
+	memguard.SafeExit(code)
+}
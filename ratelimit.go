@@ -0,0 +1,88 @@
+package mattress
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// WithExposureRateLimit causes exposures of the Secret beyond n in any period of per to
+// fail with ErrExposureRateExceeded, as a tripwire against a compromised code path
+// exposing credentials in bulk. Exposures may come in bursts of up to n, after which
+// they are admitted at a steady rate of n per per. Rejected exposures expose nothing and
+// do not count against WithMaxExposures, but are reported to OnExpose hooks like any
+// other failed exposure, with an Err wrapping ErrExposureRateExceeded, so that alerts
+// can be raised from them. Operations which are not exposures, such as Equal,
+// Fingerprint, HMAC, and redaction, are never limited. n must be positive and per at
+// least n nanoseconds, so that exposures are spaced at least a nanosecond apart;
+// otherwise the constructor the option is passed to fails.
+func WithExposureRateLimit(n int, per time.Duration) Option {
+	return func(c *config) {
+		if n <= 0 || per < time.Duration(n) {
+			c.err = fmt.Errorf("mattress: invalid exposure rate limit of %d per %s", n, per)
+			return
+		}
+
+		c.rateLimit = n
+		c.ratePer = per
+	}
+}
+
+// rateEpoch is the origin of the times recorded by exposureLimiters, which are measured
+// against the monotonic clock so that changes to the wall clock do not affect them.
+var rateEpoch = time.Now()
+
+// exposureLimiter limits the rate of exposures of a Secret with the generic cell rate
+// algorithm, which only records when the next exposure would be due were exposures
+// evenly spaced, and admits an exposure provided that is no further ahead than the
+// burst allows. It is safe for concurrent use.
+type exposureLimiter struct {
+	due atomic.Int64 // due is when the next exposure is due, in nanoseconds since rateEpoch
+}
+
+// allow reports whether an exposure may take place now under a limit of n exposures
+// per per, recording it if so.
+func (l *exposureLimiter) allow(n int, per time.Duration) bool {
+	interval := per / time.Duration(n)
+	now := int64(time.Since(rateEpoch))
+
+	for {
+		due := l.due.Load()
+
+		next := max(due, now)
+		if next-now > int64(per-interval) {
+			return false
+		}
+
+		if l.due.CompareAndSwap(due, next+int64(interval)) {
+			return true
+		}
+	}
+}
+
+// reset forgets every exposure recorded.
+func (l *exposureLimiter) reset() {
+	l.due.Store(0)
+}
+
+// throttled returns view, failing with ErrExposureRateExceeded before anything is
+// viewed if the Secret was created with WithExposureRateLimit and has been exposed too
+// often. The caller must hold the lock.
+func (s *Secret[T]) throttled(view func(fn func(b []byte) error) error) func(fn func(b []byte) error) error {
+	if s.cfg.rateLimit == 0 {
+		return view
+	}
+
+	return func(fn func(b []byte) error) error {
+		// Exposures which would fail regardless are not recorded.
+		if err := s.viewable(); err != nil {
+			return err
+		}
+
+		if !s.limiter.allow(s.cfg.rateLimit, s.cfg.ratePer) {
+			return s.labelled(ErrExposureRateExceeded)
+		}
+
+		return view(fn)
+	}
+}
@@ -0,0 +1,248 @@
+package mattress
+
+import (
+	"errors"
+	"hash"
+	"io"
+	"time"
+
+	"github.com/awnumar/memguard"
+)
+
+// NewSecretFromReader initializes a new Secret with everything read from r until EOF,
+// such as a secret piped into the process on standard input. The data is read directly
+// into guarded memory, never passing through a slice on the regular heap. If r holds
+// more than limit bytes, reading stops and ErrSecretTooLarge is returned, preventing
+// unbounded consumption of locked memory; a non-positive limit disables this check.
+func NewSecretFromReader(r io.Reader, limit int64, opts ...Option) (*Secret[[]byte], error) {
+	cfg := newConfig(opts)
+
+	buffer, err := readLocked(r, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	store, err := newStorageFromBuffer(buffer, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return newSecret[[]byte](store, BytesCodec{}, cfg), nil
+}
+
+// readLocked reads r until EOF directly into a LockedBuffer. If limit is positive and
+// r holds more than limit bytes, it returns ErrSecretTooLarge.
+func readLocked(r io.Reader, limit int64) (guardedBuffer, error) {
+	if limit > 0 {
+		// Read one byte beyond the limit to detect readers which exceed it.
+		r = io.LimitReader(r, limit+1)
+	}
+
+	buffer, err := readAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if limit > 0 && int64(buffer.Size()) > limit {
+		buffer.Destroy()
+		return nil, ErrSecretTooLarge
+	}
+
+	return buffer, nil
+}
+
+// readAll reads r until EOF into a guardedBuffer.
+func readAll(r io.Reader) (guardedBuffer, error) {
+	var w guardedWriter
+
+	if _, err := w.ReadFrom(r); err != nil {
+		w.destroy()
+		return nil, err
+	}
+
+	return w.finish()
+}
+
+// readerChunk is the most bytes a Reader copies out of a Secret at once, unless the
+// Secret was created with WithChunkedStorage, when a whole chunk is copied.
+const readerChunk = 4096
+
+// Reader returns an io.ReadCloser streaming the encoded bytes held by the Secret, which
+// for Secrets using BytesCodec or StringCodec are the plaintext itself, so that large
+// secrets can be piped into parsers, such as PEM decoders and tar readers, without a
+// full plaintext copy. The bytes are copied out a small chunk at a time into guarded
+// memory, and each byte is wiped from it once read. Secrets created with
+// WithChunkedStorage are decrypted a chunk at a time, so that the whole value is never
+// held in plaintext at once.
+//
+// The stream counts as a single exposure on its first Read, and is reported to any
+// OnExpose hooks once it ends, with the Fingerprint of the bytes read. A Secret whose
+// exposure limit is reached by the stream is destroyed once the first chunk has been
+// copied, after which Read fails with ErrSecretExpired; use ExposeTo for such Secrets.
+// Read fails with ErrDestroyed if the Secret is destroyed or given new data mid-stream.
+// Close wipes any bytes not yet read.
+func (s *Secret[T]) Reader() io.ReadCloser {
+	r := &secretReader[T]{secret: s}
+	r.file, r.line = caller()
+
+	return r
+}
+
+// secretReader streams the encoded bytes held by a Secret.
+type secretReader[T any] struct {
+	secret  *Secret[T]    // secret is the Secret being read
+	store   storage       // store is the store being read, once the first chunk is copied
+	off     int           // off is the offset of the next chunk to copy
+	buffer  guardedBuffer // buffer holds the current chunk
+	pending []byte        // pending is the unread part of buffer
+	hash    hash.Hash     // hash accumulates the Fingerprint, if auditing
+	err     error         // err is returned by every Read once the stream has ended
+	file    string        // file is where Reader was called, for ExposeEvents
+	line    int           // line is where Reader was called, for ExposeEvents
+}
+
+// Read copies the next bytes of the stream into p, wiping them from the chunk.
+func (r *secretReader[T]) Read(p []byte) (int, error) {
+	if len(r.pending) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+
+		if err := r.fill(); err != nil {
+			r.end(err)
+			return 0, err
+		}
+	}
+
+	n := copy(p, r.pending)
+	memguard.WipeBytes(r.pending[:n])
+	r.pending = r.pending[n:]
+
+	return n, nil
+}
+
+// Close wipes any bytes not yet read. Later Reads fail with ErrDestroyed.
+func (r *secretReader[T]) Close() error {
+	r.end(ErrDestroyed)
+
+	return nil
+}
+
+// fill copies the next chunk of the Secret into buffer, counting the first as an
+// exposure, or returns io.EOF once the stream is exhausted.
+func (r *secretReader[T]) fill() error {
+	if r.buffer != nil {
+		r.buffer.Destroy()
+		r.buffer = nil
+	}
+
+	s := r.secret
+
+	if r.store == nil {
+		if auditing() {
+			r.hash = auditHash()
+		}
+
+		return s.countExposure(r.view, r.copy)
+	}
+
+	if s == nil {
+		return ErrUninitialized
+	}
+
+	s.lock.RLock()         // RLock before reading the store
+	defer s.lock.RUnlock() // Ensure the lock is RUnlocked when the method returns
+
+	// A Secret given new data mid-stream holds a different store.
+	if s.store != r.store {
+		return s.labelled(ErrDestroyed)
+	}
+
+	return r.view(r.copy)
+}
+
+// view calls fn with the encoded bytes held by the Secret from off, up to the end of
+// their chunk or readerChunk bytes. The caller must hold the lock of the Secret.
+func (r *secretReader[T]) view(fn func(b []byte) error) error {
+	s := r.secret
+
+	if err := s.viewable(); err != nil {
+		return err
+	}
+
+	if r.store == nil {
+		r.store = s.store
+	}
+
+	if r.off >= s.store.size() {
+		return io.EOF
+	}
+
+	if chunked, ok := s.store.(*chunkedStorage); ok {
+		b, release, err := chunked.from(r.off)
+		if err != nil {
+			return s.labelled(err)
+		}
+		defer release()
+
+		return fn(b)
+	}
+
+	return s.view(func(b []byte) error {
+		return fn(b[r.off:min(r.off+readerChunk, len(b))])
+	})
+}
+
+// copy copies b into a new chunk.
+func (r *secretReader[T]) copy(b []byte) error {
+	buffer, err := newViewBuffer(len(b))
+	if err != nil {
+		return err
+	}
+
+	copy(buffer.Bytes(), b)
+
+	if r.hash != nil {
+		r.hash.Write(b)
+	}
+
+	r.buffer, r.pending = buffer, buffer.Bytes()
+	r.off += len(b)
+
+	return nil
+}
+
+// end wipes the current chunk and ends the stream with err, reporting the exposure to
+// any OnExpose hooks if it was counted.
+func (r *secretReader[T]) end(err error) {
+	if r.buffer != nil {
+		r.buffer.Destroy()
+		r.buffer, r.pending = nil, nil
+	}
+
+	if r.err != nil {
+		return
+	}
+
+	r.err = err
+
+	if r.hash == nil {
+		return
+	}
+
+	var fingerprint Fingerprint
+	r.hash.Sum(fingerprint[:0])
+
+	ev := ExposeEvent{
+		Label:       r.secret.Label(),
+		Fingerprint: fingerprint,
+		File:        r.file,
+		Line:        r.line,
+		Time:        time.Now(),
+	}
+	if !errors.Is(err, io.EOF) {
+		ev.Err = err
+	}
+
+	emit(ev)
+}
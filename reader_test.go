@@ -0,0 +1,28 @@
+package mattress_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	m "github.com/garrettladley/mattress"
+)
+
+func TestNewSecretFromReader(t *testing.T) {
+	// Larger than a page, so that the buffer it is read into must grow.
+	data := bytes.Repeat([]byte("0123456789abcdef"), 1024)
+
+	secret, err := m.NewSecretFromReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer secret.Destroy()
+
+	if got, err := secret.ExposeErr(); err != nil || !bytes.Equal(got, data) {
+		t.Fatalf("ExposeErr() = %d bytes, %v, want %d bytes", len(got), err, len(data))
+	}
+
+	if _, err := m.NewSecretFromReader(bytes.NewReader(data), int64(len(data))-1); !errors.Is(err, m.ErrSecretTooLarge) {
+		t.Errorf("NewSecretFromReader beyond the limit = %v, want ErrSecretTooLarge", err)
+	}
+}
@@ -0,0 +1,151 @@
+package mattress
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/awnumar/memguard"
+)
+
+// Placeholder is the text that stands in for a Secret wherever it would otherwise be
+// rendered, such as in formatted output and logs.
+const Placeholder = "[SECRET]"
+
+// Redactable is implemented by values whose plaintext can be searched for within, and
+// masked out of, arbitrary output. Secret implements Redactable.
+type Redactable interface {
+	// WithPlaintext calls fn with the textual form of the plaintext. The slice is only
+	// valid for the duration of fn and must not be retained.
+	WithPlaintext(fn func(plaintext []byte) error) error
+}
+
+// plaintextViewer is implemented by Redactables which can provide their plaintext
+// without it counting as an exposure, as Secret does.
+type plaintextViewer interface {
+	withPlaintext(fn func(plaintext []byte) error) error
+}
+
+// WithPlaintext exposes the stored data and calls fn with its textual form: the bytes
+// of a string or byte slice as-is, and the fmt representation of any other type. The
+// slice is wiped once fn returns.
+func (s *Secret[T]) WithPlaintext(fn func(plaintext []byte) error) error {
+	return s.WithExposed(func(data T) error {
+		return textual(data, fn)
+	})
+}
+
+// withPlaintext calls fn with the textual form of the stored data, as WithPlaintext
+// does, without counting as an exposure or reporting one to OnExpose hooks. It serves
+// the operations which never hand the plaintext to the caller, such as Fingerprint,
+// HMAC, and redaction.
+func (s *Secret[T]) withPlaintext(fn func(plaintext []byte) error) error {
+	return s.withBytes(func(b []byte) error {
+		data, err := s.decode(b)
+		if err != nil {
+			return err
+		}
+
+		defer wipe(&data)
+
+		return textual(data, fn)
+	})
+}
+
+// textual calls fn with the textual form of data, as passed to WithPlaintext, wiping
+// it once fn returns.
+func textual[T any](data T, fn func(plaintext []byte) error) error {
+	var plaintext []byte
+
+	switch v := any(data).(type) {
+	case string:
+		plaintext = []byte(v)
+	case []byte:
+		plaintext = append([]byte(nil), v...)
+	default:
+		plaintext = fmt.Append(nil, v)
+	}
+
+	// WipeBytes securely erases the textual copy once fn is finished with it.
+	defer memguard.WipeBytes(plaintext)
+
+	return fn(plaintext)
+}
+
+// viewPlaintext calls fn with the textual form of the plaintext of secret, without
+// counting as an exposure if secret is a Secret.
+func viewPlaintext(secret Redactable, fn func(plaintext []byte) error) error {
+	if viewer, ok := secret.(plaintextViewer); ok {
+		return viewer.withPlaintext(fn)
+	}
+
+	return secret.WithPlaintext(fn)
+}
+
+// ExposeMasked returns the textual form of the stored data, as passed to WithPlaintext,
+// with every character but the last visibleSuffix replaced by '*', such as
+// "************3f9a", so that support tooling can identify a key without exposing it.
+// At most half of the characters are ever revealed, so that short secrets are not
+// shown in full, and the mask preserves the length of the text. If the data cannot be
+// exposed, ExposeMasked returns the placeholder rendered by String.
+func (s *Secret[T]) ExposeMasked(visibleSuffix int) string {
+	var masked string
+
+	err := s.WithPlaintext(func(plaintext []byte) error {
+		runes := utf8.RuneCount(plaintext)
+		visible := min(max(visibleSuffix, 0), runes/2)
+
+		suffix := plaintext
+		for range runes - visible {
+			_, size := utf8.DecodeRune(suffix)
+			suffix = suffix[size:]
+		}
+
+		masked = strings.Repeat("*", runes-visible) + string(suffix)
+
+		return nil
+	})
+	if err != nil {
+		return s.placeholder()
+	}
+
+	return masked
+}
+
+// Redact returns text with every occurrence of the plaintext of secrets replaced by
+// Placeholder, reporting whether any replacement was made, for integrations which
+// scrub output themselves, such as log handlers. text itself is never modified.
+//
+// Redaction fails closed: if the plaintext of any secret cannot be obtained, such as
+// once it has been destroyed or has expired, Redact returns an error wrapping
+// ErrRedactionFailed, and the output must be withheld or replaced wholesale rather
+// than passed on. Secrets should therefore outlive whatever redacts them. Obtaining
+// the plaintext of a Secret does not count as an exposure.
+func Redact(text []byte, secrets ...Redactable) ([]byte, bool, error) {
+	return redact(text, secrets)
+}
+
+// redact replaces every occurrence of the plaintext of secrets within text with
+// Placeholder, as described by Redact.
+func redact(text []byte, secrets []Redactable) ([]byte, bool, error) {
+	var changed bool
+
+	for _, secret := range secrets {
+		err := viewPlaintext(secret, func(plaintext []byte) error {
+			if len(plaintext) == 0 || !bytes.Contains(text, plaintext) {
+				return nil
+			}
+
+			text = bytes.ReplaceAll(text, plaintext, []byte(Placeholder))
+			changed = true
+
+			return nil
+		})
+		if err != nil {
+			return nil, false, fmt.Errorf("%w: %w", ErrRedactionFailed, err)
+		}
+	}
+
+	return text, changed, nil
+}
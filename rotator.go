@@ -0,0 +1,246 @@
+package mattress
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Rotation describes a rotation of the Secret held by a Rotator.
+type Rotation struct {
+	Version uint64    // Version counts the values held by the Rotator, starting from 1
+	Time    time.Time // Time is when the new value was swapped in
+}
+
+// RotatorOption configures a Rotator.
+type RotatorOption func(*rotatorConfig)
+
+// rotatorConfig holds the settings accumulated from the RotatorOptions passed to
+// NewRotator.
+type rotatorConfig struct {
+	interval time.Duration // interval is how often the value is refreshed, if positive
+	grace    time.Duration // grace is how long a replaced value remains usable
+	onError  func(error)   // onError is notified of background failures
+}
+
+// WithRotationInterval causes the Rotator to refresh its value every interval in the
+// background. Without it, values are only rotated by calls to Rotate.
+func WithRotationInterval(interval time.Duration) RotatorOption {
+	return func(c *rotatorConfig) {
+		c.interval = interval
+	}
+}
+
+// WithGracePeriod sets how long a replaced value remains usable before it is
+// destroyed, giving in-flight users of the previous value time to finish. The default
+// is one minute.
+func WithGracePeriod(grace time.Duration) RotatorOption {
+	return func(c *rotatorConfig) {
+		c.grace = grace
+	}
+}
+
+// OnRotationError registers fn to be called with any error encountered while
+// refreshing the value in the background. Failed refreshes are retried at the next
+// interval.
+func OnRotationError(fn func(error)) RotatorOption {
+	return func(c *rotatorConfig) {
+		c.onError = fn
+	}
+}
+
+// Rotator holds the current value of a rotating secret, such as a database password or
+// API key, atomically swapping in new values produced by a refresh function and
+// destroying the old ones once a grace period has elapsed.
+type Rotator[T any] struct {
+	refresh     func(ctx context.Context) (*Secret[T], error) // refresh produces new values
+	cfg         rotatorConfig                                 // cfg holds the options the Rotator was created with
+	current     *Secret[T]                                    // current is the current value
+	version     uint64                                        // version counts the values held
+	retired     map[*Secret[T]]*time.Timer                    // retired maps replaced values to their destruction timers
+	subscribers map[chan Rotation]struct{}                    // subscribers are notified of rotations
+	lock        sync.RWMutex                                  // synchronize access to the fields above
+	rotating    sync.Mutex                                    // serialize rotations
+	cancel      context.CancelFunc
+	done        chan struct{}
+}
+
+// NewRotator calls refresh to produce the initial value and returns a Rotator holding
+// it. If WithRotationInterval is given, refresh is called again every interval until
+// the Rotator is closed or ctx is cancelled. The Secrets returned by refresh become
+// owned by the Rotator.
+func NewRotator[T any](ctx context.Context, refresh func(ctx context.Context) (*Secret[T], error), opts ...RotatorOption) (*Rotator[T], error) {
+	cfg := rotatorConfig{grace: time.Minute, onError: func(error) {}}
+
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	secret, err := refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Rotator[T]{
+		refresh:     refresh,
+		cfg:         cfg,
+		current:     secret,
+		version:     1,
+		retired:     make(map[*Secret[T]]*time.Timer),
+		subscribers: make(map[chan Rotation]struct{}),
+	}
+
+	if cfg.interval > 0 {
+		ctx, r.cancel = context.WithCancel(ctx)
+		r.done = make(chan struct{})
+
+		go r.run(ctx)
+	}
+
+	return r, nil
+}
+
+// Current returns the current value. The Secret remains owned by the Rotator and is
+// destroyed once the grace period after it is replaced has elapsed, so callers should
+// call Current each time the value is needed rather than retaining it.
+func (r *Rotator[T]) Current() *Secret[T] {
+	r.lock.RLock()         // RLock before reading the current value
+	defer r.lock.RUnlock() // Ensure the lock is RUnlocked when the method returns
+
+	return r.current
+}
+
+// Version returns the number of values the Rotator has held, starting from 1 for the
+// initial value.
+func (r *Rotator[T]) Version() uint64 {
+	r.lock.RLock()         // RLock before reading the version
+	defer r.lock.RUnlock() // Ensure the lock is RUnlocked when the method returns
+
+	return r.version
+}
+
+// WithExposed exposes the current value to fn, guaranteeing that it is not replaced
+// while fn runs.
+func (r *Rotator[T]) WithExposed(fn func(T) error) error {
+	r.lock.RLock()         // RLock before reading the current value
+	defer r.lock.RUnlock() // Ensure the lock is RUnlocked when the method returns
+
+	return r.current.WithExposed(fn)
+}
+
+// Rotate calls the refresh function immediately and swaps in the new value,
+// notifying subscribers. It can be used as an external trigger, for example from a
+// webhook, whether or not the Rotator also refreshes on an interval.
+func (r *Rotator[T]) Rotate(ctx context.Context) error {
+	r.rotating.Lock()         // Lock before rotating
+	defer r.rotating.Unlock() // Ensure the lock is Unlocked when the method returns
+
+	secret, err := r.refresh(ctx)
+	if err != nil {
+		return err
+	}
+
+	r.lock.Lock()         // Lock before replacing the current value
+	defer r.lock.Unlock() // Ensure the lock is Unlocked when the method returns
+
+	r.retire(r.current)
+	r.current = secret
+	r.version++
+
+	rotation := Rotation{Version: r.version, Time: time.Now()}
+
+	for ch := range r.subscribers {
+		// Drop any undelivered Rotation in favour of the latest.
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- rotation
+	}
+
+	return nil
+}
+
+// Subscribe returns a channel on which each Rotation is delivered, along with a
+// function that cancels the subscription. Rotations are not queued: a subscriber that
+// has not received the previous Rotation only receives the latest.
+func (r *Rotator[T]) Subscribe() (<-chan Rotation, func()) {
+	ch := make(chan Rotation, 1)
+
+	r.lock.Lock()
+	r.subscribers[ch] = struct{}{}
+	r.lock.Unlock()
+
+	var once sync.Once
+
+	return ch, func() {
+		once.Do(func() {
+			r.lock.Lock()
+			defer r.lock.Unlock()
+
+			if _, ok := r.subscribers[ch]; ok {
+				delete(r.subscribers, ch)
+				close(ch)
+			}
+		})
+	}
+}
+
+// Close stops refreshing the value, closes every subscription, and destroys the
+// current value along with any replaced values still within their grace period.
+func (r *Rotator[T]) Close() {
+	if r.cancel != nil {
+		r.cancel()
+		<-r.done
+	}
+
+	r.lock.Lock()         // Lock before destroying the values
+	defer r.lock.Unlock() // Ensure the lock is Unlocked when the method returns
+
+	for secret, timer := range r.retired {
+		timer.Stop()
+		secret.Destroy()
+		delete(r.retired, secret)
+	}
+
+	r.current.Destroy()
+
+	for ch := range r.subscribers {
+		delete(r.subscribers, ch)
+		close(ch)
+	}
+}
+
+// run refreshes the value every interval until ctx is cancelled.
+func (r *Rotator[T]) run(ctx context.Context) {
+	defer close(r.done)
+
+	ticker := time.NewTicker(r.cfg.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := r.Rotate(ctx); err != nil && ctx.Err() == nil {
+			r.cfg.onError(err)
+		}
+	}
+}
+
+// retire schedules secret to be destroyed once the grace period has elapsed. The
+// caller must hold the lock.
+func (r *Rotator[T]) retire(secret *Secret[T]) {
+	r.retired[secret] = time.AfterFunc(r.cfg.grace, func() {
+		r.lock.Lock()         // Lock before forgetting the retired value
+		defer r.lock.Unlock() // Ensure the lock is Unlocked when the method returns
+
+		if _, ok := r.retired[secret]; ok {
+			delete(r.retired, secret)
+			secret.Destroy()
+		}
+	})
+}
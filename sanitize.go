@@ -0,0 +1,100 @@
+package mattress
+
+import (
+	"errors"
+	"reflect"
+)
+
+// SanitizeError returns err with the plaintext of secrets masked out of its message,
+// and out of the messages of every error it wraps, by replacing each occurrence with
+// Placeholder. Drivers and clients frequently echo connection strings, and with them
+// passwords, in their errors, so errors which may contain a secret should be sanitized
+// before they are logged or returned to a caller.
+//
+// The returned error preserves the structure of the chain, so errors.Is and errors.As
+// behave as they would for err. Note that errors.As yields the original, unsanitized
+// error. SanitizeError returns err itself if it contains none of the secrets, and nil
+// if err is nil. Sanitization fails closed: if the plaintext of any secret cannot be
+// obtained, as described by Redact, every message in the chain is replaced by
+// Placeholder.
+func SanitizeError(err error, secrets ...Redactable) error {
+	sanitized, changed := sanitize(err, secrets)
+	if !changed {
+		return err
+	}
+
+	return sanitized
+}
+
+// sanitizedError stands in for an error in a chain whose message, or that of an error
+// it wraps, contained a secret.
+type sanitizedError struct {
+	msg     string  // msg is the sanitized message of err
+	err     error   // err is the original error
+	wrapped []error // wrapped holds the sanitized errors wrapped by err
+}
+
+// sanitize returns a sanitized copy of the chain rooted at err, reporting whether any
+// message within it contained a secret.
+func sanitize(err error, secrets []Redactable) (error, bool) {
+	if err == nil {
+		return nil, false
+	}
+
+	var inner []error
+
+	switch e := err.(type) {
+	case interface{ Unwrap() error }:
+		if wrapped := e.Unwrap(); wrapped != nil {
+			inner = []error{wrapped}
+		}
+	case interface{ Unwrap() []error }:
+		inner = e.Unwrap()
+	}
+
+	msg, changed, redactErr := redact([]byte(err.Error()), secrets)
+	if redactErr != nil {
+		msg, changed = []byte(Placeholder), true
+	}
+
+	wrapped := make([]error, 0, len(inner))
+	for _, e := range inner {
+		sanitized, ok := sanitize(e, secrets)
+		wrapped = append(wrapped, sanitized)
+		changed = changed || ok
+	}
+
+	if !changed {
+		return err, false
+	}
+
+	return &sanitizedError{msg: string(msg), err: err, wrapped: wrapped}, true
+}
+
+func (e *sanitizedError) Error() string {
+	return e.msg
+}
+
+func (e *sanitizedError) Unwrap() []error {
+	return e.wrapped
+}
+
+// Is reports whether the original error matches target, as errors.Is would without
+// following the chain, which is instead followed through the sanitized errors.
+func (e *sanitizedError) Is(target error) bool {
+	if reflect.TypeOf(target).Comparable() && e.err == target {
+		return true
+	}
+
+	if is, ok := e.err.(interface{ Is(error) bool }); ok {
+		return is.Is(target)
+	}
+
+	return false
+}
+
+// As finds the first error in the original chain which matches target, as errors.As
+// does.
+func (e *sanitizedError) As(target any) bool {
+	return errors.As(e.err, target)
+}
@@ -0,0 +1,108 @@
+package mattress
+
+import (
+	"cmp"
+	"regexp"
+	"slices"
+)
+
+// ScanRule describes a kind of credential recognized by Scan.
+type ScanRule struct {
+	Name        string         // Name identifies the rule, such as "aws-access-key-id"
+	Description string         // Description describes the credential the rule detects
+	Pattern     *regexp.Regexp // Pattern matches the credential, or its first group does if it has one
+}
+
+// Finding locates a credential detected by Scan. It records where the credential is,
+// rather than the credential itself, so that findings can be logged safely; the span
+// data[Start:End] holds the match.
+type Finding struct {
+	Rule        string // Rule is the Name of the ScanRule which matched
+	Description string // Description is the Description of the ScanRule which matched
+	Start       int    // Start is the offset of the first byte of the match
+	End         int    // End is the offset just past the last byte of the match
+}
+
+// DefaultScanRules are the rules applied by Scan, covering common credential formats.
+// They favour precision over recall: formats without a distinctive prefix, such as
+// AWS secret access keys, are only matched alongside a telling key name.
+var DefaultScanRules = []ScanRule{
+	{
+		Name:        "aws-access-key-id",
+		Description: "AWS access key ID",
+		Pattern:     regexp.MustCompile(`\b(?:AKIA|ASIA|ABIA|ACCA)[A-Z2-7]{16}\b`),
+	},
+	{
+		Name:        "aws-secret-access-key",
+		Description: "AWS secret access key",
+		Pattern:     regexp.MustCompile(`(?i)aws_?secret_?(?:access_?)?key["']?\s*[:=]\s*["']?([A-Za-z0-9/+]{40})\b`),
+	},
+	{
+		Name:        "github-token",
+		Description: "GitHub token",
+		Pattern:     regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36}|github_pat_[A-Za-z0-9_]{82})\b`),
+	},
+	{
+		Name:        "private-key",
+		Description: "private key",
+		Pattern:     regexp.MustCompile(`-----BEGIN (?:[A-Z0-9]+ )*PRIVATE KEY(?: BLOCK)?-----`),
+	},
+	{
+		Name:        "slack-token",
+		Description: "Slack token",
+		Pattern:     regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}`),
+	},
+	{
+		Name:        "stripe-secret-key",
+		Description: "Stripe secret or restricted key",
+		Pattern:     regexp.MustCompile(`\b[sr]k_live_[A-Za-z0-9]{24,}\b`),
+	},
+	{
+		Name:        "google-api-key",
+		Description: "Google API key",
+		Pattern:     regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}`),
+	},
+	{
+		Name:        "jwt",
+		Description: "JSON Web Token",
+		Pattern:     regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`),
+	},
+}
+
+// Scan searches data for credentials matching DefaultScanRules, so that outbound
+// payloads, logs, and user uploads can be vetted before they leave the process. It
+// returns every Finding in order of its position within data, or nil if there are
+// none.
+func Scan(data []byte) []Finding {
+	return ScanWith(data, DefaultScanRules...)
+}
+
+// ScanWith searches data for credentials matching rules, as Scan does, allowing the
+// default rules to be extended or replaced.
+func ScanWith(data []byte, rules ...ScanRule) []Finding {
+	var findings []Finding
+
+	for _, rule := range rules {
+		for _, match := range rule.Pattern.FindAllSubmatchIndex(data, -1) {
+			start, end := match[0], match[1]
+
+			// Rules with a group match the credential within its surrounding context.
+			if len(match) >= 4 && match[2] >= 0 {
+				start, end = match[2], match[3]
+			}
+
+			findings = append(findings, Finding{
+				Rule:        rule.Name,
+				Description: rule.Description,
+				Start:       start,
+				End:         end,
+			})
+		}
+	}
+
+	slices.SortStableFunc(findings, func(a, b Finding) int {
+		return cmp.Compare(a.Start, b.Start)
+	})
+
+	return findings
+}
@@ -0,0 +1,142 @@
+package mattress
+
+import (
+	"fmt"
+	"sync"
+)
+
+// SecretMap holds a collection of Secrets keyed by K, such as a credential per tenant,
+// each sealed independently so that exposing or destroying one entry never touches
+// the others.
+type SecretMap[K comparable, V any] struct {
+	entries map[K]*Secret[V] // entries maps keys to the Secrets owned by the map
+	codec   Codec[V]         // codec serializes the values sealed by Set, if given to NewSecretMapWithCodec
+	opts    []Option         // opts are the options each value sealed by Set is created with
+	lock    sync.RWMutex     // synchronize access to entries
+}
+
+// NewSecretMap returns an empty SecretMap whose values are created with opts, as
+// NewSecret does, serialized with encoding/gob unless opts include WithCodec.
+func NewSecretMap[K comparable, V any](opts ...Option) *SecretMap[K, V] {
+	return &SecretMap[K, V]{
+		entries: make(map[K]*Secret[V]),
+		opts:    opts,
+	}
+}
+
+// NewSecretMapWithCodec returns an empty SecretMap whose values are serialized with
+// codec rather than encoding/gob.
+func NewSecretMapWithCodec[K comparable, V any](codec Codec[V], opts ...Option) *SecretMap[K, V] {
+	return &SecretMap[K, V]{
+		entries: make(map[K]*Secret[V]),
+		codec:   codec,
+		opts:    opts,
+	}
+}
+
+// Set seals value into a new Secret stored under key, destroying any Secret the key
+// previously held.
+func (m *SecretMap[K, V]) Set(key K, value V) error {
+	var (
+		secret *Secret[V]
+		err    error
+	)
+
+	if m.codec != nil {
+		secret, err = NewSecretWithCodec(value, m.codec, m.opts...)
+	} else {
+		secret, err = NewSecret(value, m.opts...)
+	}
+	if err != nil {
+		return err
+	}
+
+	m.Store(key, secret)
+
+	return nil
+}
+
+// Store stores secret under key, destroying any Secret the key previously held.
+// secret becomes owned by the SecretMap.
+func (m *SecretMap[K, V]) Store(key K, secret *Secret[V]) {
+	m.lock.Lock()         // Lock before replacing the entry
+	defer m.lock.Unlock() // Ensure the lock is Unlocked when the method returns
+
+	if previous, ok := m.entries[key]; ok && previous != secret {
+		previous.Destroy()
+	}
+
+	m.entries[key] = secret
+}
+
+// Get returns the Secret stored under key, and whether there is one. The Secret
+// remains owned by the SecretMap and is destroyed once replaced or deleted, so callers
+// should call Get each time the value is needed rather than retaining it, or use
+// WithExposed.
+func (m *SecretMap[K, V]) Get(key K) (*Secret[V], bool) {
+	m.lock.RLock()         // RLock before reading the entries
+	defer m.lock.RUnlock() // Ensure the lock is RUnlocked when the method returns
+
+	secret, ok := m.entries[key]
+
+	return secret, ok
+}
+
+// WithExposed exposes the Secret stored under key to fn, as Secret.WithExposed does.
+// The entry is not replaced or deleted while fn runs. It returns an error wrapping
+// ErrSecretNotFound if there is no Secret stored under key.
+func (m *SecretMap[K, V]) WithExposed(key K, fn func(V) error) error {
+	m.lock.RLock()         // RLock before reading the entries
+	defer m.lock.RUnlock() // Ensure the lock is RUnlocked when the method returns
+
+	secret, ok := m.entries[key]
+	if !ok {
+		return fmt.Errorf("mattress: key %v: %w", key, ErrSecretNotFound)
+	}
+
+	return secret.WithExposed(fn)
+}
+
+// Delete destroys the Secret stored under key, if any, and removes it from the map.
+func (m *SecretMap[K, V]) Delete(key K) {
+	m.lock.Lock()         // Lock before removing the entry
+	defer m.lock.Unlock() // Ensure the lock is Unlocked when the method returns
+
+	if secret, ok := m.entries[key]; ok {
+		secret.Destroy()
+		delete(m.entries, key)
+	}
+}
+
+// Range calls fn with each key and the Secret stored under it, in no particular order,
+// until fn returns false. Nothing is exposed unless fn exposes it, and the map must not
+// be modified from within fn.
+func (m *SecretMap[K, V]) Range(fn func(key K, secret *Secret[V]) bool) {
+	m.lock.RLock()         // RLock before reading the entries
+	defer m.lock.RUnlock() // Ensure the lock is RUnlocked when the method returns
+
+	for key, secret := range m.entries {
+		if !fn(key, secret) {
+			return
+		}
+	}
+}
+
+// Len returns the number of Secrets in the map.
+func (m *SecretMap[K, V]) Len() int {
+	m.lock.RLock()         // RLock before reading the entries
+	defer m.lock.RUnlock() // Ensure the lock is RUnlocked when the method returns
+
+	return len(m.entries)
+}
+
+// DestroyAll destroys every Secret in the map, leaving it empty and ready for reuse.
+func (m *SecretMap[K, V]) DestroyAll() {
+	m.lock.Lock()         // Lock before destroying the entries
+	defer m.lock.Unlock() // Ensure the lock is Unlocked when the method returns
+
+	for key, secret := range m.entries {
+		secret.Destroy()
+		delete(m.entries, key)
+	}
+}
@@ -0,0 +1,188 @@
+package mattress
+
+import (
+	"crypto/rand"
+	"fmt"
+
+	"github.com/awnumar/memguard"
+)
+
+// Split divides the bytes held by secret into n shares using Shamir's secret sharing
+// over GF(2^8), such that any k of them can be passed to Combine to recover it while
+// fewer reveal nothing about it. Each share is itself a Secret, one byte longer than
+// secret, and the random polynomial coefficients are wiped once the shares have been
+// secured. n must be at most 255 and k must be between 2 and n.
+func Split(secret *Secret[[]byte], n, k int) ([]*Secret[[]byte], error) {
+	if n < 2 || n > 255 {
+		return nil, fmt.Errorf("mattress: number of shares must be between 2 and 255, got %d", n)
+	}
+
+	if k < 2 || k > n {
+		return nil, fmt.Errorf("mattress: threshold must be between 2 and %d, got %d", n, k)
+	}
+
+	var shares []*Secret[[]byte]
+
+	err := secret.WithExposed(func(data []byte) error {
+		if len(data) == 0 {
+			return fmt.Errorf("mattress: cannot split an empty secret")
+		}
+
+		// raw holds each share as its y values followed by its x coordinate.
+		raw := make([][]byte, n)
+		for i := range raw {
+			raw[i] = make([]byte, len(data)+1)
+			raw[i][len(data)] = byte(i + 1)
+		}
+
+		// WipeBytes securely erases any shares that were not secured.
+		defer func() {
+			for _, r := range raw {
+				memguard.WipeBytes(r)
+			}
+		}()
+
+		coefficients := make([]byte, k)
+
+		// WipeBytes securely erases the polynomial coefficients.
+		defer memguard.WipeBytes(coefficients)
+
+		for j, b := range data {
+			coefficients[0] = b
+			if _, err := rand.Read(coefficients[1:]); err != nil {
+				return err
+			}
+
+			for i := range raw {
+				raw[i][j] = evaluate(coefficients, byte(i+1))
+			}
+		}
+
+		shares = make([]*Secret[[]byte], 0, n)
+		for _, r := range raw {
+			share, err := NewSecretBytes(r)
+			if err != nil {
+				for _, s := range shares {
+					s.Destroy()
+				}
+				return err
+			}
+
+			shares = append(shares, share)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return shares, nil
+}
+
+// Combine recovers a secret from shares produced by Split. At least the threshold
+// number of shares passed to Split must be provided; fewer yield an unrelated value
+// rather than an error. The shares remain owned by the caller.
+func Combine(shares ...*Secret[[]byte]) (*Secret[[]byte], error) {
+	if len(shares) < 2 {
+		return nil, fmt.Errorf("%w: at least 2 shares are required", ErrInvalidShares)
+	}
+
+	var (
+		xs     = make([]byte, len(shares))
+		result []byte
+	)
+
+	// WipeBytes securely erases the recovered secret if it was not secured.
+	defer func() { memguard.WipeBytes(result) }()
+
+	for i, share := range shares {
+		err := share.WithExposed(func(data []byte) error {
+			if len(data) < 2 {
+				return ErrInvalidShares
+			}
+
+			if i == 0 {
+				result = make([]byte, len(data)-1)
+			} else if len(data)-1 != len(result) {
+				return fmt.Errorf("%w: shares differ in length", ErrInvalidShares)
+			}
+
+			x := data[len(data)-1]
+			if x == 0 {
+				return ErrInvalidShares
+			}
+
+			for _, prev := range xs[:i] {
+				if prev == x {
+					return fmt.Errorf("%w: duplicate share", ErrInvalidShares)
+				}
+			}
+
+			xs[i] = x
+
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for i, share := range shares {
+		// basis is the Lagrange basis polynomial for share i evaluated at zero.
+		basis := byte(1)
+		for j, x := range xs {
+			if i != j {
+				basis = gfMul(basis, gfMul(x, gfInv(x^xs[i])))
+			}
+		}
+
+		err := share.WithExposed(func(data []byte) error {
+			for j := range result {
+				result[j] ^= gfMul(data[j], basis)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	secret, err := NewSecretBytes(result)
+	result = nil
+
+	return secret, err
+}
+
+// evaluate returns the value of the polynomial with the given coefficients, lowest
+// degree first, at x.
+func evaluate(coefficients []byte, x byte) byte {
+	var y byte
+	for i := len(coefficients) - 1; i >= 0; i-- {
+		y = gfMul(y, x) ^ coefficients[i]
+	}
+	return y
+}
+
+// gfMul multiplies a and b in GF(2^8) with the AES reducing polynomial, in constant
+// time.
+func gfMul(a, b byte) byte {
+	var p byte
+	for range 8 {
+		p ^= -(b & 1) & a
+		a = a<<1 ^ -(a>>7)&0x1b
+		b >>= 1
+	}
+	return p
+}
+
+// gfInv returns the multiplicative inverse of a in GF(2^8), computed as a^254 in
+// constant time. The inverse of zero is zero.
+func gfInv(a byte) byte {
+	result := a
+	for range 6 {
+		a = gfMul(a, a)
+		result = gfMul(result, a)
+	}
+	return gfMul(result, result)
+}
@@ -0,0 +1,38 @@
+package mattress
+
+import "github.com/awnumar/memguard"
+
+// SecretString is a Secret holding a string. Secrets created with NewSecretString store
+// the raw bytes of the string, avoiding the overhead and intermediate copies of gob.
+type SecretString = Secret[string]
+
+// SecretBytes is a Secret holding a byte slice. Secrets created with NewSecretBytes
+// store the bytes as-is, avoiding the overhead and intermediate copies of gob.
+type SecretBytes = Secret[[]byte]
+
+// NewSecretString initializes a new SecretString with the provided data, storing its
+// raw bytes without any serialization step.
+func NewSecretString(data string, opts ...Option) (*SecretString, error) {
+	return NewSecretWithCodec[string](data, StringCodec{}, opts...)
+}
+
+// NewSecretBytes initializes a new SecretBytes with the provided data, storing the
+// bytes without any serialization step. Ownership of data is transferred to the
+// Secret: data is wiped once it has been secured, so that no plaintext copy is left
+// behind in the caller's memory.
+func NewSecretBytes(data []byte, opts ...Option) (*SecretBytes, error) {
+	// WipeBytes securely erases the caller's copy once it has been secured, or if
+	// securing it fails.
+	defer memguard.WipeBytes(data)
+
+	return NewSecretWithCodec[[]byte](data, BytesCodec{}, opts...)
+}
+
+// NewSecretFromBytesUnsafe initializes a new SecretBytes with a copy of data, as
+// NewSecretBytes does, but leaves data untouched rather than wiping it. The caller's
+// copy of the plaintext therefore remains on the regular heap. It exists for fuzz
+// tests and similar harnesses, whose inputs are owned by the test framework and must
+// not be modified; production code should use NewSecretBytes.
+func NewSecretFromBytesUnsafe(data []byte, opts ...Option) (*SecretBytes, error) {
+	return NewSecretWithCodec[[]byte](data, BytesCodec{}, opts...)
+}
@@ -0,0 +1,410 @@
+package mattress
+
+import (
+	"errors"
+	"os"
+	"runtime"
+	"sync"
+
+	"github.com/awnumar/memguard"
+	"github.com/awnumar/memguard/core"
+)
+
+// storage abstracts over where the encoded bytes of a Secret are kept between
+// exposures.
+type storage interface {
+	// view returns the plaintext bytes along with a release function that must be
+	// called once the caller is finished with them. The bytes must not be retained
+	// after release is called.
+	view() (b []byte, release func(), err error)
+
+	// destroy irreversibly wipes the stored bytes.
+	destroy()
+
+	// size returns the number of stored bytes.
+	size() int
+}
+
+// newStorage secures b according to cfg, wiping b in the process.
+func newStorage(b []byte, cfg config) (storage, error) {
+	if cfg.err != nil {
+		memguard.WipeBytes(b)
+		return nil, cfg.err
+	}
+
+	// memguard refuses to allocate zero-length containers, so empty payloads are
+	// represented without one.
+	if len(b) == 0 {
+		return emptyStorage{}, nil
+	}
+
+	if cfg.compress {
+		return newCompressedStorage(b, cfg)
+	}
+
+	if Degraded() {
+		return newMaskedStorage(b), nil
+	}
+
+	if cfg.pool != nil {
+		return newPooledStorage(cfg.pool, b), nil
+	}
+
+	if cfg.chunkSize > 0 && len(b) > cfg.chunkSize {
+		return newChunkedStorage(b, cfg.chunkSize), nil
+	}
+
+	if cfg.dpapi {
+		return newDPAPIStorage(b, cfg)
+	}
+
+	if cfg.sealed && cfg.ownKey {
+		return newKeyedStorage(b, cfg)
+	}
+
+	if cfg.sealed {
+		return newEnclaveStorage(b), nil
+	}
+
+	return newLockedStorage(b, cfg)
+}
+
+// newStorageFromBuffer secures the contents of buffer according to cfg, taking
+// ownership of buffer. It allows data which was read directly into guarded memory to
+// be secured without passing through the regular heap.
+func newStorageFromBuffer(buffer guardedBuffer, cfg config) (storage, error) {
+	if cfg.err != nil {
+		buffer.Destroy()
+		return nil, cfg.err
+	}
+
+	if buffer.Size() == 0 {
+		buffer.Destroy()
+		return emptyStorage{}, nil
+	}
+
+	if cfg.compress {
+		// Melt makes a LockedBuffer mutable, so that it can be wiped as it is compressed.
+		if locked, ok := buffer.(*memguard.LockedBuffer); ok {
+			locked.Melt()
+		}
+		defer buffer.Destroy()
+
+		return newCompressedStorage(buffer.Bytes(), cfg)
+	}
+
+	locked, ok := buffer.(*memguard.LockedBuffer)
+	if !ok {
+		// newMaskedStorage wipes the heap buffer once masked.
+		return newMaskedStorage(buffer.Bytes()), nil
+	}
+
+	if cfg.pool != nil {
+		// Seal encrypts the buffer into an Enclave, destroying the buffer.
+		return &pooledStorage{pool: cfg.pool, enclave: locked.Seal()}, nil
+	}
+
+	if cfg.chunkSize > 0 && locked.Size() > cfg.chunkSize || cfg.dpapi || cfg.sealed && cfg.ownKey {
+		// Melt makes the buffer mutable, as buffers read by memguard are returned frozen,
+		// so that it can be wiped as it is secured.
+		locked.Melt()
+		defer locked.Destroy()
+
+		return newStorage(locked.Bytes(), cfg)
+	}
+
+	if cfg.sealed {
+		// Seal encrypts the buffer into an Enclave, destroying the buffer.
+		return &enclaveStorage{enclave: locked.Seal()}, nil
+	}
+
+	locked.Freeze()
+
+	return newLockedStorageFromBuffer(locked, cfg), nil
+}
+
+// emptyStorage represents a zero-length payload, which holds nothing to protect.
+type emptyStorage struct{}
+
+func (emptyStorage) view() ([]byte, func(), error) {
+	return nil, func() {}, nil
+}
+
+func (emptyStorage) destroy() {}
+
+func (emptyStorage) size() int { return 0 }
+
+// lockedStorage keeps the plaintext in a memguard.LockedBuffer for the lifetime of
+// the Secret. Exposure is cheap, but the plaintext is resident in (guarded) memory
+// the entire time, other than while it is resealed around a ForkBoundary.
+type lockedStorage struct {
+	id       uint64          // id identifies the storage in the fork registry
+	contents *lockedContents // contents holds the data, apart so that a cleanup can destroy it
+	cleanup  runtime.Cleanup // cleanup destroys the contents once the storage is garbage collected
+	lock     sync.RWMutex    // synchronize resealing with views of the data
+}
+
+// lockedContents holds the data of a lockedStorage.
+type lockedContents struct {
+	buffer *memguard.LockedBuffer // buffer holds the plaintext, unless resealed
+	sealed *memguard.Enclave      // sealed holds the data while it is resealed
+}
+
+// lockedCleanup holds what must be destroyed once a lockedStorage is garbage collected.
+type lockedCleanup struct {
+	id       uint64
+	contents *lockedContents
+}
+
+// newLockedStorage moves b into a LockedBuffer, wiping b in the process. The bytes are
+// copied straight into the buffer rather than via an Enclave, which would allocate and
+// decrypt a second copy.
+func newLockedStorage(b []byte, cfg config) (*lockedStorage, error) {
+	if err := reserveLocked(len(b)); err != nil {
+		memguard.WipeBytes(b)
+		return nil, err
+	}
+
+	return newLockedStorageFromBuffer(memguard.NewBufferFromBytes(b), cfg), nil
+}
+
+// newLockedStorageFromBuffer wraps buffer, taking ownership of it, and records it in
+// the fork registry so that it can be resealed around a ForkBoundary.
+func newLockedStorageFromBuffer(buffer *memguard.LockedBuffer, cfg config) *lockedStorage {
+	locked := &lockedStorage{contents: &lockedContents{buffer: buffer}}
+
+	registerForkable(locked)
+
+	// Attach a cleanup to ensure the secure buffer is wiped when the storage, and
+	// therefore the Secret holding it, is garbage collected. The cleanup is attached
+	// here rather than to the Secret so that it also covers Secrets which were not
+	// allocated by a constructor, such as those populated by an unmarshaler.
+	if !cfg.noCleanup {
+		locked.cleanup = runtime.AddCleanup(locked, destroyLocked, lockedCleanup{id: locked.id, contents: locked.contents})
+	}
+
+	return locked
+}
+
+// destroyLocked destroys the contents of a garbage collected lockedStorage and removes
+// its entry from the fork registry.
+func destroyLocked(c lockedCleanup) {
+	c.contents.buffer.Destroy()
+	unregisterForkable(c.id)
+}
+
+func (l *lockedStorage) view() ([]byte, func(), error) {
+	l.lock.RLock() // RLock before reading the buffer, until the view is released
+
+	if l.contents.sealed != nil {
+		buffer, err := openEnclave(l.contents.sealed)
+		if err != nil {
+			l.lock.RUnlock()
+			return nil, nil, err
+		}
+
+		return buffer.Bytes(), func() {
+			buffer.Destroy()
+			l.lock.RUnlock()
+		}, nil
+	}
+
+	// The buffer is only destroyed behind the Secret's back by Purge.
+	if !l.contents.buffer.IsAlive() {
+		l.lock.RUnlock()
+		return nil, nil, ErrDestroyed
+	}
+
+	return l.contents.buffer.Bytes(), l.lock.RUnlock, nil
+}
+
+func (l *lockedStorage) destroy() {
+	l.lock.Lock()         // Lock before destroying the buffer
+	defer l.lock.Unlock() // Ensure the lock is Unlocked when the method returns
+
+	l.cleanup.Stop()
+
+	l.contents.buffer.Destroy()
+	l.contents.sealed = nil
+
+	unregisterForkable(l.id)
+}
+
+func (l *lockedStorage) size() int {
+	l.lock.RLock()         // RLock before reading the buffer
+	defer l.lock.RUnlock() // Ensure the lock is RUnlocked when the method returns
+
+	if l.contents.sealed != nil {
+		return l.contents.sealed.Size()
+	}
+
+	return l.contents.buffer.Size()
+}
+
+// reseal encrypts the plaintext into an Enclave, destroying the buffer, so that no
+// plaintext is resident until unseal is called.
+func (l *lockedStorage) reseal() {
+	l.lock.Lock()         // Lock before replacing the buffer
+	defer l.lock.Unlock() // Ensure the lock is Unlocked when the method returns
+
+	if l.contents.sealed != nil || !l.contents.buffer.IsAlive() {
+		return
+	}
+
+	// Seal encrypts the buffer into an Enclave, destroying the buffer.
+	l.contents.sealed = l.contents.buffer.Seal()
+}
+
+// unseal decrypts the data resealed by reseal back into a LockedBuffer. Should that
+// fail, the data is left sealed and decrypted for each view instead.
+func (l *lockedStorage) unseal() {
+	l.lock.Lock()         // Lock before replacing the buffer
+	defer l.lock.Unlock() // Ensure the lock is Unlocked when the method returns
+
+	if l.contents.sealed == nil {
+		return
+	}
+
+	buffer, err := openEnclave(l.contents.sealed)
+	if err != nil {
+		return
+	}
+
+	buffer.Freeze()
+
+	l.contents.buffer, l.contents.sealed = buffer, nil
+}
+
+// enclaveStorage keeps the data encrypted within a memguard.Enclave and only
+// decrypts it into a LockedBuffer for the duration of a single view.
+type enclaveStorage struct {
+	enclave *memguard.Enclave
+}
+
+// newEnclaveStorage seals b into an Enclave, wiping b in the process.
+func newEnclaveStorage(b []byte) *enclaveStorage {
+	return &enclaveStorage{enclave: memguard.NewEnclave(b)}
+}
+
+func (e *enclaveStorage) view() ([]byte, func(), error) {
+	buffer, err := openEnclave(e.enclave)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return buffer.Bytes(), buffer.Destroy, nil
+}
+
+// keyedStorage keeps the data encrypted, as enclaveStorage does, but under a key of its
+// own rather than the session key memguard shares between every Enclave, so that Rekey
+// can replace the key without Purge. The key is held split across a core.Coffer, which
+// memguard re-splits in the background until it is destroyed.
+type keyedStorage struct {
+	key        *core.Coffer    // key holds the key the data is encrypted under
+	ciphertext []byte          // ciphertext holds the encrypted data
+	cleanup    runtime.Cleanup // cleanup destroys the key once the storage is garbage collected
+}
+
+// newKeyedStorage encrypts b under a freshly generated key, wiping b in the process.
+func newKeyedStorage(b []byte, cfg config) (*keyedStorage, error) {
+	// WipeBytes securely erases b once it has been encrypted, or if encrypting it fails.
+	defer memguard.WipeBytes(b)
+
+	// A Coffer locks three pages of its own, and a view of it a fourth.
+	if err := reserveLocked(4 * os.Getpagesize()); err != nil {
+		return nil, err
+	}
+
+	key := core.NewCoffer()
+
+	k, err := key.View()
+	if err != nil {
+		key.Destroy()
+		return nil, err
+	}
+	defer k.Destroy()
+
+	ciphertext, err := core.Encrypt(b, k.Data())
+	if err != nil {
+		key.Destroy()
+		return nil, err
+	}
+
+	keyed := &keyedStorage{key: key, ciphertext: ciphertext}
+
+	// The key is destroyed when the storage is garbage collected, as the cleanup of a
+	// lockedStorage destroys its buffer, which also stops the Coffer re-splitting it.
+	if !cfg.noCleanup {
+		keyed.cleanup = runtime.AddCleanup(keyed, destroyKey, key)
+	}
+
+	return keyed, nil
+}
+
+// destroyKey destroys the key of a garbage collected keyedStorage.
+func destroyKey(key *core.Coffer) {
+	key.Destroy()
+}
+
+func (k *keyedStorage) view() ([]byte, func(), error) {
+	if err := reserveView(os.Getpagesize() + k.size()); err != nil {
+		return nil, nil, err
+	}
+
+	// The key is only destroyed behind the Secret's back by Purge.
+	key, err := k.key.View()
+	if err != nil {
+		return nil, nil, ErrDestroyed
+	}
+	defer key.Destroy()
+
+	buffer := memguard.NewBuffer(k.size())
+
+	if _, err := core.Decrypt(k.ciphertext, key.Data(), buffer.Bytes()); err != nil {
+		buffer.Destroy()
+		return nil, nil, ErrDecryptionFailed
+	}
+
+	return buffer.Bytes(), buffer.Destroy, nil
+}
+
+func (k *keyedStorage) destroy() {
+	k.cleanup.Stop()
+	k.key.Destroy()
+	k.ciphertext = nil
+}
+
+func (k *keyedStorage) size() int { return len(k.ciphertext) - core.Overhead }
+
+// openEnclave decrypts enclave into a LockedBuffer.
+func openEnclave(enclave *memguard.Enclave) (*memguard.LockedBuffer, error) {
+	if err := reserveView(enclave.Size()); err != nil {
+		return nil, err
+	}
+
+	buffer, err := enclave.Open()
+	if err != nil {
+		// The session key is only replaced behind the Secret's back by Purge, after
+		// which the Enclave can no longer be opened.
+		if errors.Is(err, core.ErrDecryptionFailed) {
+			return nil, ErrDestroyed
+		}
+		return nil, err
+	}
+
+	return buffer, nil
+}
+
+// destroy drops the reference to the Enclave. The ciphertext is left to the garbage
+// collector; without the reference it can no longer be opened through the Secret.
+func (e *enclaveStorage) destroy() {
+	e.enclave = nil
+}
+
+func (e *enclaveStorage) size() int {
+	if e.enclave == nil {
+		return 0
+	}
+
+	return e.enclave.Size()
+}
@@ -0,0 +1,25 @@
+package mattress
+
+import (
+	"fmt"
+
+	"github.com/awnumar/memguard"
+)
+
+// UnmarshalTOML implements toml.Unmarshaler for github.com/BurntSushi/toml, securing
+// the string value v within the Secret as UnmarshalText does, so that configuration
+// files can be decoded directly into Secrets. Any data the Secret previously held is
+// destroyed.
+func (s *Secret[T]) UnmarshalTOML(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("mattress: cannot decode TOML %T into a Secret", v)
+	}
+
+	text := []byte(str)
+
+	// WipeBytes securely erases the intermediate copy once it has been secured.
+	defer memguard.WipeBytes(text)
+
+	return s.UnmarshalText(text)
+}
@@ -0,0 +1,179 @@
+package mattress
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"maps"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+)
+
+// SecretInfo describes a live Secret tracked by the registry enabled with
+// TrackSecrets. It never includes any of the data held by the Secret.
+type SecretInfo struct {
+	ID      uint64    // ID uniquely identifies the Secret within the process
+	Label   string    // Label is the label of the Secret, as set by WithLabel
+	Site    string    // Site is the file and line of the call which created the Secret
+	Size    int       // Size is the number of encoded bytes held in guarded memory
+	Created time.Time // Created is when the data was secured
+}
+
+// Age returns how long ago the Secret was created.
+func (i SecretInfo) Age() time.Duration {
+	return time.Since(i.Created)
+}
+
+// tracking reports whether newly secured data is recorded in the registry.
+var tracking atomic.Bool
+
+// registry records every live Secret while tracking is enabled.
+var registry struct {
+	entries map[uint64]SecretInfo // entries maps IDs to live Secrets
+	next    uint64                // next is the ID of the next Secret tracked
+	lock    sync.Mutex            // synchronize access to the fields above
+}
+
+// TrackSecrets enables or disables the registry of live Secrets reported by
+// LiveSecrets and DumpStats. While enabled, the creation site, size, and age of every
+// Secret secured is recorded until it is destroyed or garbage collected, so that
+// Secrets which are never destroyed, and hold locked pages for longer than necessary,
+// can be found. Secrets created while tracking was disabled are not reported.
+func TrackSecrets(enabled bool) ConfigureOption {
+	return func(c *globalConfig) {
+		c.tracking = enabled
+	}
+}
+
+// LiveSecrets returns every tracked Secret which has not yet been destroyed or
+// garbage collected, oldest first. It returns nil unless TrackSecrets is enabled.
+func LiveSecrets() []SecretInfo {
+	registry.lock.Lock()         // Lock before reading the entries
+	defer registry.lock.Unlock() // Ensure the lock is Unlocked when the function returns
+
+	var infos []SecretInfo
+	for _, info := range registry.entries {
+		infos = append(infos, info)
+	}
+
+	slices.SortFunc(infos, func(a, b SecretInfo) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	return infos
+}
+
+// DumpStats writes a summary of the live Secrets reported by LiveSecrets to w,
+// grouped by creation site, followed by each Secret in turn.
+func DumpStats(w io.Writer) error {
+	infos := LiveSecrets()
+
+	total := 0
+	sites := make(map[string]int)
+	for _, info := range infos {
+		total += info.Size
+		sites[info.Site]++
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "%d live secrets holding %d bytes\n\n", len(infos), total)
+
+	fmt.Fprintln(tw, "SITE\tCOUNT")
+	for _, site := range slices.Sorted(maps.Keys(sites)) {
+		fmt.Fprintf(tw, "%s\t%d\n", site, sites[site])
+	}
+
+	fmt.Fprintln(tw, "\nID\tLABEL\tSIZE\tAGE\tSITE")
+	for _, info := range infos {
+		label := cmp.Or(info.Label, "-")
+		fmt.Fprintf(tw, "%d\t%s\t%d\t%s\t%s\n", info.ID, label, info.Size, info.Age().Round(time.Millisecond), info.Site)
+	}
+
+	return tw.Flush()
+}
+
+// track records store, held by a Secret labelled label, in the registry, if tracking is
+// enabled and store holds any data, returning its ID or zero if it was not recorded.
+// The entry is removed once store is garbage collected, should it never be destroyed.
+func track(store storage, label string) uint64 {
+	if !tracking.Load() || store.size() == 0 {
+		return 0
+	}
+
+	registry.lock.Lock()         // Lock before adding the entry
+	defer registry.lock.Unlock() // Ensure the lock is Unlocked when the function returns
+
+	if registry.entries == nil {
+		registry.entries = make(map[uint64]SecretInfo)
+	}
+
+	registry.next++
+	id := registry.next
+
+	file, line := caller()
+
+	registry.entries[id] = SecretInfo{
+		ID:      id,
+		Label:   label,
+		Site:    fmt.Sprintf("%s:%d", file, line),
+		Size:    store.size(),
+		Created: time.Now(),
+	}
+
+	switch s := store.(type) {
+	case *lockedStorage:
+		runtime.AddCleanup(s, untrack, id)
+	case *enclaveStorage:
+		runtime.AddCleanup(s, untrack, id)
+	case *maskedStorage:
+		runtime.AddCleanup(s, untrack, id)
+	case *pooledStorage:
+		runtime.AddCleanup(s, untrack, id)
+	case *chunkedStorage:
+		runtime.AddCleanup(s, untrack, id)
+	case *compressedStorage:
+		runtime.AddCleanup(s, untrack, id)
+	case *keyedStorage:
+		runtime.AddCleanup(s, untrack, id)
+	}
+
+	// Storages specific to a platform, such as those of WithDPAPI, are only untracked
+	// when destroyed.
+
+	return id
+}
+
+// untrack removes the entry with the given ID from the registry, if present.
+func untrack(id uint64) {
+	if id == 0 {
+		return
+	}
+
+	registry.lock.Lock()         // Lock before removing the entry
+	defer registry.lock.Unlock() // Ensure the lock is Unlocked when the function returns
+
+	delete(registry.entries, id)
+}
+
+// caller returns the file and line of the first caller outside this package.
+func caller() (string, int) {
+	pcs := make([]uintptr, 32)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs)])
+
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, "github.com/garrettladley/mattress.") {
+			return frame.File, frame.Line
+		}
+
+		if !more {
+			return "unknown", 0
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package mattress
+
+// SecretValue is the behaviour of a Secret relied upon by most services which are
+// handed one: exposing, fingerprinting, rendering, and destroying its data. *Secret[T]
+// implements SecretValue[T], so services may accept the interface and tests may
+// inject a trivial in-memory fake, such as mattresstest.Fake, which allocates no
+// locked pages.
+type SecretValue[T any] interface {
+	// Expose returns the data held by the Secret, or the zero value of T if it cannot
+	// be exposed.
+	Expose() T
+
+	// Destroy wipes the data held by the Secret. Calling it more than once is a no-op.
+	Destroy()
+
+	// Fingerprint returns the SHA-256 digest of the textual form of the data held by
+	// the Secret.
+	Fingerprint() (Fingerprint, error)
+
+	// String returns a representation of the Secret which never includes its data.
+	String() string
+}
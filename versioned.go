@@ -0,0 +1,107 @@
+package mattress
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// VersionedSecret keeps the most recent versions of a rotating secret, each held in its
+// own independently sealed Secret. During a rotation window, consumers validating
+// inbound signatures or tokens can try the current version and then earlier ones with
+// TryExposed.
+type VersionedSecret[T any] struct {
+	versions []*Secret[T] // versions holds the retained versions, newest first
+	limit    int          // limit is the maximum number of versions retained
+	lock     sync.RWMutex // synchronize access to versions
+}
+
+// NewVersionedSecret returns a VersionedSecret whose current version is initial,
+// retaining at most limit versions. initial becomes owned by the VersionedSecret.
+func NewVersionedSecret[T any](initial *Secret[T], limit int) (*VersionedSecret[T], error) {
+	if limit < 1 {
+		return nil, fmt.Errorf("mattress: version limit must be positive, got %d", limit)
+	}
+
+	return &VersionedSecret[T]{versions: []*Secret[T]{initial}, limit: limit}, nil
+}
+
+// Push makes secret the current version, destroying the oldest version if more than
+// the limit would otherwise be retained. secret becomes owned by the VersionedSecret.
+func (v *VersionedSecret[T]) Push(secret *Secret[T]) {
+	v.lock.Lock()         // Lock before replacing the versions
+	defer v.lock.Unlock() // Ensure the lock is Unlocked when the method returns
+
+	v.versions = append([]*Secret[T]{secret}, v.versions...)
+
+	for len(v.versions) > v.limit {
+		v.versions[len(v.versions)-1].Destroy()
+		v.versions = v.versions[:len(v.versions)-1]
+	}
+}
+
+// Rollback destroys the current version, making the previous version current again.
+// It returns ErrNoPreviousVersion if only one version is retained.
+func (v *VersionedSecret[T]) Rollback() error {
+	v.lock.Lock()         // Lock before replacing the versions
+	defer v.lock.Unlock() // Ensure the lock is Unlocked when the method returns
+
+	if len(v.versions) < 2 {
+		return ErrNoPreviousVersion
+	}
+
+	v.versions[0].Destroy()
+	v.versions = v.versions[1:]
+
+	return nil
+}
+
+// Current returns the current version. The Secret remains owned by the
+// VersionedSecret and is destroyed once it falls out of the retained versions, so
+// callers should call Current each time the value is needed rather than retaining it.
+func (v *VersionedSecret[T]) Current() *Secret[T] {
+	v.lock.RLock()         // RLock before reading the versions
+	defer v.lock.RUnlock() // Ensure the lock is RUnlocked when the method returns
+
+	return v.versions[0]
+}
+
+// Versions returns the retained versions, newest first. The Secrets remain owned by
+// the VersionedSecret, as described by Current.
+func (v *VersionedSecret[T]) Versions() []*Secret[T] {
+	v.lock.RLock()         // RLock before reading the versions
+	defer v.lock.RUnlock() // Ensure the lock is RUnlocked when the method returns
+
+	return append([]*Secret[T](nil), v.versions...)
+}
+
+// TryExposed exposes each retained version to fn in turn, newest first, until fn
+// returns nil. It returns nil if any version was accepted, and otherwise the errors
+// returned for every version joined together. No version is replaced while fn runs.
+func (v *VersionedSecret[T]) TryExposed(fn func(T) error) error {
+	v.lock.RLock()         // RLock before reading the versions
+	defer v.lock.RUnlock() // Ensure the lock is RUnlocked when the method returns
+
+	var errs []error
+
+	for _, secret := range v.versions {
+		err := secret.WithExposed(fn)
+		if err == nil {
+			return nil
+		}
+
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
+
+// Destroy destroys every retained version.
+func (v *VersionedSecret[T]) Destroy() {
+	v.lock.Lock()         // Lock before destroying the versions
+	defer v.lock.Unlock() // Ensure the lock is Unlocked when the method returns
+
+	for _, secret := range v.versions {
+		secret.Destroy()
+	}
+}
@@ -0,0 +1,58 @@
+package mattress
+
+import (
+	"reflect"
+
+	"github.com/awnumar/memguard"
+)
+
+// wipe makes a best-effort attempt to overwrite the plaintext held by the value v
+// points to. Byte slices reachable from v are wiped in place and v is then reset to
+// the zero value of its type. Strings are immutable in Go and so can only be
+// dereferenced, not overwritten; their backing memory is left to the garbage collector.
+func wipe[T any](v *T) {
+	if v == nil {
+		return
+	}
+
+	wipeValue(reflect.ValueOf(v).Elem())
+
+	var zero T
+	*v = zero
+}
+
+// wipeValue recursively walks rv, wiping any byte slices and zeroing any settable
+// values it encounters.
+func wipeValue(rv reflect.Value) {
+	switch rv.Kind() {
+	case reflect.Slice:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			memguard.WipeBytes(rv.Bytes())
+			return
+		}
+		for i := 0; i < rv.Len(); i++ {
+			wipeValue(rv.Index(i))
+		}
+	case reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			wipeValue(rv.Index(i))
+		}
+	case reflect.Pointer, reflect.Interface:
+		if !rv.IsNil() {
+			wipeValue(rv.Elem())
+		}
+	case reflect.Struct:
+		for i := 0; i < rv.NumField(); i++ {
+			wipeValue(rv.Field(i))
+		}
+	case reflect.Map:
+		iter := rv.MapRange()
+		for iter.Next() {
+			wipeValue(iter.Value())
+		}
+	}
+
+	if rv.CanSet() {
+		rv.SetZero()
+	}
+}
@@ -0,0 +1,132 @@
+package mattress
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/awnumar/memguard"
+)
+
+// RedactingWriter is an io.Writer which replaces every occurrence of the plaintext of
+// its secrets within the stream written to it with Placeholder before passing it on.
+// It is safe for concurrent use.
+type RedactingWriter struct {
+	w       io.Writer    // w receives the redacted stream
+	secrets []Redactable // secrets are the values redacted from the stream
+	pending []byte       // pending holds the end of the stream which may begin a plaintext
+	lock    sync.Mutex   // synchronize writes
+}
+
+// RedactWriter returns a RedactingWriter which redacts secrets from everything written
+// to it before writing it to w, as a last line of defense for stdout, stderr, and log
+// files.
+//
+// A plaintext may be split across several writes, so any trailing bytes which could
+// begin a plaintext are held back until the next write shows whether they do. Call
+// Flush or Close once the stream is complete to write out anything still held back.
+// Each secret is briefly decrypted for every write, so the cost of writing grows with
+// the number of secrets provided; this does not count as an exposure. Redaction fails
+// closed, as described by Redact: once the plaintext of any secret cannot be obtained,
+// every write fails with an error wrapping ErrRedactionFailed and writes nothing.
+func RedactWriter(w io.Writer, secrets ...Redactable) *RedactingWriter {
+	return &RedactingWriter{w: w, secrets: secrets}
+}
+
+// Write redacts p, along with any bytes held back from earlier writes, and writes the
+// result to the underlying writer, holding back any trailing bytes which could begin a
+// plaintext. It returns len(p) unless redaction or the underlying writer fails. If
+// redaction fails, nothing is written, and the bytes held back are discarded.
+func (r *RedactingWriter) Write(p []byte) (int, error) {
+	r.lock.Lock()         // Lock before touching the pending bytes
+	defer r.lock.Unlock() // Ensure the lock is Unlocked when the method returns
+
+	text := append(r.pending, p...)
+	r.pending = nil
+
+	// WipeBytes securely erases the unredacted stream once it has been redacted.
+	defer memguard.WipeBytes(text)
+
+	redacted, held, err := r.redact(text)
+	if err != nil {
+		return 0, err
+	}
+
+	r.pending = append([]byte(nil), redacted[len(redacted)-held:]...)
+
+	if _, err := r.w.Write(redacted[:len(redacted)-held]); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// Flush writes any bytes held back by earlier writes to the underlying writer.
+func (r *RedactingWriter) Flush() error {
+	r.lock.Lock()         // Lock before touching the pending bytes
+	defer r.lock.Unlock() // Ensure the lock is Unlocked when the method returns
+
+	return r.flush()
+}
+
+// Close flushes the RedactingWriter, as described by Flush. It does not close the
+// underlying writer.
+func (r *RedactingWriter) Close() error {
+	return r.Flush()
+}
+
+// flush writes the pending bytes to the underlying writer. The caller must hold the
+// lock.
+func (r *RedactingWriter) flush() error {
+	if len(r.pending) == 0 {
+		return nil
+	}
+
+	pending := r.pending
+	r.pending = nil
+
+	defer memguard.WipeBytes(pending)
+
+	_, err := r.w.Write(pending)
+
+	return err
+}
+
+// redact returns text with every plaintext replaced by Placeholder, along with the
+// number of trailing bytes of the result which could begin a plaintext and so must be
+// held back.
+func (r *RedactingWriter) redact(text []byte) ([]byte, int, error) {
+	text, _, err := redact(text, r.secrets)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// The bytes to hold back are only known once every plaintext has been replaced,
+	// as a later replacement may change the end of the text.
+	held := 0
+
+	for _, secret := range r.secrets {
+		err := viewPlaintext(secret, func(plaintext []byte) error {
+			held = max(held, prefixSuffix(text, plaintext))
+			return nil
+		})
+		if err != nil {
+			return nil, 0, fmt.Errorf("%w: %w", ErrRedactionFailed, err)
+		}
+	}
+
+	return text, held, nil
+}
+
+// prefixSuffix returns the length of the longest suffix of text which is a proper
+// prefix of plaintext.
+func prefixSuffix(text, plaintext []byte) int {
+	for n := min(len(plaintext)-1, len(text)); n > 0; n-- {
+		if bytes.HasSuffix(text, plaintext[:n]) {
+			return n
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,60 @@
+package mattress
+
+import (
+	"encoding/base64"
+	"fmt"
+
+	"github.com/awnumar/memguard"
+	"gopkg.in/yaml.v3"
+)
+
+// UnmarshalYAML implements yaml.Unmarshaler for gopkg.in/yaml.v3, securing the scalar
+// held by node within the Secret as UnmarshalText does, so that configuration files
+// can be decoded directly into Secrets. Values tagged !!binary are base64-decoded
+// first. Once secured, the value is dropped from node, leaving the garbage collector
+// the only remaining copy. Any data the Secret previously held is destroyed.
+func (s *Secret[T]) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.ScalarNode {
+		return fmt.Errorf("mattress: cannot decode YAML %s into a Secret", kindName(node.Kind))
+	}
+
+	var text []byte
+
+	if node.ShortTag() == "!!binary" {
+		decoded, err := base64.StdEncoding.DecodeString(node.Value)
+		if err != nil {
+			return fmt.Errorf("mattress: decoding !!binary value: %w", err)
+		}
+
+		text = decoded
+	} else {
+		text = []byte(node.Value)
+	}
+
+	// WipeBytes securely erases the intermediate copy once it has been secured.
+	defer memguard.WipeBytes(text)
+
+	if err := s.UnmarshalText(text); err != nil {
+		return err
+	}
+
+	node.Value = ""
+
+	return nil
+}
+
+// kindName returns the name of a YAML node kind, for use in errors.
+func kindName(kind yaml.Kind) string {
+	switch kind {
+	case yaml.DocumentNode:
+		return "document"
+	case yaml.SequenceNode:
+		return "sequence"
+	case yaml.MappingNode:
+		return "mapping"
+	case yaml.AliasNode:
+		return "alias"
+	default:
+		return "node"
+	}
+}